@@ -9,6 +9,8 @@ import (
 
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/attractions"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tracing"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tune"
 
@@ -39,6 +41,10 @@ func main() {
 	var result map[string]string
 	json.Unmarshal([]byte(byteValue), &result)
 
+	if err := tls.Init(result, "Attractions"); err != nil {
+		tempLogger.Panic().Msgf("Got error while loading TLS certificates: %v", err)
+	}
+
 	tempLogger.Info().Msgf("Read database URL: %v", result["AttractionsMongoAddress"])
 	tempLogger.Info().Msg("Initializing DB connection...")
 	mongo_session, mongoClose := initializeDatabase(result["AttractionsMongoAddress"])
@@ -73,12 +79,12 @@ func main() {
 	log.Logger = logger
 	logger.Info().Msg("OpenTelemetry tracer and logger initialized")
 
-	logger.Info().Msgf("Initializing consul agent [host: %v]...", *consuladdr)
-	registry, err := registry.NewClient(*consuladdr)
+	logger.Info().Msgf("Initializing service registry [backend: %v | consul: %v]...", result["Registry"], *consuladdr)
+	registry, err := registry.New(result, *consuladdr)
 	if err != nil {
-		logger.Panic().Msgf("Got error while initializing consul agent: %v", err)
+		logger.Panic().Msgf("Got error while initializing service registry: %v", err)
 	}
-	logger.Info().Msg("Consul agent initialized")
+	logger.Info().Msg("Service registry initialized")
 
 	srv := attractions.Server{
 		Tracer:      tracer,
@@ -89,7 +95,11 @@ func main() {
 	}
 
 	logger.Info().Msg("Starting server...")
-	if err := srv.Run(); err != nil {
+	if err := srv.Setup(); err != nil {
+		logger.Fatal().Msgf("Server error: %v", err)
+	}
+	if err := shutdown.Run(srv.Run, srv.Shutdown, shutdown.Flush(tracing.Shutdown)); err != nil {
 		logger.Fatal().Msgf("Server error: %v", err)
 	}
+	logger.Info().Msg("Server stopped")
 }
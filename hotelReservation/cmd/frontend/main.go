@@ -10,6 +10,8 @@ import (
 
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/frontend"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tracing"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tune"
 	"github.com/rs/zerolog"
@@ -34,13 +36,18 @@ func main() {
 	var result map[string]string
 	json.Unmarshal([]byte(byteValue), &result)
 
+	if err := tls.Init(result, "Frontend"); err != nil {
+		log.Panic().Msgf("Got error while loading TLS certificates: %v", err)
+	}
+
 	servPort, _ := strconv.Atoi(result["FrontendPort"])
 	servIP := result["FrontendIP"]
-	knativeDNS := result["KnativeDomainName"]
 
 	var (
 		jaegerAddr = flag.String("jaegeraddr", result["jaegerAddress"], "Jaeger address")
-		consulAddr = flag.String("consuladdr", result["consulAddress"], "Consul address")
+		consulAddr    = flag.String("consuladdr", result["consulAddress"], "Consul address")
+		sessionSecret = flag.String("sessionsecret", os.Getenv("SESSION_SECRET"), "Secret used to verify session tokens")
+		authMode      = flag.String("authmode", result["AuthMode"], "Authentication mode: strict or permissive")
 	)
 	flag.Parse()
 	// Initialize OpenTelemetry with logging support
@@ -54,22 +61,28 @@ func main() {
 	log.Logger = logger
 	logger.Info().Msg("OpenTelemetry tracer and logger initialized")
 
-	logger.Info().Msgf("Initializing consul agent [host: %v]...", *consulAddr)
-	registry, err := registry.NewClient(*consulAddr)
+	logger.Info().Msgf("Initializing service registry [backend: %v | consul: %v]...", result["Registry"], *consulAddr)
+	registry, err := registry.New(result, *consulAddr)
 	if err != nil {
-		logger.Panic().Msgf("Got error while initializing consul agent: %v", err)
+		logger.Panic().Msgf("Got error while initializing service registry: %v", err)
 	}
-	logger.Info().Msg("Consul agent initialized")
+	logger.Info().Msg("Service registry initialized")
 
 	srv := &frontend.Server{
-		KnativeDns: knativeDNS,
-		Registry:   registry,
-		Tracer:     tracer,
-		IpAddr:     servIP,
-		ConsulAddr: *consulAddr,
-		Port:       servPort,
+		Registry:      registry,
+		Tracer:        tracer,
+		IpAddr:        servIP,
+		Port:          servPort,
+		SessionSecret: []byte(*sessionSecret),
+		AuthMode:      *authMode,
 	}
 
 	logger.Info().Msg("Starting server...")
-	logger.Fatal().Msg(srv.Run().Error())
+	if err := srv.Setup(); err != nil {
+		logger.Fatal().Msgf("Server error: %v", err)
+	}
+	if err := shutdown.Run(srv.Run, srv.Shutdown, shutdown.Flush(tracing.Shutdown)); err != nil {
+		logger.Fatal().Msgf("Server error: %v", err)
+	}
+	logger.Info().Msg("Server stopped")
 }
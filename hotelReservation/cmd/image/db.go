@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ImageMeta struct {
+	ImageId     string `bson:"imageId"`
+	Owner       string `bson:"owner"`
+	OwnerId     string `bson:"ownerId"`
+	ContentType string `bson:"contentType"`
+	Width       int32  `bson:"width"`
+	Height      int32  `bson:"height"`
+	Default     bool   `bson:"default"`
+	CreatedAt   int64  `bson:"createdAt"`
+}
+
+const (
+	seedWidth  = 640
+	seedHeight = 480
+)
+
+// seedImage draws a gradient in colours derived from the hotel id, so every
+// hotel gets a distinct but reproducible picture
+func seedImage(hotel int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, seedWidth, seedHeight))
+	base := color.RGBA{uint8(hotel * 37), uint8(hotel * 71), uint8(hotel * 113), 255}
+	for y := 0; y < seedHeight; y++ {
+		for x := 0; x < seedWidth; x++ {
+			img.Set(x, y, color.RGBA{
+				base.R + uint8(x*255/seedWidth),
+				base.G + uint8(y*255/seedHeight),
+				base.B + uint8((x+y)*127/(seedWidth+seedHeight)),
+				255,
+			})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 75}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func initializeDatabase(url string) (*mongo.Client, func()) {
+	uri := fmt.Sprintf("mongodb://%s", url)
+	log.Info().Msgf("Attempting connection to %v", uri)
+
+	opts := options.Client().ApplyURI(uri)
+	client, err := mongo.Connect(context.TODO(), opts)
+	if err != nil {
+		log.Panic().Msg(err.Error())
+	}
+	log.Info().Msg("Successfully connected to MongoDB")
+
+	collection := client.Database("image-db").Collection("images")
+
+	// images are listed per owner and fetched by id
+	_, err = collection.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "owner", Value: 1}, {Key: "ownerId", Value: 1}}},
+		{Keys: bson.D{{Key: "imageId", Value: 1}}},
+	})
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+
+	// the images are kept across restarts, so only an empty DB is seeded
+	count, err := collection.CountDocuments(context.TODO(), bson.M{})
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+	if count > 0 {
+		log.Info().Msgf("Image DB already holds %d images", count)
+	} else {
+		bucket, err := gridfs.NewBucket(client.Database("image-db"), options.GridFSBucket().SetName("images"))
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+
+		var metas []interface{}
+		for i := 1; i <= 80; i++ {
+			data, err := seedImage(i)
+			if err != nil {
+				log.Fatal().Msg(err.Error())
+			}
+			imageId := fmt.Sprintf("hotel-%d", i)
+			if _, err := bucket.UploadFromStream(imageId, bytes.NewReader(data)); err != nil {
+				log.Fatal().Msg(err.Error())
+			}
+			metas = append(metas, &ImageMeta{
+				ImageId:     imageId,
+				Owner:       "hotel",
+				OwnerId:     fmt.Sprintf("%d", i),
+				ContentType: "image/jpeg",
+				Width:       seedWidth,
+				Height:      seedHeight,
+				Default:     true,
+				CreatedAt:   time.Now().Unix(),
+			})
+		}
+		_, err = collection.InsertMany(context.TODO(), metas)
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+		log.Info().Msg("Successfully inserted test data into image DB")
+	}
+
+	return client, func() {
+		if err := client.Disconnect(context.TODO()); err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+	}
+
+}
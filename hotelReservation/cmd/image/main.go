@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/image"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tracing"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tune"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"time"
+)
+
+func main() {
+	tune.Init()
+
+	// Initialize temporary logger for startup
+	tempLogger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}).With().Timestamp().Caller().Logger()
+	log.Logger = tempLogger
+
+	tempLogger.Info().Msg("Reading config...")
+	jsonFile, err := os.Open("config.json")
+	if err != nil {
+		tempLogger.Error().Msgf("Got error while reading config: %v", err)
+	}
+
+	defer jsonFile.Close()
+
+	byteValue, _ := ioutil.ReadAll(jsonFile)
+
+	var result map[string]string
+	json.Unmarshal([]byte(byteValue), &result)
+
+	if err := tls.Init(result, "Image"); err != nil {
+		tempLogger.Panic().Msgf("Got error while loading TLS certificates: %v", err)
+	}
+
+	tempLogger.Info().Msgf("Read database URL: %v", result["ImageMongoAddress"])
+	tempLogger.Info().Msg("Initializing DB connection...")
+	mongo_session, mongoClose := initializeDatabase(result["ImageMongoAddress"])
+	defer mongoClose()
+	tempLogger.Info().Msg("Successfull")
+
+	serv_port, _ := strconv.Atoi(result["ImagePort"])
+	serv_ip := result["ImageIP"] // Will be empty, allowing auto-detection
+
+	var (
+		jaegeraddr = flag.String("jaegeraddr", result["jaegerAddress"], "Jaeger address")
+		consuladdr = flag.String("consuladdr", result["consulAddress"], "Consul address")
+	)
+	flag.Parse()
+
+	// Initialize OpenTelemetry with logging support
+	tempLogger.Info().Msgf("Initializing OpenTelemetry with logging [service name: %v | host: %v]...", "image", *jaegeraddr)
+	tracer, logger, err := tracing.InitWithLogging("image", *jaegeraddr)
+	if err != nil {
+		tempLogger.Panic().Msgf("Got error while initializing OpenTelemetry: %v", err)
+	}
+
+	// Set the global logger to the one with OTLP export
+	log.Logger = logger
+	logger.Info().Msg("OpenTelemetry tracer and logger initialized")
+
+	logger.Info().Msgf("Initializing service registry [backend: %v | consul: %v]...", result["Registry"], *consuladdr)
+	registry, err := registry.New(result, *consuladdr)
+	if err != nil {
+		logger.Panic().Msgf("Got error while initializing service registry: %v", err)
+	}
+	logger.Info().Msg("Service registry initialized")
+
+	srv := image.Server{
+		Tracer:      tracer,
+		Registry:    registry,
+		Port:        serv_port,
+		IpAddr:      serv_ip,
+		MongoClient: mongo_session,
+	}
+
+	logger.Info().Msg("Starting server...")
+	if err := srv.Setup(); err != nil {
+		logger.Fatal().Msgf("Server error: %v", err)
+	}
+	if err := shutdown.Run(srv.Run, srv.Shutdown, shutdown.Flush(tracing.Shutdown)); err != nil {
+		logger.Fatal().Msgf("Server error: %v", err)
+	}
+	logger.Info().Msg("Server stopped")
+}
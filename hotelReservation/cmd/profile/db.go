@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -176,6 +177,15 @@ func initializeDatabase(url string) (*mongo.Client, func()) {
 	}
 	log.Info().Msg("Successfully inserted test data into profile DB")
 
+	// city search looks hotels up by city and postal code
+	_, err = collection.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "address.city", Value: 1}}},
+		{Keys: bson.D{{Key: "address.postalCode", Value: 1}}},
+	})
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+
 	return client, func() {
 		if err := client.Disconnect(context.TODO()); err != nil {
 			log.Fatal().Msg(err.Error())
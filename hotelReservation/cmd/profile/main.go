@@ -10,6 +10,8 @@ import (
 
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/profile"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tracing"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tune"
 	"github.com/rs/zerolog"
@@ -36,6 +38,10 @@ func main() {
 	var result map[string]string
 	json.Unmarshal([]byte(byteValue), &result)
 
+	if err := tls.Init(result, "Profile"); err != nil {
+		tempLogger.Panic().Msgf("Got error while loading TLS certificates: %v", err)
+	}
+
 	tempLogger.Info().Msg("Initializing DB connection...")
 	mongoClient, mongoClose := initializeDatabase(result["ProfileMongoAddress"])
 	defer mongoClose()
@@ -65,12 +71,12 @@ func main() {
 	log.Logger = logger
 	logger.Info().Msg("OpenTelemetry tracer and logger initialized")
 
-	logger.Info().Msgf("Initializing consul agent [host: %v]...", *consulAddr)
-	registry, err := registry.NewClient(*consulAddr)
+	logger.Info().Msgf("Initializing service registry [backend: %v | consul: %v]...", result["Registry"], *consulAddr)
+	registry, err := registry.New(result, *consulAddr)
 	if err != nil {
-		logger.Panic().Msgf("Got error while initializing consul agent: %v", err)
+		logger.Panic().Msgf("Got error while initializing service registry: %v", err)
 	}
-	logger.Info().Msg("Consul agent initialized")
+	logger.Info().Msg("Service registry initialized")
 
 	srv := &profile.Server{
 		Port:        servPort,
@@ -82,5 +88,11 @@ func main() {
 	}
 
 	logger.Info().Msg("Starting server...")
-	logger.Fatal().Msg(srv.Run().Error())
+	if err := srv.Setup(); err != nil {
+		logger.Fatal().Msgf("Server error: %v", err)
+	}
+	if err := shutdown.Run(srv.Run, srv.Shutdown, shutdown.Flush(tracing.Shutdown)); err != nil {
+		logger.Fatal().Msgf("Server error: %v", err)
+	}
+	logger.Info().Msg("Server stopped")
 }
@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -26,6 +27,14 @@ type RatePlan struct {
 	RoomType *RoomType `bson:"roomType"`
 }
 
+// The wrk2 workloads search and book stays between 2015-04-09 and 2015-04-28.
+// Rates are priced per night, so the seeded plans cover every night of that
+// range for hotels to keep showing up in searches.
+const (
+	seedInDate  = "2015-04-09"
+	seedOutDate = "2015-04-28"
+)
+
 func initializeDatabase(url string) (*mongo.Client, func()) {
 	log.Info().Msg("Generating test data...")
 
@@ -33,8 +42,8 @@ func initializeDatabase(url string) (*mongo.Client, func()) {
 		RatePlan{
 			"1",
 			"RACK",
-			"2015-04-09",
-			"2015-04-10",
+			seedInDate,
+			seedOutDate,
 			&RoomType{
 				109.00,
 				"KNG",
@@ -46,8 +55,8 @@ func initializeDatabase(url string) (*mongo.Client, func()) {
 		RatePlan{
 			"2",
 			"RACK",
-			"2015-04-09",
-			"2015-04-10",
+			seedInDate,
+			seedOutDate,
 			&RoomType{
 				139.00,
 				"QN",
@@ -59,8 +68,8 @@ func initializeDatabase(url string) (*mongo.Client, func()) {
 		RatePlan{
 			"3",
 			"RACK",
-			"2015-04-09",
-			"2015-04-10",
+			seedInDate,
+			seedOutDate,
 			&RoomType{
 				109.00,
 				"KNG",
@@ -78,13 +87,6 @@ func initializeDatabase(url string) (*mongo.Client, func()) {
 
 		hotelID := strconv.Itoa(i)
 
-		endDate := "2015-04-"
-		if i%2 == 0 {
-			endDate = fmt.Sprintf("%s17", endDate)
-		} else {
-			endDate = fmt.Sprintf("%s24", endDate)
-		}
-
 		rate := 109.00
 		rateInc := 123.17
 		if i%5 == 1 {
@@ -106,8 +108,8 @@ func initializeDatabase(url string) (*mongo.Client, func()) {
 			RatePlan{
 				hotelID,
 				"RACK",
-				"2015-04-09",
-				endDate,
+				seedInDate,
+				seedOutDate,
 				&RoomType{
 					rate,
 					"KNG",
@@ -136,6 +138,14 @@ func initializeDatabase(url string) (*mongo.Client, func()) {
 	}
 	log.Info().Msg("Successfully inserted test data into rate DB")
 
+	// rates are looked up per hotel and stay
+	_, err = collection.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "hotelId", Value: 1}, {Key: "inDate", Value: 1}},
+	})
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+
 	return client, func() {
 		if err := client.Disconnect(context.TODO()); err != nil {
 			log.Fatal().Msg(err.Error())
@@ -9,6 +9,8 @@ import (
 
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tracing"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tune"
 	"github.com/rs/zerolog"
@@ -19,7 +21,7 @@ import (
 
 func main() {
 	tune.Init()
-	
+
 	// Initialize temporary logger for startup
 	tempLogger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}).With().Timestamp().Caller().Logger()
 	log.Logger = tempLogger
@@ -37,6 +39,10 @@ func main() {
 	var result map[string]string
 	json.Unmarshal([]byte(byteValue), &result)
 
+	if err := tls.Init(result, "Rate"); err != nil {
+		tempLogger.Panic().Msgf("Got error while loading TLS certificates: %v", err)
+	}
+
 	tempLogger.Info().Msg("Initializing DB connection...")
 	mongoClient, mongoClose := initializeDatabase(result["RateMongoAddress"])
 	defer mongoClose()
@@ -52,36 +58,60 @@ func main() {
 	var (
 		jaegerAddr = flag.String("jaegeraddr", result["jaegerAddress"], "Jaeger address")
 		consulAddr = flag.String("consuladdr", result["consulAddress"], "Consul address")
+		pricing    = flag.String("pricing", result["RatePricingPolicy"], "Pricing policy (static, surge or linear)")
 	)
 	flag.Parse()
 
+	pricingPolicy, err := rate.PolicyByName(*pricing)
+	if err != nil {
+		tempLogger.Panic().Msgf("Got error while reading pricing policy: %v", err)
+	}
+
+	baseCurrency := result["RateBaseCurrency"]
+	if baseCurrency == "" {
+		baseCurrency = rate.DefaultCurrency
+	}
+	currencies, err := rate.ParseCurrencies(baseCurrency, result["RateCurrencies"])
+	if err != nil {
+		tempLogger.Panic().Msgf("Got error while reading currency table: %v", err)
+	}
+
 	// Initialize OpenTelemetry with logging support
 	tempLogger.Info().Msgf("Initializing OpenTelemetry with logging [service name: %v | host: %v]...", "rate", *jaegerAddr)
 	tracer, logger, err := tracing.InitWithLogging("rate", *jaegerAddr)
 	if err != nil {
 		tempLogger.Panic().Msgf("Got error while initializing OpenTelemetry: %v", err)
 	}
-	
+
 	// Set the global logger to the one with OTLP export
 	log.Logger = logger
 	logger.Info().Msg("OpenTelemetry tracer and logger initialized")
 
-	logger.Info().Msgf("Initializing consul agent [host: %v]...", *consulAddr)
-	registry, err := registry.NewClient(*consulAddr)
+	logger.Info().Msgf("Initializing service registry [backend: %v | consul: %v]...", result["Registry"], *consulAddr)
+	registry, err := registry.New(result, *consulAddr)
 	if err != nil {
-		logger.Panic().Msgf("Got error while initializing consul agent: %v", err)
+		logger.Panic().Msgf("Got error while initializing service registry: %v", err)
 	}
-	logger.Info().Msg("Consul agent initialized")
+	logger.Info().Msg("Service registry initialized")
 
 	srv := &rate.Server{
-		Tracer:      tracer,
-		Registry:    registry,
-		Port:        servPort,
-		IpAddr:      servIP,
-		MongoClient: mongoClient,
-		MemcClient:  memcClient,
+		Tracer:       tracer,
+		Registry:     registry,
+		Port:         servPort,
+		IpAddr:       servIP,
+		MongoClient:  mongoClient,
+		MemcClient:   memcClient,
+		Pricing:      pricingPolicy,
+		BaseCurrency: baseCurrency,
+		Currencies:   currencies,
 	}
 
 	logger.Info().Msg("Starting server...")
-	logger.Fatal().Msg(srv.Run().Error())
+	if err := srv.Setup(); err != nil {
+		logger.Fatal().Msgf("Server error: %v", err)
+	}
+	if err := shutdown.Run(srv.Run, srv.Shutdown, shutdown.Flush(tracing.Shutdown)); err != nil {
+		logger.Fatal().Msgf("Server error: %v", err)
+	}
+	logger.Info().Msg("Server stopped")
 }
@@ -11,6 +11,8 @@ import (
 
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/recommendation"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tracing"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tune"
 	"github.com/rs/zerolog"
@@ -36,6 +38,10 @@ func main() {
 	var result map[string]string
 	json.Unmarshal([]byte(byteValue), &result)
 
+	if err := tls.Init(result, "Recommend"); err != nil {
+		log.Panic().Msgf("Got error while loading TLS certificates: %v", err)
+	}
+
 	log.Info().Msg("Initializing DB connection...")
 	mongoClient, mongoClose := initializeDatabase(result["RecommendMongoAddress"])
 	defer mongoClose()
@@ -44,8 +50,9 @@ func main() {
 	servIP := result["RecommendIP"]
 
 	var (
-		jaegerAddr = flag.String("jaegeraddr", result["jaegerAddress"], "Jaeger address")
-		consulAddr = flag.String("consuladdr", result["consulAddress"], "Consul address")
+		jaegerAddr   = flag.String("jaegeraddr", result["jaegerAddress"], "Jaeger address")
+		consulAddr   = flag.String("consuladdr", result["consulAddress"], "Consul address")
+		ratingSource = flag.String("ratingsource", result["RecommendRatingSource"], "Source of hotel ratings (static or review)")
 	)
 	flag.Parse()
 
@@ -60,21 +67,28 @@ func main() {
 	log.Logger = logger
 	logger.Info().Msg("OpenTelemetry tracer and logger initialized")
 
-	logger.Info().Msgf("Initializing consul agent [host: %v]...", *consulAddr)
-	registry, err := registry.NewClient(*consulAddr)
+	logger.Info().Msgf("Initializing service registry [backend: %v | consul: %v]...", result["Registry"], *consulAddr)
+	registry, err := registry.New(result, *consulAddr)
 	if err != nil {
-		logger.Panic().Msgf("Got error while initializing consul agent: %v", err)
+		logger.Panic().Msgf("Got error while initializing service registry: %v", err)
 	}
-	logger.Info().Msg("Consul agent initialized")
+	logger.Info().Msg("Service registry initialized")
 
 	srv := &recommendation.Server{
-		Port:        servPort,
-		IpAddr:      servIP,
-		Tracer:      tracer,
-		Registry:    registry,
-		MongoClient: mongoClient,
+		Port:         servPort,
+		IpAddr:       servIP,
+		Tracer:       tracer,
+		Registry:     registry,
+		MongoClient:  mongoClient,
+		RatingSource: *ratingSource,
 	}
 
 	logger.Info().Msg("Starting server...")
-	logger.Fatal().Msg(srv.Run().Error())
+	if err := srv.Setup(); err != nil {
+		logger.Fatal().Msgf("Server error: %v", err)
+	}
+	if err := shutdown.Run(srv.Run, srv.Shutdown, shutdown.Flush(tracing.Shutdown)); err != nil {
+		logger.Fatal().Msgf("Server error: %v", err)
+	}
+	logger.Info().Msg("Server stopped")
 }
@@ -6,16 +6,18 @@ import (
 	"strconv"
 
 	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Reservation struct {
-	HotelId      string `bson:"hotelId"`
-	CustomerName string `bson:"customerName"`
-	InDate       string `bson:"inDate"`
-	OutDate      string `bson:"outDate"`
-	Number       int    `bson:"number"`
+	ReservationId string `bson:"reservationId"`
+	HotelId       string `bson:"hotelId"`
+	CustomerName  string `bson:"customerName"`
+	InDate        string `bson:"inDate"`
+	OutDate       string `bson:"outDate"`
+	Number        int    `bson:"number"`
 }
 
 type Number struct {
@@ -27,7 +29,7 @@ func initializeDatabase(url string) (*mongo.Client, func()) {
 	log.Info().Msg("Generating test data...")
 
 	newReservations := []interface{}{
-		Reservation{"4", "Alice", "2015-04-09", "2015-04-10", 1},
+		Reservation{"0a9e4c3e-3c1f-4d0c-9d5e-6f1b2a7c8d90", "4", "Alice", "2015-04-09", "2015-04-10", 1},
 	}
 
 	newNumbers := []interface{}{
@@ -65,6 +67,7 @@ func initializeDatabase(url string) (*mongo.Client, func()) {
 	database := client.Database("reservation-db")
 	resCollection := database.Collection("reservation")
 	numCollection := database.Collection("number")
+	occCollection := database.Collection("occupancy")
 
 	_, err = resCollection.InsertMany(context.TODO(), newReservations)
 	if err != nil {
@@ -77,6 +80,30 @@ func initializeDatabase(url string) (*mongo.Client, func()) {
 	}
 	log.Info().Msg("Successfully inserted test data into reservation DB")
 
+	// one occupancy counter per hotel and night, so that concurrent
+	// reservations cannot create duplicate counters
+	_, err = occCollection.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "hotelId", Value: 1},
+			{Key: "inDate", Value: 1},
+			{Key: "outDate", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+
+	// reservations are looked up by id, by customer and by hotel night
+	_, err = resCollection.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "reservationId", Value: 1}}},
+		{Keys: bson.D{{Key: "customerName", Value: 1}}},
+		{Keys: bson.D{{Key: "hotelId", Value: 1}, {Key: "inDate", Value: 1}}},
+	})
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+
 	return client, func() {
 		if err := client.Disconnect(context.TODO()); err != nil {
 			log.Fatal().Msg(err.Error())
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -98,6 +99,15 @@ func initializeDatabase(url string) (*mongo.Client, func()) {
 	}
 	log.Info().Msg("Successfully inserted test data into rate DB")
 
+	// reviews are listed per hotel and deleted by id
+	_, err = collection.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hotelId", Value: 1}}},
+		{Keys: bson.D{{Key: "reviewId", Value: 1}}},
+	})
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+
 	return client, func() {
 		if err := client.Disconnect(context.TODO()); err != nil {
 			log.Fatal().Msg(err.Error())
@@ -9,6 +9,8 @@ import (
 
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/review"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tracing"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tune"
 
@@ -38,6 +40,10 @@ func main() {
 	var result map[string]string
 	json.Unmarshal([]byte(byteValue), &result)
 
+	if err := tls.Init(result, "Review"); err != nil {
+		log.Panic().Msgf("Got error while loading TLS certificates: %v", err)
+	}
+
 	log.Info().Msgf("Read database URL: %v", result["ReviewMongoAddress"])
 	log.Info().Msg("Initializing DB connection...")
 	mongo_session, mongoClose := initializeDatabase(result["ReviewMongoAddress"])
@@ -72,12 +78,12 @@ func main() {
 	}
 	log.Info().Msg("Jaeger agent initialized")
 
-	log.Info().Msgf("Initializing consul agent [host: %v]...", *consuladdr)
-	registry, err := registry.NewClient(*consuladdr)
+	log.Info().Msgf("Initializing service registry [backend: %v | consul: %v]...", result["Registry"], *consuladdr)
+	registry, err := registry.New(result, *consuladdr)
 	if err != nil {
-		log.Panic().Msgf("Got error while initializing consul agent: %v", err)
+		log.Panic().Msgf("Got error while initializing service registry: %v", err)
 	}
-	log.Info().Msg("Consul agent initialized")
+	log.Info().Msg("Service registry initialized")
 
 	srv := review.Server{
 		Tracer: tracer,
@@ -90,5 +96,11 @@ func main() {
 	}
 
 	log.Info().Msg("Starting server...")
-	log.Fatal().Msg(srv.Run().Error())
+	if err := srv.Setup(); err != nil {
+		log.Fatal().Msgf("Server error: %v", err)
+	}
+	if err := shutdown.Run(srv.Run, srv.Shutdown, shutdown.Flush(tracing.Shutdown)); err != nil {
+		log.Fatal().Msgf("Server error: %v", err)
+	}
+	log.Info().Msg("Server stopped")
 }
@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -47,11 +48,28 @@ func initializeDatabase(url string) (*mongo.Client, func()) {
 	log.Info().Msg("Successfully connected to MongoDB")
 
 	collection := client.Database("user-db").Collection("user")
-	_, err = collection.InsertMany(context.TODO(), newUsers)
+
+	// users register and get their hashes migrated at runtime, so only seed
+	// an empty database
+	count, err := collection.CountDocuments(context.TODO(), bson.D{})
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+	if count == 0 {
+		_, err = collection.InsertMany(context.TODO(), newUsers)
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+		log.Info().Msg("Successfully inserted test data into user DB")
+	}
+
+	_, err = collection.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "username", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
 	if err != nil {
 		log.Fatal().Msg(err.Error())
 	}
-	log.Info().Msg("Successfully inserted test data into user DB")
 
 	return client, func() {
 		if err := client.Disconnect(context.TODO()); err != nil {
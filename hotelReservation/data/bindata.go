@@ -131,7 +131,7 @@ func dataInventoryJson() (*asset, error) {
 	return a, nil
 }
 
-var _dataLocalesJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x4c\x8f\x31\x4b\xf4\x40\x10\x86\xfb\xfb\x15\x2f\xa9\xf3\x7d\x20\x88\x85\x9d\x60\x63\x71\x20\x04\x2b\xb1\x98\xec\x4e\x2e\x43\xe6\x66\xe3\xec\xae\x67\x10\xff\xbb\x6c\xae\xd0\x29\xa6\x78\xde\xe1\x61\xde\xd7\x03\x00\x7c\xed\xbb\x4d\x37\xa7\xc2\xfa\x14\xbb\x7b\x74\x37\x5d\xff\xcb\x35\x05\x52\x6e\x98\xed\x2f\x8f\x9c\x83\xcb\x5a\x24\x59\x0b\x1f\x70\xf7\xef\x2c\x56\x0b\xe3\x42\xba\x60\xf2\x74\xc6\x8b\x49\x32\x0c\xef\x95\x9c\x41\x16\x71\x8b\xeb\x4d\xbe\xe6\x84\x63\x35\xc1\x91\x8b\x27\xe4\x42\x4d\xd6\xa3\xcc\x92\xa1\xf5\xb3\xfa\x86\xfd\x2b\x44\xce\x72\x32\x8e\x18\x37\x3c\xcf\xa2\xb2\xae\x8c\xa1\x90\x87\x05\x13\x53\xa9\xce\x19\x64\x20\x2f\x79\xc3\x54\xdd\xa4\x31\x84\xa4\xca\xa1\x59\x21\x86\x32\x33\x34\x8d\xe3\xd6\x43\x2c\x68\x8d\x62\x27\x5c\x92\x2f\x4d\x3b\x90\x7e\x50\x4c\x8e\x47\x52\xf9\xdf\xed\x3d\xbf\x0f\x6f\x87\x9f\x00\x00\x00\xff\xff\x84\x3e\x09\x90\x28\x01\x00\x00")
+var _dataLocalesJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xa5\x95\xbd\x6e\x13\x41\x10\xc7\xfb\x3c\xc5\xc8\x4d\x1a\x07\x29\x1f\x42\x0a\x5d\x02\x0d\x45\xa4\x08\x43\x81\x10\xc5\xdc\xde\xf8\x6e\x93\xbd\xdd\xcb\x7e\xd8\x39\x21\x24\xde\x81\x17\x00\x1a\x94\xc2\x05\x82\x37\x38\xf1\x5e\xcc\x9c\x63\xfb\x1c\x43\x82\x9c\x2b\x2c\xef\xec\x79\x3e\x7e\xf3\x9f\xf1\xbb\x1d\xe0\xe7\x43\xf7\x29\xcf\xa0\x74\x91\xcc\xcb\x7c\xf0\x0c\x06\xfb\x83\xe1\xca\x6e\x9c\x42\x43\x62\x26\xdb\xb7\xe7\x14\x94\xd7\x75\xd4\xce\xca\xe5\x09\x3c\xdd\xab\xb4\x4d\x91\x60\x8a\xe6\x12\xc6\xde\x55\xf0\xc6\xf2\x2d\x8c\xae\x12\x7a\x02\xb4\x39\x1c\xc1\xfc\x9d\x30\xbf\x47\x38\x4b\x56\xc3\x19\x45\xef\x20\x44\x14\x67\x43\x88\xa5\x0e\x60\xd2\x75\xf2\x0d\x74\x59\x01\xc7\xd2\x85\xa5\x1c\xb2\x06\xce\x4b\x6d\x74\x5d\x13\x8c\x22\x7a\xc5\x81\x08\x63\xf2\xec\x11\x2d\xa0\x8f\xa1\x81\x71\xf2\x56\x8b\x0d\x94\x33\x86\x94\x78\x05\x6d\xd9\x2f\x81\x71\x59\xd6\x0c\xf9\xa4\x4c\xca\xb5\x2d\x60\xea\xfc\xa5\xb8\x1d\xa1\x99\x60\xee\x3c\xbc\x40\xa3\x9f\x0c\xba\x3a\x3f\x0e\xb7\x80\x14\xee\x87\x34\x07\xe0\x38\x5d\xa8\x35\x71\x65\xeb\x90\x1a\xb6\x1f\x2d\xdf\xe1\xdb\x64\x11\x88\xd1\x28\xdd\xfe\xb4\x62\x58\x11\x1b\xca\x05\x2d\x11\x31\xb2\x0b\x07\xb9\x0e\xd4\xfe\xe0\x4a\xa0\xe6\x62\xee\xc2\x52\x89\x6c\x44\xe6\x62\x3b\xc7\xcc\x87\xd4\xd2\x73\x95\x28\x33\x02\xd2\xc7\x76\x16\xa2\x56\x9c\x01\x59\x60\xe7\x13\x0e\xd4\xce\xb2\x64\x38\xe6\x55\xa2\x39\xbe\x86\xc0\x65\x1e\xbb\x2c\xfb\xf4\xda\xd9\x23\xf0\x8d\xfd\x3d\xf8\xda\x4f\x0b\x7e\x9c\x65\xfb\x45\x00\xe6\x90\xef\xae\x01\xa4\x28\x37\x2b\x9d\xe5\xbb\xc9\xd2\x42\x5b\x77\xf9\x29\x7e\xbb\x6c\x7f\x2d\xf9\x5d\x8b\x64\x6c\xfb\x3d\x41\x8d\x9b\xf0\x6a\xdf\xde\x04\xe6\xc7\x5d\x43\x1b\x20\xb0\xbf\x12\x8d\x01\x09\xd0\x53\x9a\x90\xa4\xb4\x20\xa9\x19\x24\x23\x0b\x43\xc8\x9d\x8d\xa2\x64\xf8\xfd\x39\x4d\x44\xb0\x5b\x71\x3b\xd8\x4a\x76\x15\xd9\x95\x9c\x54\xc2\xdc\x23\x9f\x0c\xbc\x25\x9f\x21\x9c\xb2\x2a\x10\x9e\x4b\x69\x1e\xc6\x9c\x8d\x4c\xca\x09\xcf\xd2\xad\xc2\x2a\x97\x93\xb7\xee\x56\x69\xa2\x85\xaf\x2c\xd3\xfd\x83\xfb\xb5\xfc\x88\x6a\x1e\x52\x41\xe5\xb4\x9d\xb7\x16\xea\xf6\x5b\x6c\x6f\x3a\xe8\xa8\xb9\x25\x6c\x4e\x0f\x97\xd5\x6b\x3c\x4b\xd8\xaa\x92\x5d\x04\x02\x16\x45\x9a\x90\x08\x68\x51\xdc\x3f\x85\xf6\x3f\xd5\x1d\x6e\xd5\xab\xc3\x0d\xac\x06\x0a\xcd\x0b\x52\xbe\x44\xce\x76\xd2\xce\xa4\x7b\x70\xee\xa6\xc4\xf2\x1b\x45\x4f\x5c\x4e\xd3\x49\xf8\xce\xb2\x38\x3d\x79\xf5\xfa\xaf\x4d\x44\x38\xee\x6f\x99\xb5\x11\x52\xae\xca\x34\x93\x43\xe3\x2e\xb0\xd2\xcc\x4f\x62\xf3\x91\x37\x47\x24\x65\x9d\x71\x85\xa4\x20\x5b\x24\x3a\x11\x77\x7f\x65\x3c\x02\xcc\x43\x6d\x3f\xdc\xec\x49\xe2\x8c\xbc\x58\xbb\xbe\x33\x9d\x6a\x8a\xcd\x26\x1c\x8a\xb7\x74\x0a\x29\x70\x4e\xa5\xa7\x81\x48\x96\x47\x5a\x75\x9d\x3f\xee\xad\x8e\xf5\xff\x2f\x63\xb8\x1b\xac\x94\x8c\x7c\x41\x3c\x51\xfc\x73\x5d\x94\x7b\x8c\xa4\x94\x00\xd1\x25\x55\xae\x0f\xfd\x82\xc5\xce\xfb\x9d\x3f\x1b\xc7\x7c\xa0\x70\x07\x00\x00")
 
 func dataLocalesJsonBytes() ([]byte, error) {
 	return bindataRead(
@@ -146,7 +146,7 @@ func dataLocalesJson() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "data/locales.json", size: 296, mode: os.FileMode(420), modTime: time.Unix(1502238721, 0)}
+	info := bindataFileInfo{name: "data/locales.json", size: 1904, mode: os.FileMode(420), modTime: time.Unix(1502238721, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -0,0 +1,155 @@
+package dialer
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+)
+
+// Balancing policies of WithBalancer
+const (
+	// RoundRobin sends calls to each instance in turn (default)
+	RoundRobin = "round_robin"
+	// LeastRequest sends calls to the instance with the fewest calls in
+	// flight of two picked at random
+	LeastRequest = "least_request"
+	// WeightedRoundRobin sends calls to each instance in turn, in proportion
+	// to the weight it registered with
+	WeightedRoundRobin = "weighted_rr"
+	// ZoneAware sends calls round robin to the instances in the zone of the
+	// caller, and to all instances when none of them is in it
+	ZoneAware = "zone_aware"
+)
+
+func init() {
+	balancer.Register(base.NewBalancerBuilder(LeastRequest, leastRequestBuilder{}, base.Config{}))
+	balancer.Register(base.NewBalancerBuilder(WeightedRoundRobin, weightedBuilder{}, base.Config{}))
+	balancer.Register(base.NewBalancerBuilder(ZoneAware, zoneAwareBuilder{}, base.Config{}))
+}
+
+// leastRequestBuilder builds power of two choices pickers. Counts of calls in
+// flight start over when the set of ready instances changes.
+type leastRequestBuilder struct{}
+
+func (leastRequestBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	p := &leastRequestPicker{}
+	for sc := range info.ReadySCs {
+		p.subConns = append(p.subConns, &countedSubConn{sc: sc})
+	}
+	return p
+}
+
+type countedSubConn struct {
+	sc       balancer.SubConn
+	inflight atomic.Int64
+}
+
+type leastRequestPicker struct {
+	subConns []*countedSubConn
+}
+
+func (p *leastRequestPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	n := len(p.subConns)
+	i := rand.Intn(n)
+	c := p.subConns[i]
+	if n > 1 {
+		// a second instance, other than the first
+		j := rand.Intn(n - 1)
+		if j >= i {
+			j++
+		}
+		if other := p.subConns[j]; other.inflight.Load() < c.inflight.Load() {
+			c = other
+		}
+	}
+
+	c.inflight.Add(1)
+	return balancer.PickResult{
+		SubConn: c.sc,
+		Done:    func(balancer.DoneInfo) { c.inflight.Add(-1) },
+	}, nil
+}
+
+// weightedBuilder builds smooth weighted round robin pickers, which spread
+// the calls of heavier instances over the round instead of sending them in
+// a burst
+type weightedBuilder struct{}
+
+func (weightedBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	p := &weightedPicker{}
+	for sc, sci := range info.ReadySCs {
+		w := int64(registry.EndpointOf(sci.Address).Weight)
+		p.subConns = append(p.subConns, &weightedSubConn{sc: sc, weight: w})
+		p.total += w
+	}
+	return p
+}
+
+type weightedSubConn struct {
+	sc      balancer.SubConn
+	weight  int64
+	current int64
+}
+
+type weightedPicker struct {
+	mu       sync.Mutex
+	subConns []*weightedSubConn
+	total    int64
+}
+
+func (p *weightedPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var best *weightedSubConn
+	for _, c := range p.subConns {
+		c.current += c.weight
+		if best == nil || c.current > best.current {
+			best = c
+		}
+	}
+	best.current -= p.total
+	return balancer.PickResult{SubConn: best.sc}, nil
+}
+
+// zoneAwareBuilder builds round robin pickers over the instances in the zone
+// of the caller, see registry.LocalZone
+type zoneAwareBuilder struct{}
+
+func (zoneAwareBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	var local, all []balancer.SubConn
+	zone := registry.LocalZone()
+	for sc, sci := range info.ReadySCs {
+		all = append(all, sc)
+		if zone != "" && registry.EndpointOf(sci.Address).Zone == zone {
+			local = append(local, sc)
+		}
+	}
+	if len(local) == 0 {
+		local = all
+	}
+	return &roundRobinPicker{subConns: local, next: uint32(rand.Intn(len(local)))}
+}
+
+type roundRobinPicker struct {
+	subConns []balancer.SubConn
+	next     uint32
+}
+
+func (p *roundRobinPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	i := atomic.AddUint32(&p.next, 1)
+	return balancer.PickResult{SubConn: p.subConns[i%uint32(len(p.subConns))]}, nil
+}
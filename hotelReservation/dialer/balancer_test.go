@@ -0,0 +1,242 @@
+package dialer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+)
+
+// subConn stands for the connection to one instance
+type subConn struct {
+	balancer.SubConn
+	addr string
+}
+
+// ready returns the build info of one ready connection per address
+func ready(addrs ...resolver.Address) base.PickerBuildInfo {
+	info := base.PickerBuildInfo{ReadySCs: map[balancer.SubConn]base.SubConnInfo{}}
+	for _, a := range addrs {
+		info.ReadySCs[&subConn{addr: a.Addr}] = base.SubConnInfo{Address: a}
+	}
+	return info
+}
+
+// picks counts the instances n picks of p go to, leaving the calls in flight
+func picks(t *testing.T, p balancer.Picker, n int) map[string]int {
+	t.Helper()
+	counts := map[string]int{}
+	for i := 0; i < n; i++ {
+		res, err := p.Pick(balancer.PickInfo{})
+		if err != nil {
+			t.Fatalf("pick %d: %v", i, err)
+		}
+		counts[res.SubConn.(*subConn).addr]++
+	}
+	return counts
+}
+
+func TestPickersWithoutInstances(t *testing.T) {
+	for name, b := range map[string]base.PickerBuilder{
+		LeastRequest:       leastRequestBuilder{},
+		WeightedRoundRobin: weightedBuilder{},
+		ZoneAware:          zoneAwareBuilder{},
+	} {
+		if _, err := b.Build(ready()).Pick(balancer.PickInfo{}); !errors.Is(err, balancer.ErrNoSubConnAvailable) {
+			t.Errorf("%s: got %v, want ErrNoSubConnAvailable", name, err)
+		}
+	}
+}
+
+func TestLeastRequestPicker(t *testing.T) {
+	p := leastRequestBuilder{}.Build(ready(
+		resolver.Address{Addr: "10.0.0.1:8080"},
+		resolver.Address{Addr: "10.0.0.2:8080"},
+	))
+
+	// with two instances both are always compared, so calls left in flight
+	// alternate between them
+	var held []balancer.PickResult
+	counts := map[string]int{}
+	for i := 0; i < 100; i++ {
+		res, err := p.Pick(balancer.PickInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		held = append(held, res)
+		counts[res.SubConn.(*subConn).addr]++
+		if d := counts["10.0.0.1:8080"] - counts["10.0.0.2:8080"]; d < -1 || d > 1 {
+			t.Fatalf("pick %d: got %v calls in flight", i, counts)
+		}
+	}
+
+	// finished calls are no longer in flight
+	for _, res := range held {
+		res.Done(balancer.DoneInfo{})
+	}
+	for _, c := range p.(*leastRequestPicker).subConns {
+		if n := c.inflight.Load(); n != 0 {
+			t.Errorf("got %d calls in flight after all finished", n)
+		}
+	}
+
+	// an instance busy with a slow call is passed over whenever it is
+	// compared with an idle one
+	slow, err := p.Pick(balancer.PickInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	busy := slow.SubConn.(*subConn).addr
+	for i := 0; i < 20; i++ {
+		res, err := p.Pick(balancer.PickInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.SubConn.(*subConn).addr == busy {
+			t.Fatalf("pick %d went to the busy instance", i)
+		}
+		res.Done(balancer.DoneInfo{})
+	}
+}
+
+func TestWeightedPicker(t *testing.T) {
+	tests := []struct {
+		name  string
+		addrs []resolver.Address
+		want  map[string]int
+	}{
+		{
+			name: "shares by weight",
+			addrs: []resolver.Address{
+				registry.Endpoint{Weight: 1}.Address("a"),
+				registry.Endpoint{Weight: 2}.Address("b"),
+				registry.Endpoint{Weight: 5}.Address("c"),
+			},
+			want: map[string]int{"a": 100, "b": 200, "c": 500},
+		},
+		{
+			name: "equal weights",
+			addrs: []resolver.Address{
+				registry.Endpoint{Weight: 3}.Address("a"),
+				registry.Endpoint{Weight: 3}.Address("b"),
+			},
+			want: map[string]int{"a": 400, "b": 400},
+		},
+		{
+			name: "addresses without a weight count as 1",
+			addrs: []resolver.Address{
+				{Addr: "a"},
+				registry.Endpoint{Weight: 3}.Address("b"),
+			},
+			want: map[string]int{"a": 200, "b": 600},
+		},
+		{
+			name:  "single instance",
+			addrs: []resolver.Address{registry.Endpoint{Weight: 7}.Address("a")},
+			want:  map[string]int{"a": 800},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := picks(t, weightedBuilder{}.Build(ready(tt.addrs...)), 800)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for addr, n := range tt.want {
+				if got[addr] != n {
+					t.Errorf("%s: got %d picks, want %d", addr, got[addr], n)
+				}
+			}
+		})
+	}
+}
+
+func TestWeightedPickerIsSmooth(t *testing.T) {
+	p := weightedBuilder{}.Build(ready(
+		registry.Endpoint{Weight: 1}.Address("a"),
+		registry.Endpoint{Weight: 4}.Address("b"),
+	))
+
+	// the calls of b are spread over the round rather than sent in a burst
+	run := 0
+	for i := 0; i < 50; i++ {
+		res, err := p.Pick(balancer.PickInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.SubConn.(*subConn).addr == "b" {
+			run++
+		} else {
+			run = 0
+		}
+		if run > 4 {
+			t.Fatalf("pick %d: b got %d calls in a row", i, run)
+		}
+	}
+}
+
+func TestZoneAwarePicker(t *testing.T) {
+	tests := []struct {
+		name  string
+		zone  string
+		addrs []resolver.Address
+		want  map[string]int
+	}{
+		{
+			name: "only instances in the zone",
+			zone: "eu-1",
+			addrs: []resolver.Address{
+				registry.Endpoint{Zone: "eu-1"}.Address("a"),
+				registry.Endpoint{Zone: "eu-1"}.Address("b"),
+				registry.Endpoint{Zone: "us-1"}.Address("c"),
+			},
+			want: map[string]int{"a": 50, "b": 50},
+		},
+		{
+			name: "all instances when none is in the zone",
+			zone: "ap-1",
+			addrs: []resolver.Address{
+				registry.Endpoint{Zone: "eu-1"}.Address("a"),
+				registry.Endpoint{Zone: "us-1"}.Address("b"),
+			},
+			want: map[string]int{"a": 50, "b": 50},
+		},
+		{
+			name: "all instances without a local zone",
+			zone: "",
+			addrs: []resolver.Address{
+				registry.Endpoint{Zone: "eu-1"}.Address("a"),
+				{Addr: "b"},
+			},
+			want: map[string]int{"a": 50, "b": 50},
+		},
+		{
+			name: "instances without a zone are not local",
+			zone: "eu-1",
+			addrs: []resolver.Address{
+				registry.Endpoint{Zone: "eu-1"}.Address("a"),
+				{Addr: "b"},
+			},
+			want: map[string]int{"a": 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DSB_ZONE", tt.zone)
+			got := picks(t, zoneAwareBuilder{}.Build(ready(tt.addrs...)), 100)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for addr, n := range tt.want {
+				if got[addr] != n {
+					t.Errorf("%s: got %d picks, want %d", addr, got[addr], n)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package dialer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BreakerPolicy configures the circuit breaker of a connection
+type BreakerPolicy struct {
+	// Failures is the number of consecutive failed calls opening the breaker
+	Failures int
+	// OpenTimeout is how long an open breaker fails calls before letting
+	// one through to probe the service
+	OpenTimeout time.Duration
+}
+
+// DefaultBreakerPolicy opens after 5 failed calls in a row and probes the
+// service again after 5s
+var DefaultBreakerPolicy = BreakerPolicy{
+	Failures:    5,
+	OpenTimeout: 5 * time.Second,
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+var (
+	meter = otel.Meter("github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/dialer")
+
+	breakerTransitions, _ = meter.Int64Counter("rpc.client.breaker.transitions",
+		metric.WithDescription("Circuit breaker state changes, by target and new state"))
+	breakerRejected, _ = meter.Int64Counter("rpc.client.breaker.rejected",
+		metric.WithDescription("Calls failed by an open circuit breaker, by target"))
+	breakerOpenGauge, _ = meter.Int64UpDownCounter("rpc.client.breaker.open",
+		metric.WithDescription("Circuit breakers not closed, by target"))
+)
+
+// WithCircuitBreaker fails calls at once with UNAVAILABLE while the service
+// keeps failing, instead of having every caller wait for it. State changes
+// are added as events to the span of the call causing them.
+func WithCircuitBreaker(p BreakerPolicy) DialOption {
+	return func(name string, o *dialOptions) error {
+		if p.Failures <= 0 || p.OpenTimeout <= 0 {
+			return fmt.Errorf("invalid circuit breaker policy: %+v", p)
+		}
+		o.breaker = &breaker{target: name, policy: p}
+		return nil
+	}
+}
+
+// breaker counts consecutive failed calls. Once open it rejects calls until
+// OpenTimeout passes, then lets a single call through: the breaker closes if
+// it succeeds and opens again if not.
+type breaker struct {
+	target string
+	policy BreakerPolicy
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func (b *breaker) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if !b.allow(ctx) {
+		breakerRejected.Add(ctx, 1, metric.WithAttributes(attribute.String("rpc.target", b.target)))
+		trace.SpanFromContext(ctx).AddEvent("circuit_breaker.rejected",
+			trace.WithAttributes(attribute.String("rpc.target", b.target)))
+		return status.Errorf(codes.Unavailable, "circuit breaker open for %s", b.target)
+	}
+
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	b.done(ctx, err)
+	return err
+}
+
+// allow reports whether a call may go through
+func (b *breaker) allow(ctx context.Context) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.policy.OpenTimeout {
+			return false
+		}
+		b.transition(ctx, breakerHalfOpen)
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// done records the outcome of a call let through
+func (b *breaker) done(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// a call cut short by the deadline or cancellation of its caller says
+	// nothing about the service
+	callerDone := ctx.Err() != nil
+
+	if b.state == breakerHalfOpen {
+		b.probing = false
+		if callerDone {
+			// the next call probes instead
+			return
+		}
+		if isFailure(err) {
+			b.transition(ctx, breakerOpen)
+		} else {
+			b.failures = 0
+			b.transition(ctx, breakerClosed)
+		}
+		return
+	}
+
+	if callerDone {
+		return
+	}
+	if !isFailure(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerClosed && b.failures >= b.policy.Failures {
+		b.transition(ctx, breakerOpen)
+	}
+}
+
+// transition moves the breaker to state, b.mu held
+func (b *breaker) transition(ctx context.Context, state breakerState) {
+	from := b.state
+	if from == state {
+		return
+	}
+	b.state = state
+	if state == breakerOpen {
+		b.openedAt = time.Now()
+	}
+
+	target := attribute.String("rpc.target", b.target)
+	breakerTransitions.Add(ctx, 1, metric.WithAttributes(target, attribute.String("state", state.String())))
+	if from == breakerClosed {
+		breakerOpenGauge.Add(ctx, 1, metric.WithAttributes(target))
+	} else if state == breakerClosed {
+		breakerOpenGauge.Add(ctx, -1, metric.WithAttributes(target))
+	}
+	trace.SpanFromContext(ctx).AddEvent("circuit_breaker.state_change", trace.WithAttributes(
+		target,
+		attribute.String("circuit_breaker.from", from.String()),
+		attribute.String("circuit_breaker.to", state.String()),
+	))
+	log.Warn().Msgf("Circuit breaker of %s is %s, was %s", b.target, state, from)
+}
+
+// isFailure reports whether err means the service is in trouble, as opposed
+// to a call the service rightly refused
+func isFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,216 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errUnavailable = status.Error(codes.Unavailable, "connection refused")
+	errNotFound    = status.Error(codes.NotFound, "no such hotel")
+)
+
+func newTestBreaker(failures int) *breaker {
+	return &breaker{target: "srv-test", policy: BreakerPolicy{Failures: failures, OpenTimeout: time.Hour}}
+}
+
+// call makes a call through b that the service answers with err
+func call(ctx context.Context, b *breaker, err error) error {
+	return b.intercept(ctx, "/test.Test/Call", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return err
+		})
+}
+
+// expire makes the open timeout of b pass
+func expire(b *breaker) {
+	b.mu.Lock()
+	b.openedAt = time.Now().Add(-b.policy.OpenTimeout)
+	b.mu.Unlock()
+}
+
+func (b *breaker) current() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func TestBreakerOpensAfterFailures(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBreaker(3)
+
+	for i := 0; i < 2; i++ {
+		call(ctx, b, errUnavailable)
+	}
+	if b.current() != breakerClosed {
+		t.Fatalf("got %s after 2 failures, want closed", b.current())
+	}
+
+	// a success resets the count
+	call(ctx, b, nil)
+	for i := 0; i < 2; i++ {
+		call(ctx, b, errUnavailable)
+	}
+	if b.current() != breakerClosed {
+		t.Fatalf("got %s after a success and 2 failures, want closed", b.current())
+	}
+
+	// errors the service answers with on purpose are no failures
+	call(ctx, b, errNotFound)
+	call(ctx, b, errNotFound)
+	if b.current() != breakerClosed {
+		t.Fatalf("got %s after NOT_FOUND answers, want closed", b.current())
+	}
+
+	for i := 0; i < 3; i++ {
+		call(ctx, b, status.Error(codes.DeadlineExceeded, "too slow"))
+	}
+	if b.current() != breakerOpen {
+		t.Fatalf("got %s after 3 failures, want open", b.current())
+	}
+
+	called := false
+	err := b.intercept(ctx, "/test.Test/Call", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			called = true
+			return nil
+		})
+	if called || status.Code(err) != codes.Unavailable {
+		t.Errorf("open breaker let a call through: called=%v, err=%v", called, err)
+	}
+}
+
+func TestBreakerHalfOpenSingleProbe(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBreaker(1)
+	call(ctx, b, errUnavailable)
+	expire(b)
+
+	probing := make(chan struct{})
+	release := make(chan struct{})
+	probed := make(chan error)
+	go func() {
+		probed <- b.intercept(ctx, "/test.Test/Call", nil, nil, nil,
+			func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				close(probing)
+				<-release
+				return nil
+			})
+	}()
+	<-probing
+
+	if b.current() != breakerHalfOpen {
+		t.Fatalf("got %s while probing, want half-open", b.current())
+	}
+	// other calls are rejected while the probe runs
+	for i := 0; i < 3; i++ {
+		if err := call(ctx, b, nil); status.Code(err) != codes.Unavailable {
+			t.Errorf("call during the probe: got %v, want UNAVAILABLE", err)
+		}
+	}
+
+	close(release)
+	if err := <-probed; err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if b.current() != breakerClosed {
+		t.Errorf("got %s after a successful probe, want closed", b.current())
+	}
+}
+
+func TestBreakerProbeOutcome(t *testing.T) {
+	tests := []struct {
+		name  string
+		probe error
+		want  breakerState
+	}{
+		{"success closes", nil, breakerClosed},
+		{"refused call closes", errNotFound, breakerClosed},
+		{"failure opens again", errUnavailable, breakerOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			b := newTestBreaker(2)
+			call(ctx, b, errUnavailable)
+			call(ctx, b, errUnavailable)
+			expire(b)
+
+			if err := call(ctx, b, tt.probe); !errors.Is(err, tt.probe) {
+				t.Fatalf("probe: got %v, want %v", err, tt.probe)
+			}
+			if b.current() != tt.want {
+				t.Fatalf("got %s, want %s", b.current(), tt.want)
+			}
+
+			switch tt.want {
+			case breakerOpen:
+				// the open timeout starts over
+				if err := call(ctx, b, nil); status.Code(err) != codes.Unavailable {
+					t.Errorf("got %v right after a failed probe, want UNAVAILABLE", err)
+				}
+			case breakerClosed:
+				// failures are counted from zero again
+				call(ctx, b, errUnavailable)
+				if b.current() != breakerClosed {
+					t.Errorf("got %s after one failure, want closed", b.current())
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerIgnoresCallerCancellation(t *testing.T) {
+	b := newTestBreaker(2)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		call(cancelled, b, status.Error(codes.Canceled, "context canceled"))
+		call(expired, b, status.Error(codes.DeadlineExceeded, "context deadline exceeded"))
+	}
+	if b.current() != breakerClosed {
+		t.Fatalf("got %s after calls cut short by their callers, want closed", b.current())
+	}
+
+	// nor does a cancelled probe decide the state: the next call probes
+	call(context.Background(), b, errUnavailable)
+	call(context.Background(), b, errUnavailable)
+	expire(b)
+	call(cancelled, b, status.Error(codes.Canceled, "context canceled"))
+	if b.current() != breakerHalfOpen {
+		t.Fatalf("got %s after a cancelled probe, want half-open", b.current())
+	}
+	if err := call(context.Background(), b, nil); err != nil {
+		t.Fatalf("next probe: %v", err)
+	}
+	if b.current() != breakerClosed {
+		t.Errorf("got %s after the next probe succeeded, want closed", b.current())
+	}
+}
+
+func TestCircuitBreakerPolicy(t *testing.T) {
+	for _, p := range []BreakerPolicy{
+		{Failures: 0, OpenTimeout: time.Second},
+		{Failures: 3, OpenTimeout: 0},
+		{Failures: -1, OpenTimeout: -time.Second},
+	} {
+		if err := WithCircuitBreaker(p)("srv-test", &dialOptions{}); err == nil {
+			t.Errorf("policy %+v accepted", p)
+		}
+	}
+	o := &dialOptions{}
+	if err := WithCircuitBreaker(DefaultBreakerPolicy)("srv-test", o); err != nil || o.breaker == nil {
+		t.Errorf("default policy: %v", err)
+	}
+}
@@ -2,10 +2,10 @@ package dialer
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
-	consul "github.com/hashicorp/consul/api"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
@@ -13,19 +13,43 @@ import (
 )
 
 // DialOption allows optional config for dialer
-type DialOption func(name string) (grpc.DialOption, error)
+type DialOption func(name string, o *dialOptions) error
+
+// dialOptions collects the DialOptions of a Dial, as several of them end up
+// in the same service config or interceptor chain
+type dialOptions struct {
+	tracing  bool
+	balancer string
+	methods  map[string]*methodConfig
+	breaker  *breaker
+}
 
 // WithTracer traces rpc calls
 func WithTracer(tracer trace.Tracer) DialOption {
-	return func(name string) (grpc.DialOption, error) {
-		return grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()), nil
+	return func(name string, o *dialOptions) error {
+		o.tracing = true
+		return nil
 	}
 }
 
-// WithBalancer enables client side load balancing
-func WithBalancer(registry *consul.Client) DialOption {
-	return func(name string) (grpc.DialOption, error) {
-		return grpc.WithDefaultServiceConfig(`{"loadBalancingConfig": [{"round_robin":{}}]}`), nil
+// WithBalancer enables client side load balancing with policy, one of
+// RoundRobin, LeastRequest, WeightedRoundRobin and ZoneAware. An empty
+// policy takes the one set in the RPC_BALANCER environment variable, round
+// robin by default.
+func WithBalancer(policy string) DialOption {
+	return func(name string, o *dialOptions) error {
+		if policy == "" {
+			policy = os.Getenv("RPC_BALANCER")
+		}
+		switch policy {
+		case "":
+			o.balancer = RoundRobin
+		case RoundRobin, LeastRequest, WeightedRoundRobin, ZoneAware:
+			o.balancer = policy
+		default:
+			return fmt.Errorf("unknown balancer %q", policy)
+		}
+		return nil
 	}
 }
 
@@ -45,12 +69,31 @@ func Dial(name string, opts ...DialOption) (*grpc.ClientConn, error) {
 		dialopts = append(dialopts, grpc.WithInsecure())
 	}
 
+	o := &dialOptions{}
 	for _, fn := range opts {
-		opt, err := fn(name)
-		if err != nil {
+		if err := fn(name, o); err != nil {
 			return nil, fmt.Errorf("config error: %v", err)
 		}
-		dialopts = append(dialopts, opt)
+	}
+
+	sc, err := o.serviceConfig()
+	if err != nil {
+		return nil, fmt.Errorf("config error: %v", err)
+	}
+	if sc != "" {
+		dialopts = append(dialopts, grpc.WithDefaultServiceConfig(sc))
+	}
+
+	// the breaker runs inside the call span, so its events land there
+	var interceptors []grpc.UnaryClientInterceptor
+	if o.tracing {
+		interceptors = append(interceptors, otelgrpc.UnaryClientInterceptor())
+	}
+	if o.breaker != nil {
+		interceptors = append(interceptors, o.breaker.intercept)
+	}
+	if len(interceptors) > 0 {
+		dialopts = append(dialopts, grpc.WithChainUnaryInterceptor(interceptors...))
 	}
 
 	conn, err := grpc.Dial(name, dialopts...)
@@ -0,0 +1,277 @@
+package dialer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Methods are named as in the gRPC service config: "" for every method of
+// the connection, "geo.Geo" for every method of a service and
+// "geo.Geo/Nearby" for one method.
+
+// RetryPolicy retries calls failing with one of Codes, waiting a growing
+// backoff between attempts. Attempts are capped at 5 by gRPC.
+type RetryPolicy struct {
+	MaxAttempts       int
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	Codes             []codes.Code
+}
+
+// DefaultRetryPolicy tries a call up to 3 times while the server is unavailable
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:       3,
+	InitialBackoff:    50 * time.Millisecond,
+	MaxBackoff:        500 * time.Millisecond,
+	BackoffMultiplier: 2,
+	Codes:             []codes.Code{codes.Unavailable},
+}
+
+// HedgingPolicy sends a copy of a call every Delay until one answers, up to
+// MaxAttempts copies, so one slow instance does not hold the call. A copy
+// failing with a code outside NonFatalCodes ends the call. Only for methods
+// that are safe to run more than once.
+type HedgingPolicy struct {
+	MaxAttempts   int
+	Delay         time.Duration
+	NonFatalCodes []codes.Code
+}
+
+// methodConfig is one methodConfig entry of the gRPC service config
+type methodConfig struct {
+	Name          []methodName   `json:"name"`
+	Timeout       string         `json:"timeout,omitempty"`
+	RetryPolicy   *retryPolicy   `json:"retryPolicy,omitempty"`
+	HedgingPolicy *hedgingPolicy `json:"hedgingPolicy,omitempty"`
+}
+
+type methodName struct {
+	Service string `json:"service,omitempty"`
+	Method  string `json:"method,omitempty"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int          `json:"maxAttempts"`
+	InitialBackoff       string       `json:"initialBackoff"`
+	MaxBackoff           string       `json:"maxBackoff"`
+	BackoffMultiplier    float64      `json:"backoffMultiplier"`
+	RetryableStatusCodes []codes.Code `json:"retryableStatusCodes"`
+}
+
+type hedgingPolicy struct {
+	MaxAttempts         int          `json:"maxAttempts"`
+	HedgingDelay        string       `json:"hedgingDelay,omitempty"`
+	NonFatalStatusCodes []codes.Code `json:"nonFatalStatusCodes,omitempty"`
+}
+
+// WithTimeout sets the deadline of calls to method, unless the caller
+// already set a shorter one
+func WithTimeout(method string, d time.Duration) DialOption {
+	return func(name string, o *dialOptions) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout of %q must be positive", method)
+		}
+		mc, err := o.method(method)
+		if err != nil {
+			return err
+		}
+		mc.Timeout = duration(d)
+		return nil
+	}
+}
+
+// WithRetry retries failed calls to method according to p
+func WithRetry(method string, p RetryPolicy) DialOption {
+	return func(name string, o *dialOptions) error {
+		if p.MaxAttempts < 2 || p.InitialBackoff <= 0 || p.MaxBackoff <= 0 || p.BackoffMultiplier <= 0 || len(p.Codes) == 0 {
+			return fmt.Errorf("invalid retry policy of %q: %+v", method, p)
+		}
+		mc, err := o.method(method)
+		if err != nil {
+			return err
+		}
+		if mc.HedgingPolicy != nil {
+			return fmt.Errorf("%q has both retry and hedging policies", method)
+		}
+		mc.RetryPolicy = &retryPolicy{
+			MaxAttempts:          p.MaxAttempts,
+			InitialBackoff:       duration(p.InitialBackoff),
+			MaxBackoff:           duration(p.MaxBackoff),
+			BackoffMultiplier:    p.BackoffMultiplier,
+			RetryableStatusCodes: p.Codes,
+		}
+		return nil
+	}
+}
+
+// WithHedging hedges calls to method according to p
+func WithHedging(method string, p HedgingPolicy) DialOption {
+	return func(name string, o *dialOptions) error {
+		if p.MaxAttempts < 2 || p.Delay < 0 {
+			return fmt.Errorf("invalid hedging policy of %q: %+v", method, p)
+		}
+		mc, err := o.method(method)
+		if err != nil {
+			return err
+		}
+		if mc.RetryPolicy != nil {
+			return fmt.Errorf("%q has both retry and hedging policies", method)
+		}
+		mc.HedgingPolicy = &hedgingPolicy{
+			MaxAttempts:         p.MaxAttempts,
+			NonFatalStatusCodes: p.NonFatalCodes,
+		}
+		if p.Delay > 0 {
+			mc.HedgingPolicy.HedgingDelay = duration(p.Delay)
+		}
+		return nil
+	}
+}
+
+// WithEnvPolicies applies the call policies set in the environment, for
+// every method of the connection. All are off unless set:
+//   - RPC_TIMEOUT: deadline of calls, as in 2s
+//   - RPC_MAX_ATTEMPTS: attempts of calls failing with UNAVAILABLE, with the
+//     backoff of DefaultRetryPolicy
+//   - RPC_BREAKER_FAILURES: consecutive failures opening the circuit breaker,
+//     which stays open for RPC_BREAKER_OPEN_TIMEOUT (5s by default)
+func WithEnvPolicies() DialOption {
+	return func(name string, o *dialOptions) error {
+		if v := os.Getenv("RPC_TIMEOUT"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid RPC_TIMEOUT %q", v)
+			}
+			if err := WithTimeout("", d)(name, o); err != nil {
+				return err
+			}
+		}
+		if v := os.Getenv("RPC_MAX_ATTEMPTS"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid RPC_MAX_ATTEMPTS %q", v)
+			}
+			if n > 1 {
+				p := DefaultRetryPolicy
+				p.MaxAttempts = n
+				if err := WithRetry("", p)(name, o); err != nil {
+					return err
+				}
+			}
+		}
+		if v := os.Getenv("RPC_BREAKER_FAILURES"); v != "" {
+			p := DefaultBreakerPolicy
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid RPC_BREAKER_FAILURES %q", v)
+			}
+			p.Failures = n
+			if v := os.Getenv("RPC_BREAKER_OPEN_TIMEOUT"); v != "" {
+				if p.OpenTimeout, err = time.ParseDuration(v); err != nil {
+					return fmt.Errorf("invalid RPC_BREAKER_OPEN_TIMEOUT %q", v)
+				}
+			}
+			if n > 0 {
+				return WithCircuitBreaker(p)(name, o)
+			}
+		}
+		return nil
+	}
+}
+
+// method returns the config of method, adding it if needed
+func (o *dialOptions) method(method string) (*methodConfig, error) {
+	var n methodName
+	if method != "" {
+		parts := strings.Split(strings.TrimPrefix(method, "/"), "/")
+		if len(parts) > 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid method name %q", method)
+		}
+		n.Service = parts[0]
+		if len(parts) == 2 {
+			n.Method = parts[1]
+		}
+	}
+
+	key := n.Service + "/" + n.Method
+	if o.methods == nil {
+		o.methods = make(map[string]*methodConfig)
+	}
+	mc, ok := o.methods[key]
+	if !ok {
+		mc = &methodConfig{Name: []methodName{n}}
+		o.methods[key] = mc
+	}
+	return mc, nil
+}
+
+// serviceConfig returns the JSON service config of the options, empty if
+// they do not need one
+func (o *dialOptions) serviceConfig() (string, error) {
+	var sc struct {
+		LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig,omitempty"`
+		MethodConfig        []*methodConfig       `json:"methodConfig,omitempty"`
+	}
+	if o.balancer != "" {
+		sc.LoadBalancingConfig = []map[string]struct{}{{o.balancer: {}}}
+	}
+	keys := make([]string, 0, len(o.methods))
+	for k := range o.methods {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sc.MethodConfig = append(sc.MethodConfig, o.inherit(o.methods[k]))
+	}
+	if sc.LoadBalancingConfig == nil && sc.MethodConfig == nil {
+		return "", nil
+	}
+
+	b, err := json.Marshal(sc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// inherit returns mc with the policies it does not set taken from the
+// config of its service, then from the default config. gRPC only applies the
+// most specific config of a method, so a timeout on one method would
+// otherwise drop the retries set for all of them.
+func (o *dialOptions) inherit(mc *methodConfig) *methodConfig {
+	n := mc.Name[0]
+	parents := []string{"/"}
+	if n.Method != "" {
+		parents = []string{n.Service + "/", "/"}
+	} else if n.Service == "" {
+		return mc
+	}
+
+	out := *mc
+	for _, key := range parents {
+		p, ok := o.methods[key]
+		if !ok {
+			continue
+		}
+		if out.Timeout == "" {
+			out.Timeout = p.Timeout
+		}
+		if out.RetryPolicy == nil && out.HedgingPolicy == nil {
+			out.RetryPolicy, out.HedgingPolicy = p.RetryPolicy, p.HedgingPolicy
+		}
+	}
+	return &out
+}
+
+// duration formats d as a JSON protobuf duration
+func duration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
@@ -49,6 +49,13 @@ spec:
             value: {{ $value | quote }}
           {{- end }}
         {{- end }}
+        {{- if .sessionSecret }}
+          - name: SESSION_SECRET
+            valueFrom:
+              secretKeyRef:
+                name: {{ $.Values.global.sessionSecretName }}
+                key: session-secret
+        {{- end }}
         {{- if .command}}
         command:
         - {{ .command }}
@@ -1,6 +1,18 @@
 {{- define "hotelreservation.templates.service-config.json" }}
 {
     "consulAddress": "consul-{{ include "hotel-reservation.fullname" . }}.{{ .Release.Namespace }}.svc.{{ .Values.global.serviceDnsDomain }}:8500",
+    "Registry": "{{ .Values.global.registry }}",
+    "RegistryFile": "",
+    "RegistryDNSName": "%s-{{ include "hotel-reservation.fullname" . }}.{{ .Release.Namespace }}.svc.{{ .Values.global.serviceDnsDomain }}",
+    "ConsulCheck": "{{ .Values.global.consulCheck }}",
+    "ConsulCheckInterval": "{{ .Values.global.consulCheckInterval }}",
+    "ConsulDeregisterAfter": "{{ .Values.global.consulDeregisterAfter }}",
+    "TLSCACert": "x509/ca_cert.pem",
+    "TLSCert": "x509/server_cert.pem",
+    "TLSKey": "x509/server_key.pem",
+    "TLSServerName": "x.test.example.com",
+    "TLSTrustDomain": "{{ .Values.global.tlsTrustDomain }}",
+    "TLSReloadInterval": "{{ .Values.global.tlsReloadInterval }}",
     "jaegerAddress": "jaeger-{{ include "hotel-reservation.fullname" . }}.{{ .Release.Namespace }}.svc.{{ .Values.global.serviceDnsDomain }}:6831",
     "FrontendPort": "5000",
     "GeoPort": "8083",
@@ -20,7 +32,14 @@
     "UserPort": "8086",
     "UserMongoAddress": "mongodb-user-{{ include "hotel-reservation.fullname" . }}.{{ .Release.Namespace }}.svc.{{ .Values.global.serviceDnsDomain }}:27023",
     "AttractionsPort": "8089",
-    "AttractionsMongoAddress": "mongodb-attractions-{{ include "hotel-reservation.fullname" . }}.{{ .Release.Namespace }}.svc.{{ .Values.global.serviceDnsDomain }}:27024"
+    "AttractionsMongoAddress": "mongodb-attractions-{{ include "hotel-reservation.fullname" . }}.{{ .Release.Namespace }}.svc.{{ .Values.global.serviceDnsDomain }}:27024",
+    "ImagePort": "8090",
+    "ImageMongoAddress": "mongodb-image-{{ include "hotel-reservation.fullname" . }}.{{ .Release.Namespace }}.svc.{{ .Values.global.serviceDnsDomain }}:27025",
+    "AuthMode": "{{ .Values.global.authMode }}",
+    "RecommendRatingSource": "{{ .Values.global.recommendRatingSource }}",
+    "RatePricingPolicy": "{{ .Values.global.ratePricingPolicy }}",
+    "RateBaseCurrency": "{{ .Values.global.rateBaseCurrency }}",
+    "RateCurrencies": "{{ .Values.global.rateCurrencies }}"
 }
 {{- end }}
 
@@ -0,0 +1,208 @@
+package liveindex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hailocab/go-geoindex"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultPollInterval is how often a collection is reloaded when change
+// streams are not available (e.g. a standalone mongod).
+const DefaultPollInterval = 30 * time.Second
+
+// Decoder turns a mongo document into an index point
+type Decoder func(raw bson.Raw) (geoindex.Point, error)
+
+// Index is a geo index that is safe for concurrent lookups and updates
+type Index struct {
+	mu    sync.RWMutex
+	index *geoindex.ClusteringIndex
+	// point id -> point, for lookups by id
+	points map[string]geoindex.Point
+	// mongo _id -> point id, needed to apply deletes from a change stream
+	docIds map[string]string
+}
+
+// New returns an empty index
+func New() *Index {
+	return &Index{
+		index:  geoindex.NewClusteringIndex(),
+		points: make(map[string]geoindex.Point),
+		docIds: make(map[string]string),
+	}
+}
+
+// Load returns an index filled with every document of the collection
+func Load(ctx context.Context, coll *mongo.Collection, decode Decoder) (*Index, error) {
+	idx := New()
+	if err := idx.Sync(ctx, coll, decode); err != nil {
+		return idx, err
+	}
+	return idx, nil
+}
+
+// Add inserts or replaces a point
+func (i *Index) Add(p geoindex.Point) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.index.Add(p)
+	i.points[p.Id()] = p
+}
+
+// Remove deletes a point by id
+func (i *Index) Remove(id string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.index.Remove(id)
+	delete(i.points, id)
+}
+
+// Get returns the point with the given id
+func (i *Index) Get(id string) (geoindex.Point, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	p, ok := i.points[id]
+	return p, ok
+}
+
+// KNearest returns the k nearest points within maxDistance that match accept
+func (i *Index) KNearest(point geoindex.Point, k int, maxDistance geoindex.Meters, accept func(p geoindex.Point) bool) []geoindex.Point {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.index.KNearest(point, k, maxDistance, accept)
+}
+
+// Sync rebuilds the index from the collection and swaps it in atomically,
+// so points deleted behind our back disappear as well.
+func (i *Index) Sync(ctx context.Context, coll *mongo.Collection, decode Decoder) error {
+	curr, err := coll.Find(ctx, bson.D{})
+	if err != nil {
+		return err
+	}
+	defer curr.Close(ctx)
+
+	index := geoindex.NewClusteringIndex()
+	points := make(map[string]geoindex.Point)
+	docIds := make(map[string]string)
+	for curr.Next(ctx) {
+		p, err := decode(curr.Current)
+		if err != nil {
+			log.Warn().Msgf("Skipping undecodable document in %s: %v", coll.Name(), err)
+			continue
+		}
+		index.Add(p)
+		points[p.Id()] = p
+		docIds[curr.Current.Lookup("_id").String()] = p.Id()
+	}
+	if err := curr.Err(); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	i.index = index
+	i.points = points
+	i.docIds = docIds
+	i.mu.Unlock()
+	return nil
+}
+
+// Watch keeps the index in sync with the collection until ctx is done.
+// It follows a change stream when the deployment supports one and falls
+// back to reloading the collection every interval otherwise.
+func (i *Index) Watch(ctx context.Context, coll *mongo.Collection, decode Decoder, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	for {
+		// open the stream before syncing so no change slips in between
+		stream, err := coll.Watch(ctx, mongo.Pipeline{},
+			options.ChangeStream().SetFullDocument(options.UpdateLookup))
+		if err != nil {
+			log.Debug().Msgf("Change stream unavailable on %s, polling instead: %v", coll.Name(), err)
+		}
+
+		if err := i.Sync(ctx, coll, decode); err != nil && ctx.Err() == nil {
+			log.Error().Msgf("Failed to reload %s: %v", coll.Name(), err)
+		}
+
+		if stream != nil {
+			if err := i.follow(ctx, stream, decode); err != nil && ctx.Err() == nil {
+				log.Warn().Msgf("Change stream on %s closed: %v", coll.Name(), err)
+			}
+			stream.Close(context.Background())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+type changeEvent struct {
+	OperationType string   `bson:"operationType"`
+	FullDocument  bson.Raw `bson:"fullDocument"`
+	DocumentKey   bson.Raw `bson:"documentKey"`
+}
+
+// follow applies change stream events to the index until the stream fails
+func (i *Index) follow(ctx context.Context, stream *mongo.ChangeStream, decode Decoder) error {
+	for stream.Next(ctx) {
+		var ev changeEvent
+		if err := stream.Decode(&ev); err != nil {
+			return err
+		}
+		if err := i.apply(ev, decode); err != nil {
+			return err
+		}
+	}
+	return stream.Err()
+}
+
+// apply applies a single change event to the index. It fails for events
+// that end the stream.
+func (i *Index) apply(ev changeEvent, decode Decoder) error {
+	docId := ev.DocumentKey.Lookup("_id").String()
+
+	switch ev.OperationType {
+	case "insert", "update", "replace":
+		if ev.FullDocument == nil {
+			// document was deleted before the lookup, a delete event follows
+			return nil
+		}
+		p, err := decode(ev.FullDocument)
+		if err != nil {
+			log.Warn().Msgf("Skipping undecodable change event: %v", err)
+			return nil
+		}
+		i.mu.Lock()
+		if old, ok := i.docIds[docId]; ok && old != p.Id() {
+			i.index.Remove(old)
+			delete(i.points, old)
+		}
+		i.index.Add(p)
+		i.points[p.Id()] = p
+		i.docIds[docId] = p.Id()
+		i.mu.Unlock()
+	case "delete":
+		i.mu.Lock()
+		if id, ok := i.docIds[docId]; ok {
+			i.index.Remove(id)
+			delete(i.points, id)
+			delete(i.docIds, docId)
+		}
+		i.mu.Unlock()
+	case "drop", "rename", "dropDatabase", "invalidate":
+		return fmt.Errorf("collection %s", ev.OperationType)
+	}
+	return nil
+}
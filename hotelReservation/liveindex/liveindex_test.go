@@ -0,0 +1,184 @@
+package liveindex
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/hailocab/go-geoindex"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type doc struct {
+	Id  string  `bson:"_id"`
+	Key string  `bson:"key"`
+	Lat float64 `bson:"lat"`
+	Lon float64 `bson:"lon"`
+}
+
+func decodeDoc(raw bson.Raw) (geoindex.Point, error) {
+	var d doc
+	if err := bson.Unmarshal(raw, &d); err != nil {
+		return nil, err
+	}
+	return &geoindex.GeoPoint{Pid: d.Key, Plat: d.Lat, Plon: d.Lon}, nil
+}
+
+func mustRaw(t *testing.T, v interface{}) bson.Raw {
+	t.Helper()
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func event(t *testing.T, op string, d doc) changeEvent {
+	ev := changeEvent{
+		OperationType: op,
+		DocumentKey:   mustRaw(t, bson.M{"_id": d.Id}),
+	}
+	if op != "delete" {
+		ev.FullDocument = mustRaw(t, d)
+	}
+	return ev
+}
+
+func all(idx *Index) []geoindex.Point {
+	center := &geoindex.GeoPoint{Plat: 37.7867, Plon: -122.4112}
+	return idx.KNearest(center, 1000, geoindex.Km(5), func(geoindex.Point) bool { return true })
+}
+
+func TestIndexConcurrentUpdates(t *testing.T) {
+	idx := New()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for n := 0; n < 20; n++ {
+				id := fmt.Sprintf("%d-%d", w, n)
+				idx.Add(&geoindex.GeoPoint{Pid: id, Plat: 37.7867 + float64(n)/1000, Plon: -122.4112})
+				all(idx)
+				idx.Get(id)
+				if n%2 == 1 {
+					idx.Remove(id)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if got := len(all(idx)); got != 8*10 {
+		t.Errorf("got %d points, want %d", got, 8*10)
+	}
+	if _, ok := idx.Get("3-4"); !ok {
+		t.Error("point 3-4 missing")
+	}
+	if _, ok := idx.Get("3-5"); ok {
+		t.Error("removed point 3-5 still present")
+	}
+}
+
+func TestIndexApply(t *testing.T) {
+	idx := New()
+	steps := []struct {
+		name    string
+		ev      changeEvent
+		want    map[string]float64 // point id -> lat
+		wantErr bool
+	}{
+		{
+			name: "insert",
+			ev:   event(t, "insert", doc{Id: "a", Key: "1", Lat: 37.78, Lon: -122.41}),
+			want: map[string]float64{"1": 37.78},
+		},
+		{
+			name: "update moves the point",
+			ev:   event(t, "update", doc{Id: "a", Key: "1", Lat: 37.79, Lon: -122.41}),
+			want: map[string]float64{"1": 37.79},
+		},
+		{
+			name: "replace with a new key drops the old point",
+			ev:   event(t, "replace", doc{Id: "a", Key: "2", Lat: 37.79, Lon: -122.41}),
+			want: map[string]float64{"2": 37.79},
+		},
+		{
+			name: "update of a deleted document is skipped",
+			ev:   changeEvent{OperationType: "update", DocumentKey: mustRaw(t, bson.M{"_id": "b"})},
+			want: map[string]float64{"2": 37.79},
+		},
+		{
+			name: "undecodable document is skipped",
+			ev: changeEvent{
+				OperationType: "insert",
+				DocumentKey:   mustRaw(t, bson.M{"_id": "c"}),
+				FullDocument:  mustRaw(t, bson.M{"_id": "c", "lat": "north"}),
+			},
+			want: map[string]float64{"2": 37.79},
+		},
+		{
+			name: "delete of an unknown document",
+			ev:   event(t, "delete", doc{Id: "z"}),
+			want: map[string]float64{"2": 37.79},
+		},
+		{
+			name: "delete",
+			ev:   event(t, "delete", doc{Id: "a"}),
+			want: map[string]float64{},
+		},
+		{
+			name:    "drop ends the stream",
+			ev:      changeEvent{OperationType: "drop", DocumentKey: mustRaw(t, bson.M{})},
+			want:    map[string]float64{},
+			wantErr: true,
+		},
+	}
+
+	for _, step := range steps {
+		err := idx.apply(step.ev, decodeDoc)
+		if (err != nil) != step.wantErr {
+			t.Fatalf("%s: got err %v, want err %v", step.name, err, step.wantErr)
+		}
+		points := all(idx)
+		if len(points) != len(step.want) {
+			t.Fatalf("%s: got %d points, want %d", step.name, len(points), len(step.want))
+		}
+		for id, lat := range step.want {
+			p, ok := idx.Get(id)
+			if !ok || p.Lat() != lat {
+				t.Fatalf("%s: got point %s = %v, want lat %v", step.name, id, p, lat)
+			}
+		}
+	}
+}
+
+func TestIndexApplyConcurrentWithQueries(t *testing.T) {
+	idx := New()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for n := 0; n < 200; n++ {
+			d := doc{Id: fmt.Sprint(n % 10), Key: fmt.Sprint(n % 10), Lat: 37.78, Lon: -122.41}
+			op := "insert"
+			if n%3 == 0 {
+				op = "delete"
+			}
+			if err := idx.apply(event(t, op, d), decodeDoc); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			all(idx)
+			idx.Get("1")
+		}
+	}
+}
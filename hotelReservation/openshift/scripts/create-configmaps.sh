@@ -5,5 +5,7 @@ cd $(dirname $0)/..
 NS=hotel-res
 
 oc create cm configmap-config-json   --from-file=configmaps/config.json  -n ${NS}
+# random key the user and frontend services sign session tokens with
+oc create secret generic hotelres-session --from-literal=session-secret=$(openssl rand -hex 32) -n ${NS}
 
 cd - >/dev/null
@@ -0,0 +1,48 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DNS is a Registry for Kubernetes, where every service is reached through
+// the DNS name of its Service object and kube-dns keeps track of the pods
+// behind it. Registering is a no-op.
+type DNS struct {
+	config  map[string]string
+	pattern string
+}
+
+// NewDNS returns a DNS registry. pattern gives the host name of a service
+// from its short name, as in "%s" or "%s.hotel-res.svc.cluster.local" for
+// "geo"; it defaults to "%s". Ports are the <Name>Port keys of config.
+func NewDNS(config map[string]string, pattern string) *DNS {
+	if pattern == "" {
+		pattern = "%s"
+	}
+	return &DNS{config: config, pattern: pattern}
+}
+
+// Register is a no-op, Kubernetes adds the pod to its Service
+func (d *DNS) Register(name string, id string, ip string, port int) error {
+	log.Info().Msgf("DNS registry, not registering service [ name: %s, id: %s ]", name, id)
+	return nil
+}
+
+// Deregister is a no-op
+func (d *DNS) Deregister(id string) error {
+	return nil
+}
+
+// Target returns the gRPC dns target of service name. Headless services
+// resolve to every pod, so calls are balanced over them.
+func (d *DNS) Target(name string) (string, error) {
+	port, err := configPort(d.config, name)
+	if err != nil {
+		return "", err
+	}
+	host := fmt.Sprintf(d.pattern, strings.TrimPrefix(name, "srv-"))
+	return fmt.Sprintf("dns:///%s:%s", host, port), nil
+}
@@ -1,14 +1,88 @@
 package registry
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os"
+	"sync"
+	"time"
 
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	consul "github.com/hashicorp/consul/api"
 	"github.com/rs/zerolog/log"
 )
 
+// Registry records where services run and tells clients how to reach them
+type Registry interface {
+	// Register announces service name, running as id at ip:port
+	Register(name string, id string, ip string, port int) error
+	// Deregister withdraws the service registered as id
+	Deregister(id string) error
+	// Target returns the gRPC dial target of service name
+	Target(name string) (string, error)
+}
+
+// Registry backends, selected by the "Registry" key of config.json
+const (
+	// BackendConsul registers services in a consul agent (default)
+	BackendConsul = "consul"
+	// BackendStatic uses fixed addresses, see NewStatic
+	BackendStatic = "static"
+	// BackendDNS uses the DNS names of Kubernetes services, see NewDNS
+	BackendDNS = "dns"
+)
+
+// New returns the registry backend selected in config. consulAddr is only
+// used by the consul backend.
+func New(config map[string]string, consulAddr string) (Registry, error) {
+	switch config["Registry"] {
+	case "", BackendConsul:
+		c, err := NewClient(consulAddr)
+		if err != nil {
+			return nil, err
+		}
+		c.KnativeDns = config["KnativeDomainName"]
+		if err := c.SetCheck(config["ConsulCheck"], config["ConsulCheckInterval"], config["ConsulDeregisterAfter"]); err != nil {
+			return nil, err
+		}
+		return c, nil
+	case BackendStatic:
+		return NewStatic(config, config["RegistryFile"])
+	case BackendDNS:
+		return NewDNS(config, config["RegistryDNSName"]), nil
+	default:
+		return nil, fmt.Errorf("registry: unknown backend %q", config["Registry"])
+	}
+}
+
+// configNames maps service names to the prefix of their keys in config.json
+var configNames = map[string]string{
+	"srv-attractions":    "Attractions",
+	"srv-geo":            "Geo",
+	"srv-image":          "Image",
+	"srv-profile":        "Profile",
+	"srv-rate":           "Rate",
+	"srv-recommendation": "Recommend",
+	"srv-reservation":    "Reserve",
+	"srv-review":         "Review",
+	"srv-search":         "Search",
+	"srv-user":           "User",
+}
+
+// configPort returns the port of service name in config
+func configPort(config map[string]string, name string) (string, error) {
+	prefix, ok := configNames[name]
+	if !ok {
+		return "", fmt.Errorf("registry: unknown service %s", name)
+	}
+	port := config[prefix+"Port"]
+	if port == "" {
+		return "", fmt.Errorf("registry: no %sPort in config for %s", prefix, name)
+	}
+	return port, nil
+}
+
 // NewClient returns a new Client with connection to consul
 func NewClient(addr string) (*Client, error) {
 	cfg := consul.DefaultConfig()
@@ -19,12 +93,81 @@ func NewClient(addr string) (*Client, error) {
 		return nil, err
 	}
 
-	return &Client{c}, nil
+	return &Client{Client: c, Check: DefaultCheck, addr: addr}, nil
 }
 
 // Client provides an interface for communicating with registry
 type Client struct {
 	*consul.Client
+
+	// Check is attached to every service registered through the client
+	Check Check
+	// KnativeDns is the domain services are looked up under on Knative
+	KnativeDns string
+
+	addr string
+
+	mu         sync.Mutex
+	heartbeats map[string]context.CancelFunc
+}
+
+// Health check types a service can be registered with
+const (
+	// CheckGRPC has the consul agent call grpc.health.v1 on the service
+	CheckGRPC = "grpc"
+	// CheckTTL has the service report itself passing before the TTL runs
+	// out, for agents that cannot reach the service
+	CheckTTL = "ttl"
+	// CheckNone registers the service without a check
+	CheckNone = "none"
+)
+
+// Check configures the consul health check of registered services
+type Check struct {
+	Type string
+	// Interval is how often the agent probes the service, or the TTL
+	// of a TTL check
+	Interval time.Duration
+	// DeregisterCriticalAfter removes services whose check has been
+	// failing for this long, zero keeps them
+	DeregisterCriticalAfter time.Duration
+}
+
+// DefaultCheck probes the grpc health service every 10s and removes
+// instances that have been failing for a minute
+var DefaultCheck = Check{
+	Type:                    CheckGRPC,
+	Interval:                10 * time.Second,
+	DeregisterCriticalAfter: time.Minute,
+}
+
+// SetCheck sets the health check from its config values, as in
+// "grpc", "10s", "1m". Empty values keep the default.
+func (c *Client) SetCheck(typ, interval, deregisterAfter string) error {
+	check := DefaultCheck
+	switch typ {
+	case "":
+	case CheckGRPC, CheckTTL, CheckNone:
+		check.Type = typ
+	default:
+		return fmt.Errorf("registry: unknown check type %q", typ)
+	}
+	if interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("registry: invalid check interval %q", interval)
+		}
+		check.Interval = d
+	}
+	if deregisterAfter != "" {
+		d, err := time.ParseDuration(deregisterAfter)
+		if err != nil || d < 0 {
+			return fmt.Errorf("registry: invalid deregister critical after %q", deregisterAfter)
+		}
+		check.DeregisterCriticalAfter = d
+	}
+	c.Check = check
+	return nil
 }
 
 // Look for the network device being dedicated for gRPC traffic.
@@ -87,12 +230,100 @@ func (c *Client) Register(name string, id string, ip string, port int) error {
 		Name:    name,
 		Port:    port,
 		Address: ip,
+		Check:   c.serviceCheck(id, ip, port),
+		// read by the balancers of clients, see Endpoint
+		Weights: &consul.AgentWeights{Passing: localWeight(), Warning: 1},
+	}
+	if zone := LocalZone(); zone != "" {
+		reg.Meta = map[string]string{"zone": zone}
+	}
+	log.Info().Msgf("Trying to register service [ name: %s, id: %s, address: %s:%d, check: %s, weight: %d, zone: %s ]", name, id, ip, port, c.Check.Type, reg.Weights.Passing, LocalZone())
+	if err := c.Agent().ServiceRegister(reg); err != nil {
+		return err
+	}
+
+	if c.Check.Type == CheckTTL {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.mu.Lock()
+		if c.heartbeats == nil {
+			c.heartbeats = make(map[string]context.CancelFunc)
+		}
+		c.heartbeats[id] = cancel
+		c.mu.Unlock()
+		go c.heartbeat(ctx, checkID(id))
 	}
-	log.Info().Msgf("Trying to register service [ name: %s, id: %s, address: %s:%d ]", name, id, ip, port)
-	return c.Agent().ServiceRegister(reg)
+	return nil
 }
 
 // Deregister removes the service address from registry
 func (c *Client) Deregister(id string) error {
+	c.mu.Lock()
+	if cancel, ok := c.heartbeats[id]; ok {
+		cancel()
+		delete(c.heartbeats, id)
+	}
+	c.mu.Unlock()
 	return c.Agent().ServiceDeregister(id)
 }
+
+// Target returns the consul resolver target of service name, which only
+// returns instances passing their health check
+func (c *Client) Target(name string) (string, error) {
+	if c.KnativeDns != "" {
+		name = fmt.Sprintf("%s.%s", name, c.KnativeDns)
+	}
+	return fmt.Sprintf("consul://%s/%s?healthy=true", c.addr, name), nil
+}
+
+func checkID(id string) string {
+	return "service:" + id
+}
+
+// serviceCheck returns the check registered along with a service, nil
+// for CheckNone
+func (c *Client) serviceCheck(id string, ip string, port int) *consul.AgentServiceCheck {
+	check := &consul.AgentServiceCheck{
+		CheckID: checkID(id),
+	}
+	if c.Check.DeregisterCriticalAfter > 0 {
+		check.DeregisterCriticalServiceAfter = c.Check.DeregisterCriticalAfter.String()
+	}
+
+	switch c.Check.Type {
+	case CheckGRPC:
+		check.Name = "gRPC health"
+		check.GRPC = fmt.Sprintf("%s:%d", ip, port)
+		check.Interval = c.Check.Interval.String()
+		check.Timeout = (c.Check.Interval / 2).String()
+		if tls.GetServerOpt() != nil {
+			// the agent only needs to know the service answers, it has
+			// no use for the service certificate
+			check.GRPCUseTLS = true
+			check.TLSSkipVerify = true
+		}
+	case CheckTTL:
+		check.Name = "TTL heartbeat"
+		check.TTL = c.Check.Interval.String()
+	default:
+		return nil
+	}
+	return check
+}
+
+// heartbeat keeps a TTL check passing until ctx is done, reporting three
+// times per TTL so a single lost update does not fail the check
+func (c *Client) heartbeat(ctx context.Context, checkID string) {
+	ticker := time.NewTicker(c.Check.Interval / 3)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Agent().UpdateTTL(checkID, "", consul.HealthPassing); err != nil {
+			log.Warn().Msgf("Failed to update TTL check %s: %v", checkID, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
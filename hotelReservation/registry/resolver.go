@@ -0,0 +1,170 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+)
+
+// consulScheme is the resolver scheme of Client targets, as in
+// consul://consul:8500/srv-geo?healthy=true&tag=v1
+const consulScheme = "consul"
+
+func init() {
+	resolver.Register(consulBuilder{})
+}
+
+// Endpoint is what balancers know of a resolved address besides the address
+// itself
+type Endpoint struct {
+	// Weight is the share of calls of the endpoint relative to the others
+	Weight uint32
+	// Zone is the locality of the endpoint, empty if unknown
+	Zone string
+}
+
+type endpointKey struct{}
+
+// Address returns the resolver address of addr carrying e
+func (e Endpoint) Address(addr string) resolver.Address {
+	return resolver.Address{Addr: addr, Attributes: attributes.New(endpointKey{}, e)}
+}
+
+// EndpointOf returns the Endpoint carried by a resolved address, weight 1
+// and no zone for addresses without one
+func EndpointOf(a resolver.Address) Endpoint {
+	e, _ := a.Attributes.Value(endpointKey{}).(Endpoint)
+	if e.Weight == 0 {
+		e.Weight = 1
+	}
+	return e
+}
+
+// LocalZone returns the zone of this process, set in the DSB_ZONE
+// environment variable
+func LocalZone() string {
+	return os.Getenv("DSB_ZONE")
+}
+
+// localWeight returns the weight registered for this process, set in the
+// DSB_WEIGHT environment variable and 1 by default
+func localWeight() int {
+	w, err := strconv.Atoi(os.Getenv("DSB_WEIGHT"))
+	if err != nil || w < 1 {
+		return 1
+	}
+	return w
+}
+
+// consulBuilder resolves consul targets to the instances of a service,
+// with the weight and zone they registered with
+type consulBuilder struct{}
+
+func (consulBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	service := strings.TrimPrefix(target.URL.Path, "/")
+	if target.URL.Host == "" || service == "" {
+		return nil, fmt.Errorf("registry: invalid consul target %s", target.URL.String())
+	}
+	q := target.URL.Query()
+
+	cfg := consul.DefaultConfig()
+	cfg.Address = target.URL.Host
+	client, err := consul.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("registry: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &consulResolver{
+		health:  client.Health(),
+		service: service,
+		tag:     q.Get("tag"),
+		healthy: q.Get("healthy") == "true",
+		cc:      cc,
+		cancel:  cancel,
+	}
+	go r.watch(ctx)
+	return r, nil
+}
+
+func (consulBuilder) Scheme() string {
+	return consulScheme
+}
+
+// consulResolver follows the instances of a service with blocking queries
+type consulResolver struct {
+	health  *consul.Health
+	service string
+	tag     string
+	healthy bool
+	cc      resolver.ClientConn
+	cancel  context.CancelFunc
+}
+
+func (r *consulResolver) watch(ctx context.Context) {
+	var index uint64
+	backoff := 100 * time.Millisecond
+
+	for {
+		entries, meta, err := r.health.Service(r.service, r.tag, r.healthy, (&consul.QueryOptions{
+			WaitIndex: index,
+			WaitTime:  time.Minute,
+		}).WithContext(ctx))
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Error().Msgf("Failed to resolve %s in consul: %v", r.service, err)
+			r.cc.ReportError(err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < 10*time.Second {
+				backoff *= 2
+			}
+			continue
+		}
+		backoff = 100 * time.Millisecond
+		if meta.LastIndex < index {
+			// consul went back in time, as after a restart, start over
+			index = 0
+			continue
+		}
+		if meta.LastIndex == index {
+			continue
+		}
+		index = meta.LastIndex
+
+		addrs := make([]resolver.Address, 0, len(entries))
+		for _, e := range entries {
+			host := e.Service.Address
+			if host == "" {
+				host = e.Node.Address
+			}
+			ep := Endpoint{Weight: uint32(e.Service.Weights.Passing), Zone: e.Service.Meta["zone"]}
+			addrs = append(addrs, ep.Address(fmt.Sprintf("%s:%d", host, e.Service.Port)))
+		}
+		// keep the order stable, so unchanged instances are not updated
+		sort.Slice(addrs, func(i, j int) bool { return addrs[i].Addr < addrs[j].Addr })
+		if err := r.cc.UpdateState(resolver.State{Addresses: addrs}); err != nil {
+			log.Warn().Msgf("Failed to update instances of %s: %v", r.service, err)
+		}
+	}
+}
+
+func (r *consulResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (r *consulResolver) Close() {
+	r.cancel()
+}
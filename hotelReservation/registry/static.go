@@ -0,0 +1,117 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/resolver"
+)
+
+// staticScheme is the resolver scheme of Static targets, as in
+// static:///host1:8083,host2:8083
+const staticScheme = "static"
+
+func init() {
+	resolver.Register(staticBuilder{})
+}
+
+// Static is a Registry of fixed addresses, to run services locally or in
+// tests without a consul agent. Registering is a no-op.
+type Static struct {
+	config map[string]string
+	file   map[string][]string
+}
+
+// NewStatic returns a Static registry. The addresses of a service are
+// looked up in order in
+//   - the DSB_SERVICE_<NAME> environment variable, comma separated, as in
+//     DSB_SERVICE_GEO=geo-1:8083,geo-2:8083 for srv-geo
+//   - the JSON file at path, if any, mapping service names to addresses
+//   - the <Name>IP and <Name>Port keys of config, the IP defaulting to
+//     localhost
+func NewStatic(config map[string]string, path string) (*Static, error) {
+	s := &Static{config: config}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("registry: %v", err)
+		}
+		if err := json.Unmarshal(b, &s.file); err != nil {
+			return nil, fmt.Errorf("registry: invalid %s: %v", path, err)
+		}
+	}
+	return s, nil
+}
+
+// Register is a no-op, clients already know the address of the service
+func (s *Static) Register(name string, id string, ip string, port int) error {
+	log.Info().Msgf("Static registry, not registering service [ name: %s, id: %s ]", name, id)
+	return nil
+}
+
+// Deregister is a no-op
+func (s *Static) Deregister(id string) error {
+	return nil
+}
+
+// Target returns a target resolving to every address of service name
+func (s *Static) Target(name string) (string, error) {
+	addrs, err := s.addrs(name)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:///%s", staticScheme, strings.Join(addrs, ",")), nil
+}
+
+func (s *Static) addrs(name string) ([]string, error) {
+	env := "DSB_SERVICE_" + strings.ToUpper(strings.ReplaceAll(strings.TrimPrefix(name, "srv-"), "-", "_"))
+	if v := os.Getenv(env); v != "" {
+		return strings.Split(v, ","), nil
+	}
+	if addrs := s.file[name]; len(addrs) > 0 {
+		return addrs, nil
+	}
+
+	port, err := configPort(s.config, name)
+	if err != nil {
+		return nil, err
+	}
+	ip := s.config[configNames[name]+"IP"]
+	if ip == "" {
+		ip = "localhost"
+	}
+	return []string{fmt.Sprintf("%s:%s", ip, port)}, nil
+}
+
+// staticBuilder resolves static targets to the addresses they list
+type staticBuilder struct{}
+
+func (staticBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	var addrs []resolver.Address
+	for _, a := range strings.Split(target.Endpoint(), ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, resolver.Address{Addr: a})
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("registry: no address in target %s", target.URL.String())
+	}
+	if err := cc.UpdateState(resolver.State{Addresses: addrs}); err != nil {
+		return nil, err
+	}
+	return staticResolver{}, nil
+}
+
+func (staticBuilder) Scheme() string {
+	return staticScheme
+}
+
+// staticResolver has nothing to watch, the addresses never change
+type staticResolver struct{}
+
+func (staticResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (staticResolver) Close() {}
@@ -67,18 +67,94 @@ func (x *Request) GetHotelId() string {
 	return ""
 }
 
+type NearbyRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	HotelId string `protobuf:"bytes,1,opt,name=hotelId,proto3" json:"hotelId,omitempty"`
+	// "restaurant", "museum" or "cinema"; empty searches all of them
+	Category string `protobuf:"bytes,2,opt,name=category,proto3" json:"category,omitempty"`
+	// in km, 0 for the default of 10
+	Radius float64 `protobuf:"fixed64,3,opt,name=radius,proto3" json:"radius,omitempty"`
+	// 0 for the default of 5
+	Limit int32 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+}
+
+func (x *NearbyRequest) Reset() {
+	*x = NearbyRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_attractions_proto_attractions_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NearbyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NearbyRequest) ProtoMessage() {}
+
+func (x *NearbyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_services_attractions_proto_attractions_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NearbyRequest.ProtoReflect.Descriptor instead.
+func (*NearbyRequest) Descriptor() ([]byte, []int) {
+	return file_services_attractions_proto_attractions_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *NearbyRequest) GetHotelId() string {
+	if x != nil {
+		return x.HotelId
+	}
+	return ""
+}
+
+func (x *NearbyRequest) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *NearbyRequest) GetRadius() float64 {
+	if x != nil {
+		return x.Radius
+	}
+	return 0
+}
+
+func (x *NearbyRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
 type Result struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
 	AttractionIds []string `protobuf:"bytes,1,rep,name=attractionIds,proto3" json:"attractionIds,omitempty"`
+	// details of the attractions in attractionIds, in the same order
+	Attractions []*Attraction `protobuf:"bytes,2,rep,name=attractions,proto3" json:"attractions,omitempty"`
 }
 
 func (x *Result) Reset() {
 	*x = Result{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_services_attractions_proto_attractions_proto_msgTypes[1]
+		mi := &file_services_attractions_proto_attractions_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -91,7 +167,7 @@ func (x *Result) String() string {
 func (*Result) ProtoMessage() {}
 
 func (x *Result) ProtoReflect() protoreflect.Message {
-	mi := &file_services_attractions_proto_attractions_proto_msgTypes[1]
+	mi := &file_services_attractions_proto_attractions_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -104,7 +180,7 @@ func (x *Result) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Result.ProtoReflect.Descriptor instead.
 func (*Result) Descriptor() ([]byte, []int) {
-	return file_services_attractions_proto_attractions_proto_rawDescGZIP(), []int{1}
+	return file_services_attractions_proto_attractions_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *Result) GetAttractionIds() []string {
@@ -114,6 +190,237 @@ func (x *Result) GetAttractionIds() []string {
 	return nil
 }
 
+func (x *Result) GetAttractions() []*Attraction {
+	if x != nil {
+		return x.Attractions
+	}
+	return nil
+}
+
+type HotelLocation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	HotelId string  `protobuf:"bytes,1,opt,name=hotelId,proto3" json:"hotelId,omitempty"`
+	Lat     float64 `protobuf:"fixed64,2,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lon     float64 `protobuf:"fixed64,3,opt,name=lon,proto3" json:"lon,omitempty"`
+}
+
+func (x *HotelLocation) Reset() {
+	*x = HotelLocation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_attractions_proto_attractions_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HotelLocation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HotelLocation) ProtoMessage() {}
+
+func (x *HotelLocation) ProtoReflect() protoreflect.Message {
+	mi := &file_services_attractions_proto_attractions_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HotelLocation.ProtoReflect.Descriptor instead.
+func (*HotelLocation) Descriptor() ([]byte, []int) {
+	return file_services_attractions_proto_attractions_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *HotelLocation) GetHotelId() string {
+	if x != nil {
+		return x.HotelId
+	}
+	return ""
+}
+
+func (x *HotelLocation) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *HotelLocation) GetLon() float64 {
+	if x != nil {
+		return x.Lon
+	}
+	return 0
+}
+
+// category is one of "restaurant", "museum" or "cinema".
+type Attraction struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Category string  `protobuf:"bytes,1,opt,name=category,proto3" json:"category,omitempty"`
+	Id       string  `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Name     string  `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Lat      float64 `protobuf:"fixed64,4,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lon      float64 `protobuf:"fixed64,5,opt,name=lon,proto3" json:"lon,omitempty"`
+	Type     string  `protobuf:"bytes,6,opt,name=type,proto3" json:"type,omitempty"`
+	// only used for restaurants
+	Rating float32 `protobuf:"fixed32,7,opt,name=rating,proto3" json:"rating,omitempty"`
+	// km from the hotel, only set in results
+	Distance float64 `protobuf:"fixed64,8,opt,name=distance,proto3" json:"distance,omitempty"`
+}
+
+func (x *Attraction) Reset() {
+	*x = Attraction{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_attractions_proto_attractions_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Attraction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Attraction) ProtoMessage() {}
+
+func (x *Attraction) ProtoReflect() protoreflect.Message {
+	mi := &file_services_attractions_proto_attractions_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Attraction.ProtoReflect.Descriptor instead.
+func (*Attraction) Descriptor() ([]byte, []int) {
+	return file_services_attractions_proto_attractions_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Attraction) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *Attraction) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Attraction) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Attraction) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *Attraction) GetLon() float64 {
+	if x != nil {
+		return x.Lon
+	}
+	return 0
+}
+
+func (x *Attraction) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Attraction) GetRating() float32 {
+	if x != nil {
+		return x.Rating
+	}
+	return 0
+}
+
+func (x *Attraction) GetDistance() float64 {
+	if x != nil {
+		return x.Distance
+	}
+	return 0
+}
+
+type AttractionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Category string `protobuf:"bytes,1,opt,name=category,proto3" json:"category,omitempty"`
+	Id       string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *AttractionRequest) Reset() {
+	*x = AttractionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_attractions_proto_attractions_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AttractionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AttractionRequest) ProtoMessage() {}
+
+func (x *AttractionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_services_attractions_proto_attractions_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AttractionRequest.ProtoReflect.Descriptor instead.
+func (*AttractionRequest) Descriptor() ([]byte, []int) {
+	return file_services_attractions_proto_attractions_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AttractionRequest) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *AttractionRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
 var File_services_attractions_proto_attractions_proto protoreflect.FileDescriptor
 
 var file_services_attractions_proto_attractions_proto_rawDesc = []byte{
@@ -123,27 +430,79 @@ var file_services_attractions_proto_attractions_proto_rawDesc = []byte{
 	0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x23, 0x0a, 0x07, 0x52,
 	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49,
 	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64,
-	0x22, 0x2e, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x24, 0x0a, 0x0d, 0x61, 0x74,
-	0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
-	0x09, 0x52, 0x0d, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x73,
-	0x32, 0xb9, 0x01, 0x0a, 0x0b, 0x41, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73,
-	0x12, 0x37, 0x0a, 0x0a, 0x4e, 0x65, 0x61, 0x72, 0x62, 0x79, 0x52, 0x65, 0x73, 0x74, 0x12, 0x14,
+	0x22, 0x73, 0x0a, 0x0d, 0x4e, 0x65, 0x61, 0x72, 0x62, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x63,
+	0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63,
+	0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x61, 0x64, 0x69, 0x75,
+	0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x72, 0x61, 0x64, 0x69, 0x75, 0x73, 0x12,
+	0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05,
+	0x6c, 0x69, 0x6d, 0x69, 0x74, 0x22, 0x69, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12,
+	0x24, 0x0a, 0x0d, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0d, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x49, 0x64, 0x73, 0x12, 0x39, 0x0a, 0x0b, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x61, 0x74, 0x74,
+	0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x41, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x0b, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x22, 0x4d, 0x0a, 0x0d, 0x48, 0x6f, 0x74, 0x65, 0x6c, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x6c,
+	0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x61, 0x74, 0x12, 0x10, 0x0a,
+	0x03, 0x6c, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x6f, 0x6e, 0x22,
+	0xb8, 0x01, 0x0a, 0x0a, 0x41, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a,
+	0x0a, 0x08, 0x63, 0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x63, 0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x10,
+	0x0a, 0x03, 0x6c, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x61, 0x74,
+	0x12, 0x10, 0x0a, 0x03, 0x6c, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c,
+	0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x61, 0x74, 0x69, 0x6e, 0x67,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x02, 0x52, 0x06, 0x72, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x12, 0x1a,
+	0x0a, 0x08, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x08, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x22, 0x3f, 0x0a, 0x11, 0x41, 0x74,
+	0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x1a, 0x0a, 0x08, 0x63, 0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x63, 0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79, 0x12, 0x0e, 0x0a, 0x02, 0x69,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x32, 0xf3, 0x03, 0x0a, 0x0b,
+	0x41, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x39, 0x0a, 0x06, 0x4e,
+	0x65, 0x61, 0x72, 0x62, 0x79, 0x12, 0x1a, 0x2e, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x2e, 0x4e, 0x65, 0x61, 0x72, 0x62, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x13, 0x2e, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e,
+	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x37, 0x0a, 0x0a, 0x4e, 0x65, 0x61, 0x72, 0x62, 0x79,
+	0x52, 0x65, 0x73, 0x74, 0x12, 0x14, 0x2e, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x61, 0x74, 0x74,
+	0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12,
+	0x36, 0x0a, 0x09, 0x4e, 0x65, 0x61, 0x72, 0x62, 0x79, 0x4d, 0x75, 0x73, 0x12, 0x14, 0x2e, 0x61,
+	0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x13, 0x2e, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x39, 0x0a, 0x0c, 0x4e, 0x65, 0x61, 0x72, 0x62,
+	0x79, 0x43, 0x69, 0x6e, 0x65, 0x6d, 0x61, 0x12, 0x14, 0x2e, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e,
+	0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x52, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x12, 0x3b, 0x0a, 0x08, 0x41, 0x64, 0x64, 0x48, 0x6f, 0x74, 0x65, 0x6c, 0x12, 0x1a,
+	0x2e, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x48, 0x6f, 0x74,
+	0x65, 0x6c, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x1a, 0x13, 0x2e, 0x61, 0x74, 0x74,
+	0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12,
+	0x38, 0x0a, 0x0b, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x48, 0x6f, 0x74, 0x65, 0x6c, 0x12, 0x14,
 	0x2e, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x52, 0x65, 0x71,
 	0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f,
-	0x6e, 0x73, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x36, 0x0a, 0x09, 0x4e, 0x65, 0x61,
-	0x72, 0x62, 0x79, 0x4d, 0x75, 0x73, 0x12, 0x14, 0x2e, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74,
-	0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x61,
+	0x6e, 0x73, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x3d, 0x0a, 0x0d, 0x41, 0x64, 0x64,
+	0x41, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x17, 0x2e, 0x61, 0x74, 0x74,
+	0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x41, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x1a, 0x13, 0x2e, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x47, 0x0a, 0x10, 0x52, 0x65, 0x6d, 0x6f,
+	0x76, 0x65, 0x41, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1e, 0x2e, 0x61,
+	0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x41, 0x74, 0x74, 0x72, 0x61,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x61,
 	0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c,
-	0x74, 0x12, 0x39, 0x0a, 0x0c, 0x4e, 0x65, 0x61, 0x72, 0x62, 0x79, 0x43, 0x69, 0x6e, 0x65, 0x6d,
-	0x61, 0x12, 0x14, 0x2e, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x61, 0x74, 0x74, 0x72, 0x61, 0x63,
-	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x42, 0x4c, 0x5a, 0x4a,
-	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x64, 0x65, 0x6c, 0x69, 0x6d,
-	0x69, 0x74, 0x72, 0x6f, 0x75, 0x2f, 0x44, 0x65, 0x61, 0x74, 0x68, 0x53, 0x74, 0x61, 0x72, 0x42,
-	0x65, 0x6e, 0x63, 0x68, 0x2f, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76,
-	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x2f, 0x61,
-	0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	0x74, 0x42, 0x58, 0x5a, 0x56, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x64, 0x65, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x72, 0x6f, 0x75, 0x2f, 0x44, 0x65, 0x61, 0x74, 0x68,
+	0x53, 0x74, 0x61, 0x72, 0x42, 0x65, 0x6e, 0x63, 0x68, 0x2f, 0x74, 0x72, 0x65, 0x65, 0x2f, 0x6d,
+	0x61, 0x73, 0x74, 0x65, 0x72, 0x2f, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x65, 0x72,
+	0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x2f,
+	0x61, 0x74, 0x74, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -158,23 +517,38 @@ func file_services_attractions_proto_attractions_proto_rawDescGZIP() []byte {
 	return file_services_attractions_proto_attractions_proto_rawDescData
 }
 
-var file_services_attractions_proto_attractions_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_services_attractions_proto_attractions_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_services_attractions_proto_attractions_proto_goTypes = []interface{}{
-	(*Request)(nil), // 0: attractions.Request
-	(*Result)(nil),  // 1: attractions.Result
+	(*Request)(nil),           // 0: attractions.Request
+	(*NearbyRequest)(nil),     // 1: attractions.NearbyRequest
+	(*Result)(nil),            // 2: attractions.Result
+	(*HotelLocation)(nil),     // 3: attractions.HotelLocation
+	(*Attraction)(nil),        // 4: attractions.Attraction
+	(*AttractionRequest)(nil), // 5: attractions.AttractionRequest
 }
 var file_services_attractions_proto_attractions_proto_depIdxs = []int32{
-	0, // 0: attractions.Attractions.NearbyRest:input_type -> attractions.Request
-	0, // 1: attractions.Attractions.NearbyMus:input_type -> attractions.Request
-	0, // 2: attractions.Attractions.NearbyCinema:input_type -> attractions.Request
-	1, // 3: attractions.Attractions.NearbyRest:output_type -> attractions.Result
-	1, // 4: attractions.Attractions.NearbyMus:output_type -> attractions.Result
-	1, // 5: attractions.Attractions.NearbyCinema:output_type -> attractions.Result
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	4, // 0: attractions.Result.attractions:type_name -> attractions.Attraction
+	1, // 1: attractions.Attractions.Nearby:input_type -> attractions.NearbyRequest
+	0, // 2: attractions.Attractions.NearbyRest:input_type -> attractions.Request
+	0, // 3: attractions.Attractions.NearbyMus:input_type -> attractions.Request
+	0, // 4: attractions.Attractions.NearbyCinema:input_type -> attractions.Request
+	3, // 5: attractions.Attractions.AddHotel:input_type -> attractions.HotelLocation
+	0, // 6: attractions.Attractions.RemoveHotel:input_type -> attractions.Request
+	4, // 7: attractions.Attractions.AddAttraction:input_type -> attractions.Attraction
+	5, // 8: attractions.Attractions.RemoveAttraction:input_type -> attractions.AttractionRequest
+	2, // 9: attractions.Attractions.Nearby:output_type -> attractions.Result
+	2, // 10: attractions.Attractions.NearbyRest:output_type -> attractions.Result
+	2, // 11: attractions.Attractions.NearbyMus:output_type -> attractions.Result
+	2, // 12: attractions.Attractions.NearbyCinema:output_type -> attractions.Result
+	2, // 13: attractions.Attractions.AddHotel:output_type -> attractions.Result
+	2, // 14: attractions.Attractions.RemoveHotel:output_type -> attractions.Result
+	2, // 15: attractions.Attractions.AddAttraction:output_type -> attractions.Result
+	2, // 16: attractions.Attractions.RemoveAttraction:output_type -> attractions.Result
+	9, // [9:17] is the sub-list for method output_type
+	1, // [1:9] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_services_attractions_proto_attractions_proto_init() }
@@ -196,6 +570,18 @@ func file_services_attractions_proto_attractions_proto_init() {
 			}
 		}
 		file_services_attractions_proto_attractions_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NearbyRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_attractions_proto_attractions_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Result); i {
 			case 0:
 				return &v.state
@@ -207,6 +593,42 @@ func file_services_attractions_proto_attractions_proto_init() {
 				return nil
 			}
 		}
+		file_services_attractions_proto_attractions_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HotelLocation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_attractions_proto_attractions_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Attraction); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_attractions_proto_attractions_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AttractionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -214,7 +636,7 @@ func file_services_attractions_proto_attractions_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_services_attractions_proto_attractions_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
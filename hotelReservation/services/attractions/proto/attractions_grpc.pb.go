@@ -19,18 +19,33 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Attractions_NearbyRest_FullMethodName   = "/attractions.Attractions/NearbyRest"
-	Attractions_NearbyMus_FullMethodName    = "/attractions.Attractions/NearbyMus"
-	Attractions_NearbyCinema_FullMethodName = "/attractions.Attractions/NearbyCinema"
+	Attractions_Nearby_FullMethodName           = "/attractions.Attractions/Nearby"
+	Attractions_NearbyRest_FullMethodName       = "/attractions.Attractions/NearbyRest"
+	Attractions_NearbyMus_FullMethodName        = "/attractions.Attractions/NearbyMus"
+	Attractions_NearbyCinema_FullMethodName     = "/attractions.Attractions/NearbyCinema"
+	Attractions_AddHotel_FullMethodName         = "/attractions.Attractions/AddHotel"
+	Attractions_RemoveHotel_FullMethodName      = "/attractions.Attractions/RemoveHotel"
+	Attractions_AddAttraction_FullMethodName    = "/attractions.Attractions/AddAttraction"
+	Attractions_RemoveAttraction_FullMethodName = "/attractions.Attractions/RemoveAttraction"
 )
 
 // AttractionsClient is the client API for Attractions service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type AttractionsClient interface {
+	// Nearby returns the attractions closest to a hotel, nearest first.
+	Nearby(ctx context.Context, in *NearbyRequest, opts ...grpc.CallOption) (*Result, error)
 	NearbyRest(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
 	NearbyMus(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
 	NearbyCinema(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
+	// Admin: adds or moves a hotel in the hotels index.
+	AddHotel(ctx context.Context, in *HotelLocation, opts ...grpc.CallOption) (*Result, error)
+	// Admin: removes a hotel from the hotels index.
+	RemoveHotel(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
+	// Admin: adds or replaces a restaurant, museum or cinema.
+	AddAttraction(ctx context.Context, in *Attraction, opts ...grpc.CallOption) (*Result, error)
+	// Admin: removes a restaurant, museum or cinema.
+	RemoveAttraction(ctx context.Context, in *AttractionRequest, opts ...grpc.CallOption) (*Result, error)
 }
 
 type attractionsClient struct {
@@ -41,6 +56,15 @@ func NewAttractionsClient(cc grpc.ClientConnInterface) AttractionsClient {
 	return &attractionsClient{cc}
 }
 
+func (c *attractionsClient) Nearby(ctx context.Context, in *NearbyRequest, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, Attractions_Nearby_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *attractionsClient) NearbyRest(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error) {
 	out := new(Result)
 	err := c.cc.Invoke(ctx, Attractions_NearbyRest_FullMethodName, in, out, opts...)
@@ -68,13 +92,59 @@ func (c *attractionsClient) NearbyCinema(ctx context.Context, in *Request, opts
 	return out, nil
 }
 
+func (c *attractionsClient) AddHotel(ctx context.Context, in *HotelLocation, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, Attractions_AddHotel_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *attractionsClient) RemoveHotel(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, Attractions_RemoveHotel_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *attractionsClient) AddAttraction(ctx context.Context, in *Attraction, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, Attractions_AddAttraction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *attractionsClient) RemoveAttraction(ctx context.Context, in *AttractionRequest, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, Attractions_RemoveAttraction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AttractionsServer is the server API for Attractions service.
 // All implementations must embed UnimplementedAttractionsServer
 // for forward compatibility
 type AttractionsServer interface {
+	// Nearby returns the attractions closest to a hotel, nearest first.
+	Nearby(context.Context, *NearbyRequest) (*Result, error)
 	NearbyRest(context.Context, *Request) (*Result, error)
 	NearbyMus(context.Context, *Request) (*Result, error)
 	NearbyCinema(context.Context, *Request) (*Result, error)
+	// Admin: adds or moves a hotel in the hotels index.
+	AddHotel(context.Context, *HotelLocation) (*Result, error)
+	// Admin: removes a hotel from the hotels index.
+	RemoveHotel(context.Context, *Request) (*Result, error)
+	// Admin: adds or replaces a restaurant, museum or cinema.
+	AddAttraction(context.Context, *Attraction) (*Result, error)
+	// Admin: removes a restaurant, museum or cinema.
+	RemoveAttraction(context.Context, *AttractionRequest) (*Result, error)
 	mustEmbedUnimplementedAttractionsServer()
 }
 
@@ -82,6 +152,9 @@ type AttractionsServer interface {
 type UnimplementedAttractionsServer struct {
 }
 
+func (UnimplementedAttractionsServer) Nearby(context.Context, *NearbyRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Nearby not implemented")
+}
 func (UnimplementedAttractionsServer) NearbyRest(context.Context, *Request) (*Result, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method NearbyRest not implemented")
 }
@@ -91,6 +164,18 @@ func (UnimplementedAttractionsServer) NearbyMus(context.Context, *Request) (*Res
 func (UnimplementedAttractionsServer) NearbyCinema(context.Context, *Request) (*Result, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method NearbyCinema not implemented")
 }
+func (UnimplementedAttractionsServer) AddHotel(context.Context, *HotelLocation) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddHotel not implemented")
+}
+func (UnimplementedAttractionsServer) RemoveHotel(context.Context, *Request) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveHotel not implemented")
+}
+func (UnimplementedAttractionsServer) AddAttraction(context.Context, *Attraction) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddAttraction not implemented")
+}
+func (UnimplementedAttractionsServer) RemoveAttraction(context.Context, *AttractionRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveAttraction not implemented")
+}
 func (UnimplementedAttractionsServer) mustEmbedUnimplementedAttractionsServer() {}
 
 // UnsafeAttractionsServer may be embedded to opt out of forward compatibility for this service.
@@ -104,6 +189,24 @@ func RegisterAttractionsServer(s grpc.ServiceRegistrar, srv AttractionsServer) {
 	s.RegisterService(&Attractions_ServiceDesc, srv)
 }
 
+func _Attractions_Nearby_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NearbyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AttractionsServer).Nearby(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Attractions_Nearby_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AttractionsServer).Nearby(ctx, req.(*NearbyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Attractions_NearbyRest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Request)
 	if err := dec(in); err != nil {
@@ -158,6 +261,78 @@ func _Attractions_NearbyCinema_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Attractions_AddHotel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HotelLocation)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AttractionsServer).AddHotel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Attractions_AddHotel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AttractionsServer).AddHotel(ctx, req.(*HotelLocation))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Attractions_RemoveHotel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AttractionsServer).RemoveHotel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Attractions_RemoveHotel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AttractionsServer).RemoveHotel(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Attractions_AddAttraction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Attraction)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AttractionsServer).AddAttraction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Attractions_AddAttraction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AttractionsServer).AddAttraction(ctx, req.(*Attraction))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Attractions_RemoveAttraction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AttractionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AttractionsServer).RemoveAttraction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Attractions_RemoveAttraction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AttractionsServer).RemoveAttraction(ctx, req.(*AttractionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Attractions_ServiceDesc is the grpc.ServiceDesc for Attractions service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -165,6 +340,10 @@ var Attractions_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "attractions.Attractions",
 	HandlerType: (*AttractionsServer)(nil),
 	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Nearby",
+			Handler:    _Attractions_Nearby_Handler,
+		},
 		{
 			MethodName: "NearbyRest",
 			Handler:    _Attractions_NearbyRest_Handler,
@@ -177,6 +356,22 @@ var Attractions_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "NearbyCinema",
 			Handler:    _Attractions_NearbyCinema_Handler,
 		},
+		{
+			MethodName: "AddHotel",
+			Handler:    _Attractions_AddHotel_Handler,
+		},
+		{
+			MethodName: "RemoveHotel",
+			Handler:    _Attractions_RemoveHotel_Handler,
+		},
+		{
+			MethodName: "AddAttraction",
+			Handler:    _Attractions_AddAttraction_Handler,
+		},
+		{
+			MethodName: "RemoveAttraction",
+			Handler:    _Attractions_RemoveAttraction_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "services/attractions/proto/attractions.proto",
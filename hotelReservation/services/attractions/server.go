@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sort"
 	"time"
 
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/liveindex"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
 	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/attractions/proto"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	"github.com/google/uuid"
 	"github.com/hailocab/go-geoindex"
@@ -15,38 +18,57 @@ import (
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	name             = "srv-attractions"
-	maxSearchRadius  = 10
-	maxSearchResults = 5
+	name = "srv-attractions"
+	// radius (km) and number of results of a search unless a request asks
+	// for others, up to the max values
+	defaultSearchRadius  = 10
+	defaultSearchResults = 5
+	maxSearchRadius      = 100
+	maxSearchResults     = 100
 )
 
+// attractionCategories are the categories searched by a Nearby request
+// without one
+var attractionCategories = []string{"restaurant", "museum", "cinema"}
+
 // Server implements the attractions service
 type Server struct {
 	pb.UnimplementedAttractionsServer
 
-	indexH *geoindex.ClusteringIndex
-	indexR *geoindex.ClusteringIndex
-	indexM *geoindex.ClusteringIndex
-	indexC *geoindex.ClusteringIndex
-	uuid   string
-
-	Registry    *registry.Client
+	indexH     *liveindex.Index
+	indexR     *liveindex.Index
+	indexM     *liveindex.Index
+	indexC     *liveindex.Index
+	uuid       string
+	grpcServer *grpc.Server
+	listener   net.Listener
+	health     *health.Server
+	stopWatch  context.CancelFunc
+
+	Registry    registry.Registry
 	Tracer      trace.Tracer
 	Port        int
 	IpAddr      string
 	MongoClient *mongo.Client
 }
 
-// Run starts the server
-func (s *Server) Run() error {
+// Setup creates the server, listens on Port and registers in consul. It is
+// called before Run, so Shutdown only reads what Setup wrote.
+func (s *Server) Setup() error {
 	if s.Port == 0 {
 		return fmt.Errorf("server port must be set")
 	}
@@ -67,6 +89,14 @@ func (s *Server) Run() error {
 		s.indexC = newGeoIndexCinema(s.MongoClient)
 	}
 
+	// pick up documents added or removed in attractions-db while running
+	var watchCtx context.Context
+	watchCtx, s.stopWatch = context.WithCancel(context.Background())
+	go s.indexH.Watch(watchCtx, attractionsCollection(s.MongoClient, "hotels"), decodePoint, liveindex.DefaultPollInterval)
+	go s.indexR.Watch(watchCtx, attractionsCollection(s.MongoClient, "restaurants"), decodeRestaurant, liveindex.DefaultPollInterval)
+	go s.indexM.Watch(watchCtx, attractionsCollection(s.MongoClient, "museums"), decodeMuseum, liveindex.DefaultPollInterval)
+	go s.indexC.Watch(watchCtx, attractionsCollection(s.MongoClient, "cinemas"), decodeCinema, liveindex.DefaultPollInterval)
+
 	s.uuid = uuid.New().String()
 
 	opts := []grpc.ServerOption{
@@ -77,15 +107,22 @@ func (s *Server) Run() error {
 			PermitWithoutStream: true,
 		}),
 		grpc.UnaryInterceptor(
-			otelgrpc.UnaryServerInterceptor(),
+			otelgrpc.UnaryServerInterceptor(
+				otelgrpc.WithInterceptorFilter(filters.Not(filters.HealthCheck())),
+			),
 		),
 	}
 
 	if tlsopt := tls.GetServerOpt(); tlsopt != nil {
-		opts = append(opts, tlsopt)
+		opts = append(opts, tlsopt, grpc.ChainUnaryInterceptor(tls.UnaryServerInterceptor()))
 	}
 
 	srv := grpc.NewServer(opts...)
+	s.grpcServer = srv
+
+	// answers the consul check, serving until Shutdown
+	s.health = health.NewServer()
+	healthpb.RegisterHealthServer(srv, s.health)
 
 	pb.RegisterAttractionsServer(srv, s)
 
@@ -101,23 +138,39 @@ func (s *Server) Run() error {
 	}
 	log.Info().Msg("Successfully registered in consul")
 
-	return srv.Serve(lis)
+	s.listener = lis
+	return nil
 }
 
-// Shutdown cleans up any processes
-func (s *Server) Shutdown() {
-	s.Registry.Deregister(s.uuid)
+// Run serves the server set up by Setup until Shutdown
+func (s *Server) Run() error {
+	return s.grpcServer.Serve(s.listener)
 }
 
-// NearbyRest returns all restaurants close to the hotel.
-func (s *Server) NearbyRest(ctx context.Context, req *pb.Request) (*pb.Result, error) {
+// Shutdown fails the health check and deregisters the service so no new
+// calls are routed here, then waits for pending calls until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+	if s.stopWatch != nil {
+		s.stopWatch()
+	}
+	err := s.Registry.Deregister(s.uuid)
+	shutdown.GRPC(ctx, s.grpcServer)
+	return err
+}
+
+// Nearby returns the restaurants, museums and cinemas closest to a hotel,
+// nearest first. Hotel coordinates come from the in-memory hotels index.
+func (s *Server) Nearby(ctx context.Context, req *pb.NearbyRequest) (*pb.Result, error) {
 	// Get logger with trace context
 	logger := zerolog.Ctx(ctx)
 	if logger.GetLevel() == zerolog.Disabled {
 		globalLogger := log.Logger
 		logger = &globalLogger
 	}
-	
+
 	// Extract trace information and add to logger
 	span := trace.SpanFromContext(ctx)
 	if span.IsRecording() {
@@ -131,314 +184,313 @@ func (s *Server) NearbyRest(ctx context.Context, req *pb.Request) (*pb.Result, e
 			logger = &newLogger
 		}
 	}
-	
-	logger.Info().Msgf("Finding nearby restaurants: hotel_id=%s", req.HotelId)
-
-	_, mongoSpan := s.Tracer.Start(ctx, "mongo_restaurant")
-	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
 
-	c := s.MongoClient.Database("attractions-db").Collection("hotels")
+	logger.Info().Msgf("Finding nearby attractions: hotel_id=%s, category=%s, radius=%v, limit=%d", req.HotelId, req.Category, req.Radius, req.Limit)
 
-	curr, err := c.Find(context.TODO(), bson.M{"hotelId": req.HotelId})
-	if err != nil {
-		logger.Error().Msgf("Failed get hotels: %v", err)
+	if req.HotelId == "" {
+		return nil, status.Error(codes.InvalidArgument, "hotelId must be set")
+	}
+	radius := req.Radius
+	if radius == 0 {
+		radius = defaultSearchRadius
+	}
+	if radius < 0 || radius > maxSearchRadius {
+		return nil, status.Errorf(codes.InvalidArgument, "radius must be between 0 and %d km", maxSearchRadius)
+	}
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultSearchResults
+	}
+	if limit < 0 || limit > maxSearchResults {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 0 and %d", maxSearchResults)
 	}
-	var hotelReqs []point
-	curr.All(context.TODO(), &hotelReqs)
 
-	var hotelReq point
+	categories := attractionCategories
+	if req.Category != "" {
+		if s.categoryIndex(req.Category) == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown category %q", req.Category)
+		}
+		categories = []string{req.Category}
+	}
 
-	for _, hotelHelper := range hotelReqs {
-		hotelReq = hotelHelper
+	hotel, ok := s.indexH.Get(req.HotelId)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "hotel %s not found", req.HotelId)
 	}
 
-	var (
-		points = s.getNearbyPointsRest(ctx, float64(hotelReq.Plat), float64(hotelReq.Plon), logger)
-		res    = &pb.Result{}
+	_, indexSpan := s.Tracer.Start(ctx, "attractions_index_nearby")
+	indexSpan.SetAttributes(
+		attribute.String("attractions.category", req.Category),
+		attribute.Float64("attractions.radius_km", radius),
+		attribute.Int("attractions.limit", limit),
 	)
+	found := make([]*pb.Attraction, 0)
+	for _, category := range categories {
+		points := s.categoryIndex(category).KNearest(hotel, limit, geoindex.Km(radius), func(p geoindex.Point) bool {
+			return true
+		})
+		for _, p := range points {
+			found = append(found, toAttraction(category, p, hotel))
+		}
+	}
+	indexSpan.End()
 
-	logger.Info().Msgf("Found nearby restaurants: count=%d", len(points))
+	// every category brings up to limit attractions, keep the nearest overall
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Distance < found[j].Distance
+	})
+	if len(found) > limit {
+		found = found[:limit]
+	}
 
-	for _, p := range points {
-		res.AttractionIds = append(res.AttractionIds, p.Id())
+	res := &pb.Result{Attractions: found}
+	for _, a := range found {
+		res.AttractionIds = append(res.AttractionIds, a.Id)
 	}
 
+	logger.Info().Msgf("Found nearby attractions: count=%d", len(found))
 	return res, nil
 }
 
+// NearbyRest returns all restaurants close to the hotel.
+func (s *Server) NearbyRest(ctx context.Context, req *pb.Request) (*pb.Result, error) {
+	return s.nearbyCategory(ctx, req.HotelId, "restaurant")
+}
+
 // NearbyMus returns all museums close to the hotel.
 func (s *Server) NearbyMus(ctx context.Context, req *pb.Request) (*pb.Result, error) {
-	// Get logger with trace context
-	logger := zerolog.Ctx(ctx)
-	if logger.GetLevel() == zerolog.Disabled {
-		globalLogger := log.Logger
-		logger = &globalLogger
-	}
-	
-	// Extract trace information and add to logger
-	span := trace.SpanFromContext(ctx)
-	if span.IsRecording() {
-		spanCtx := span.SpanContext()
-		if spanCtx.HasTraceID() {
-			newLogger := logger.With().Str("trace_id", spanCtx.TraceID().String()).Logger()
-			logger = &newLogger
-		}
-		if spanCtx.HasSpanID() {
-			newLogger := logger.With().Str("span_id", spanCtx.SpanID().String()).Logger()
-			logger = &newLogger
-		}
-	}
-	
-	logger.Info().Msgf("Finding nearby museums: hotel_id=%s", req.HotelId)
-
-	_, mongoSpan := s.Tracer.Start(ctx, "mongo_museum")
-	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	return s.nearbyCategory(ctx, req.HotelId, "museum")
+}
 
-	c := s.MongoClient.Database("attractions-db").Collection("hotels")
+// NearbyCinema returns all cinemas close to the hotel.
+func (s *Server) NearbyCinema(ctx context.Context, req *pb.Request) (*pb.Result, error) {
+	return s.nearbyCategory(ctx, req.HotelId, "cinema")
+}
 
-	curr, err := c.Find(context.TODO(), bson.M{"hotelId": req.HotelId})
-	if err != nil {
-		logger.Error().Msgf("Failed get hotels: %v", err)
+// nearbyCategory runs Nearby with the default radius and limit. Unknown
+// hotels give an empty result rather than an error, as these RPCs always did.
+func (s *Server) nearbyCategory(ctx context.Context, hotelId, category string) (*pb.Result, error) {
+	res, err := s.Nearby(ctx, &pb.NearbyRequest{HotelId: hotelId, Category: category})
+	if code := status.Code(err); code == codes.NotFound || code == codes.InvalidArgument {
+		return &pb.Result{}, nil
 	}
-	var hotelReqs []point
-	curr.All(context.TODO(), &hotelReqs)
+	return res, err
+}
 
-	var hotelReq point
+// toAttraction describes an indexed restaurant, museum or cinema and its
+// distance from the hotel
+func toAttraction(category string, p geoindex.Point, hotel geoindex.Point) *pb.Attraction {
+	a := &pb.Attraction{
+		Category: category,
+		Id:       p.Id(),
+		Lat:      p.Lat(),
+		Lon:      p.Lon(),
+		Distance: float64(geoindex.Distance(hotel, p)) / 1000,
+	}
+	switch v := p.(type) {
+	case *Restaurant:
+		a.Name, a.Type, a.Rating = v.RestaurantName, v.Type, v.Rating
+	case *Museum:
+		a.Name, a.Type = v.MuseumName, v.Type
+	case *Cinema:
+		a.Name, a.Type = v.CinemaName, v.Type
+	}
+	return a
+}
 
-	for _, hotelHelper := range hotelReqs {
-		hotelReq = hotelHelper
+// AddHotel stores a hotel location and adds it to the hotels index.
+func (s *Server) AddHotel(ctx context.Context, req *pb.HotelLocation) (*pb.Result, error) {
+	if req.HotelId == "" {
+		return nil, status.Error(codes.InvalidArgument, "hotelId must be set")
+	}
+	if !validLocation(req.Lat, req.Lon) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid location %v,%v", req.Lat, req.Lon)
 	}
 
-	var (
-		points = s.getNearbyPointsMus(ctx, float64(hotelReq.Plat), float64(hotelReq.Plon), logger)
-		res    = &pb.Result{}
-	)
-
-	logger.Info().Msgf("Found nearby museums: count=%d", len(points))
-
-	for _, p := range points {
-		res.AttractionIds = append(res.AttractionIds, p.Id())
+	p := &point{Pid: req.HotelId, Plat: req.Lat, Plon: req.Lon}
+	if err := upsert(ctx, attractionsCollection(s.MongoClient, "hotels"), "hotelId", p.Pid, p); err != nil {
+		log.Error().Msgf("Failed to store hotel %s: %v", req.HotelId, err)
+		return nil, err
 	}
+	s.indexH.Add(p)
 
-	return res, nil
+	log.Info().Msgf("Hotel added to attractions index: hotel_id=%s", req.HotelId)
+	return &pb.Result{}, nil
 }
 
-// NearbyCinema returns all cinemas close to the hotel.
-func (s *Server) NearbyCinema(ctx context.Context, req *pb.Request) (*pb.Result, error) {
-	// Get logger with trace context
-	logger := zerolog.Ctx(ctx)
-	if logger.GetLevel() == zerolog.Disabled {
-		globalLogger := log.Logger
-		logger = &globalLogger
+// RemoveHotel deletes a hotel location from the hotels index.
+func (s *Server) RemoveHotel(ctx context.Context, req *pb.Request) (*pb.Result, error) {
+	if req.HotelId == "" {
+		return nil, status.Error(codes.InvalidArgument, "hotelId must be set")
 	}
-	
-	// Extract trace information and add to logger
-	span := trace.SpanFromContext(ctx)
-	if span.IsRecording() {
-		spanCtx := span.SpanContext()
-		if spanCtx.HasTraceID() {
-			newLogger := logger.With().Str("trace_id", spanCtx.TraceID().String()).Logger()
-			logger = &newLogger
-		}
-		if spanCtx.HasSpanID() {
-			newLogger := logger.With().Str("span_id", spanCtx.SpanID().String()).Logger()
-			logger = &newLogger
-		}
+
+	if err := remove(ctx, attractionsCollection(s.MongoClient, "hotels"), "hotelId", req.HotelId); err != nil {
+		return nil, err
 	}
-	
-	logger.Info().Msgf("Finding nearby cinemas: hotel_id=%s", req.HotelId)
+	s.indexH.Remove(req.HotelId)
 
-	_, mongoSpan := s.Tracer.Start(ctx, "mongo_cinema")
-	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	log.Info().Msgf("Hotel removed from attractions index: hotel_id=%s", req.HotelId)
+	return &pb.Result{}, nil
+}
 
-	c := s.MongoClient.Database("attractions-db").Collection("hotels")
+// AddAttraction stores a restaurant, museum or cinema and adds it to the
+// matching index.
+func (s *Server) AddAttraction(ctx context.Context, req *pb.Attraction) (*pb.Result, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id must be set")
+	}
+	if !validLocation(req.Lat, req.Lon) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid location %v,%v", req.Lat, req.Lon)
+	}
 
-	curr, err := c.Find(context.TODO(), bson.M{"hotelId": req.HotelId})
+	index, coll, idField, err := s.category(req.Category)
 	if err != nil {
-		logger.Error().Msgf("Failed get hotels: %v", err)
+		return nil, err
 	}
-	var hotelReqs []point
-	curr.All(context.TODO(), &hotelReqs)
 
-	var hotelReq point
-
-	for _, hotelHelper := range hotelReqs {
-		hotelReq = hotelHelper
+	var p geoindex.Point
+	switch req.Category {
+	case "restaurant":
+		p = &Restaurant{req.Id, req.Lat, req.Lon, req.Name, req.Rating, req.Type}
+	case "museum":
+		p = &Museum{req.Id, req.Lat, req.Lon, req.Name, req.Type}
+	case "cinema":
+		p = &Cinema{req.Id, req.Lat, req.Lon, req.Name, req.Type}
 	}
 
-	var (
-		points = s.getNearbyPointsCinema(ctx, float64(hotelReq.Plat), float64(hotelReq.Plon), logger)
-		res    = &pb.Result{}
-	)
-
-	logger.Info().Msgf("Found nearby cinemas: count=%d", len(points))
-
-	for _, p := range points {
-		res.AttractionIds = append(res.AttractionIds, p.Id())
+	if err := upsert(ctx, coll, idField, req.Id, p); err != nil {
+		log.Error().Msgf("Failed to store %s %s: %v", req.Category, req.Id, err)
+		return nil, err
 	}
+	index.Add(p)
 
-	return res, nil
+	log.Info().Msgf("Attraction added: category=%s, id=%s", req.Category, req.Id)
+	return &pb.Result{AttractionIds: []string{req.Id}}, nil
 }
 
-func (s *Server) getNearbyPointsHotel(ctx context.Context, lat, lon float64) []geoindex.Point {
-	center := &geoindex.GeoPoint{
-		Pid:  "",
-		Plat: lat,
-		Plon: lon,
+// RemoveAttraction deletes a restaurant, museum or cinema.
+func (s *Server) RemoveAttraction(ctx context.Context, req *pb.AttractionRequest) (*pb.Result, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id must be set")
 	}
 
-	return s.indexH.KNearest(
-		center,
-		maxSearchResults,
-		geoindex.Km(maxSearchRadius), func(p geoindex.Point) bool {
-			return true
-		},
-	)
+	index, coll, idField, err := s.category(req.Category)
+	if err != nil {
+		return nil, err
+	}
+	if err := remove(ctx, coll, idField, req.Id); err != nil {
+		return nil, err
+	}
+	index.Remove(req.Id)
+
+	log.Info().Msgf("Attraction removed: category=%s, id=%s", req.Category, req.Id)
+	return &pb.Result{AttractionIds: []string{req.Id}}, nil
 }
 
-func (s *Server) getNearbyPointsRest(ctx context.Context, lat, lon float64, logger *zerolog.Logger) []geoindex.Point {
-	logger.Debug().Msgf("Searching restaurants near: lat=%v, lon=%v", lat, lon)
+// category returns the index, collection and id field backing an attraction category
+func (s *Server) category(category string) (*liveindex.Index, *mongo.Collection, string, error) {
+	switch category {
+	case "restaurant":
+		return s.indexR, attractionsCollection(s.MongoClient, "restaurants"), "restaurantId", nil
+	case "museum":
+		return s.indexM, attractionsCollection(s.MongoClient, "museums"), "museumId", nil
+	case "cinema":
+		return s.indexC, attractionsCollection(s.MongoClient, "cinemas"), "cinemaId", nil
+	}
+	return nil, nil, "", status.Errorf(codes.InvalidArgument, "unknown category %q", category)
+}
 
-	center := &geoindex.GeoPoint{
-		Pid:  "",
-		Plat: lat,
-		Plon: lon,
-	}
+// categoryIndex returns the index of an attraction category, nil if unknown
+func (s *Server) categoryIndex(category string) *liveindex.Index {
+	switch category {
+	case "restaurant":
+		return s.indexR
+	case "museum":
+		return s.indexM
+	case "cinema":
+		return s.indexC
+	}
+	return nil
+}
 
-	return s.indexR.KNearest(
-		center,
-		maxSearchResults,
-		geoindex.Km(maxSearchRadius), func(p geoindex.Point) bool {
-			return true
-		},
-	)
+func validLocation(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
 }
 
-func (s *Server) getNearbyPointsMus(ctx context.Context, lat, lon float64, logger *zerolog.Logger) []geoindex.Point {
-	logger.Debug().Msgf("Searching museums near: lat=%v, lon=%v", lat, lon)
+func upsert(ctx context.Context, coll *mongo.Collection, idField, id string, doc interface{}) error {
+	_, err := coll.UpdateOne(ctx, bson.M{idField: id}, bson.M{"$set": doc}, options.Update().SetUpsert(true))
+	return err
+}
 
-	center := &geoindex.GeoPoint{
-		Pid:  "",
-		Plat: lat,
-		Plon: lon,
+func remove(ctx context.Context, coll *mongo.Collection, idField, id string) error {
+	deleted, err := coll.DeleteMany(ctx, bson.M{idField: id})
+	if err != nil {
+		log.Error().Msgf("Failed to remove %s %s from %s: %v", idField, id, coll.Name(), err)
+		return err
+	}
+	if deleted.DeletedCount == 0 {
+		return status.Errorf(codes.NotFound, "%s %s not found", idField, id)
 	}
+	return nil
+}
 
-	return s.indexM.KNearest(
-		center,
-		maxSearchResults,
-		geoindex.Km(maxSearchRadius), func(p geoindex.Point) bool {
-			return true
-		},
-	)
+func attractionsCollection(client *mongo.Client, name string) *mongo.Collection {
+	return client.Database("attractions-db").Collection(name)
 }
 
-func (s *Server) getNearbyPointsCinema(ctx context.Context, lat, lon float64, logger *zerolog.Logger) []geoindex.Point {
-	logger.Debug().Msgf("Searching cinemas near: lat=%v, lon=%v", lat, lon)
+func decodePoint(raw bson.Raw) (geoindex.Point, error) {
+	p := new(point)
+	return p, bson.Unmarshal(raw, p)
+}
 
-	center := &geoindex.GeoPoint{
-		Pid:  "",
-		Plat: lat,
-		Plon: lon,
-	}
+func decodeRestaurant(raw bson.Raw) (geoindex.Point, error) {
+	r := new(Restaurant)
+	return r, bson.Unmarshal(raw, r)
+}
 
-	return s.indexC.KNearest(
-		center,
-		maxSearchResults,
-		geoindex.Km(maxSearchRadius), func(p geoindex.Point) bool {
-			return true
-		},
-	)
+func decodeMuseum(raw bson.Raw) (geoindex.Point, error) {
+	m := new(Museum)
+	return m, bson.Unmarshal(raw, m)
 }
 
-// newGeoIndex returns a geo index with points loaded
-func newGeoIndex(client *mongo.Client) *geoindex.ClusteringIndex {
-	collection := client.Database("attractions-db").Collection("hotels")
-	curr, err := collection.Find(context.TODO(), bson.D{})
-	if err != nil {
-		log.Error().Msgf("Failed get hotels data: ", err)
-	}
+func decodeCinema(raw bson.Raw) (geoindex.Point, error) {
+	c := new(Cinema)
+	return c, bson.Unmarshal(raw, c)
+}
 
-	var points []*point
-	curr.All(context.TODO(), &points)
+// newGeoIndex returns a geo index with points loaded
+func newGeoIndex(client *mongo.Client) *liveindex.Index {
+	index, err := liveindex.Load(context.TODO(), attractionsCollection(client, "hotels"), decodePoint)
 	if err != nil {
-		log.Error().Msgf("Failed get hotels data: ", err)
+		log.Error().Msgf("Failed get hotels data: %v", err)
 	}
-
-	// add points to index
-	index := geoindex.NewClusteringIndex()
-	for _, point := range points {
-		index.Add(point)
-	}
-
 	return index
 }
 
 // newGeoIndexRest returns a geo index with points loaded
-func newGeoIndexRest(client *mongo.Client) *geoindex.ClusteringIndex {
-	collection := client.Database("attractions-db").Collection("restaurants")
-	curr, err := collection.Find(context.TODO(), bson.D{})
-	if err != nil {
-		log.Error().Msgf("Failed get restaurant data: ", err)
-	}
-
-	var points []*Restaurant
-	curr.All(context.TODO(), &points)
+func newGeoIndexRest(client *mongo.Client) *liveindex.Index {
+	index, err := liveindex.Load(context.TODO(), attractionsCollection(client, "restaurants"), decodeRestaurant)
 	if err != nil {
-		log.Error().Msgf("Failed get restaurant data: ", err)
+		log.Error().Msgf("Failed get restaurant data: %v", err)
 	}
-
-	// add points to index
-	index := geoindex.NewClusteringIndex()
-	for _, point := range points {
-		index.Add(point)
-	}
-
 	return index
 }
 
 // newGeoIndexMus returns a geo index with points loaded
-func newGeoIndexMus(client *mongo.Client) *geoindex.ClusteringIndex {
-	collection := client.Database("attractions-db").Collection("museums")
-	curr, err := collection.Find(context.TODO(), bson.D{})
+func newGeoIndexMus(client *mongo.Client) *liveindex.Index {
+	index, err := liveindex.Load(context.TODO(), attractionsCollection(client, "museums"), decodeMuseum)
 	if err != nil {
-		log.Error().Msgf("Failed get restaurant data: ", err)
+		log.Error().Msgf("Failed get museum data: %v", err)
 	}
-
-	var points []*Museum
-	curr.All(context.TODO(), &points)
-	if err != nil {
-		log.Error().Msgf("Failed get restaurant data: ", err)
-	}
-
-	// add points to index
-	index := geoindex.NewClusteringIndex()
-	for _, point := range points {
-		index.Add(point)
-	}
-
 	return index
 }
 
 // newGeoIndexCinema returns a geo index with points loaded
-func newGeoIndexCinema(client *mongo.Client) *geoindex.ClusteringIndex {
-	collection := client.Database("attractions-db").Collection("cinemas")
-	curr, err := collection.Find(context.TODO(), bson.D{})
-	if err != nil {
-		log.Error().Msgf("Failed get cinema data: ", err)
-	}
-
-	var points []*Cinema
-	curr.All(context.TODO(), &points)
+func newGeoIndexCinema(client *mongo.Client) *liveindex.Index {
+	index, err := liveindex.Load(context.TODO(), attractionsCollection(client, "cinemas"), decodeCinema)
 	if err != nil {
-		log.Error().Msgf("Failed get cinema data: ", err)
-	}
-
-	// add points to index
-	index := geoindex.NewClusteringIndex()
-	for _, point := range points {
-		index.Add(point)
+		log.Error().Msgf("Failed get cinema data: %v", err)
 	}
-
 	return index
 }
 
@@ -0,0 +1,175 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	user "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/user/proto"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/session"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// AuthStrict rejects requests without valid credentials with a 401
+	AuthStrict = "strict"
+	// AuthPermissive only logs wrong credentials and lets the request through,
+	// which is how the benchmark workloads have always been served
+	AuthPermissive = "permissive"
+)
+
+var (
+	errNoCredentials  = errors.New("no credentials given")
+	errBadCredentials = errors.New("wrong credentials")
+)
+
+type authKey struct{}
+
+type authResult struct {
+	username      string
+	authenticated bool
+}
+
+// authUser returns the user requireAuth found for the request and whether
+// the credentials were valid
+func authUser(ctx context.Context) (string, bool) {
+	res, _ := ctx.Value(authKey{}).(authResult)
+	return res.username, res.authenticated
+}
+
+// signedInUser returns the user of the request for handlers that act on
+// behalf of that user. Requests without valid credentials, which permissive
+// mode lets through, are answered with 401.
+func signedInUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	username, authenticated := authUser(r.Context())
+	if !authenticated {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="hotelreservation"`)
+		http.Error(w, "Please specify a valid session token or username and password", http.StatusUnauthorized)
+		return "", false
+	}
+	return username, true
+}
+
+// requireAuth authenticates every request before passing it to next and
+// records the decision on the request span. In strict mode missing or wrong
+// credentials are answered with 401; in permissive mode missing credentials
+// are a 400 and wrong ones are logged, the request being passed on without a
+// user.
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	return s.withAuth(next, false)
+}
+
+// optionalAuth is requireAuth for routes that also serve anonymous requests:
+// requests without credentials are passed on without a user.
+func (s *Server) optionalAuth(next http.Handler) http.Handler {
+	return s.withAuth(next, true)
+}
+
+func (s *Server) withAuth(next http.Handler, optional bool) http.Handler {
+	strict := s.AuthMode == AuthStrict
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		ctx := r.Context()
+
+		logger := zerolog.Ctx(ctx)
+		if logger.GetLevel() == zerolog.Disabled {
+			globalLogger := log.Logger
+			logger = &globalLogger
+		}
+
+		username, err := s.authenticate(r)
+
+		decision := "allowed"
+		switch {
+		case err == nil:
+		case errors.Is(err, errNoCredentials) && optional:
+			decision = "anonymous"
+		case errors.Is(err, errNoCredentials) || errors.Is(err, errBadCredentials):
+			if strict {
+				decision = "denied"
+			} else if errors.Is(err, errNoCredentials) {
+				decision = "missing"
+			} else {
+				decision = "allowed_unauthenticated"
+			}
+		default:
+			decision = "error"
+		}
+
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(
+			attribute.String("auth.mode", s.authMode()),
+			attribute.String("auth.decision", decision),
+			attribute.String("auth.user", username),
+		)
+
+		switch decision {
+		case "denied":
+			logger.Warn().Msgf("Request rejected: username=%s, reason=%v", username, err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="hotelreservation"`)
+			http.Error(w, "Please specify a valid session token or username and password", http.StatusUnauthorized)
+			return
+		case "missing":
+			http.Error(w, "Please specify a session token or username and password", http.StatusBadRequest)
+			return
+		case "error":
+			logger.Error().Err(err).Msg("User authentication failed")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		case "allowed_unauthenticated":
+			logger.Warn().Msgf("Authentication failed: username=%s", username)
+		}
+
+		// the claimed user is only logged, handlers never see it unless the
+		// credentials were right
+		if err != nil {
+			username = ""
+		}
+		ctx = context.WithValue(ctx, authKey{}, authResult{username: username, authenticated: err == nil})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) authMode() string {
+	if s.AuthMode == AuthStrict {
+		return AuthStrict
+	}
+	return AuthPermissive
+}
+
+// authenticate returns the user a request is made for. A session token in the
+// Authorization header ("Bearer <token>") is verified locally; clients
+// without one may still send username/password params, which are checked
+// with the user service. The claimed username is also returned for bad
+// credentials, to be logged.
+func (s *Server) authenticate(r *http.Request) (string, error) {
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		username, err := session.Verify(s.SessionSecret, token)
+		if err != nil {
+			return "", errBadCredentials
+		}
+		return username, nil
+	}
+
+	username, password := r.URL.Query().Get("username"), r.URL.Query().Get("password")
+	if username == "" || password == "" {
+		return "", errNoCredentials
+	}
+
+	recResp, err := s.userClient.CheckUser(r.Context(), &user.Request{
+		Username: username,
+		Password: password,
+	})
+	if err != nil {
+		return username, err
+	}
+	if !recResp.Correct {
+		return username, errBadCredentials
+	}
+	return username, nil
+}
@@ -0,0 +1,134 @@
+package frontend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	user "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/user/proto"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/session"
+	"google.golang.org/grpc"
+)
+
+var testSecret = []byte("test-secret")
+
+// users accepts password "pass" for every user
+type users struct {
+	user.UserClient
+}
+
+func (users) CheckUser(ctx context.Context, in *user.Request, opts ...grpc.CallOption) (*user.Result, error) {
+	return &user.Result{Correct: in.Password == "pass"}, nil
+}
+
+func TestWithAuth(t *testing.T) {
+	token, _, err := session.Sign(testSecret, "Cornell_1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, _, err := session.Sign([]byte("other-secret"), "Cornell_1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	none := func(r *http.Request) {}
+	password := func(pass string) func(r *http.Request) {
+		return func(r *http.Request) {
+			r.URL.RawQuery = "username=Cornell_1&password=" + pass
+		}
+	}
+	bearer := func(token string) func(r *http.Request) {
+		return func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		optional bool
+		creds    func(r *http.Request)
+		status   int
+		// user and authenticated seen by the handler
+		user          string
+		authenticated bool
+	}{
+		{"strict, none", AuthStrict, false, none, http.StatusUnauthorized, "", false},
+		{"strict, bad password", AuthStrict, false, password("wrong"), http.StatusUnauthorized, "", false},
+		{"strict, bad token", AuthStrict, false, bearer(forged), http.StatusUnauthorized, "", false},
+		{"strict, good password", AuthStrict, false, password("pass"), http.StatusOK, "Cornell_1", true},
+		{"strict, good token", AuthStrict, false, bearer(token), http.StatusOK, "Cornell_1", true},
+		{"strict, optional, none", AuthStrict, true, none, http.StatusOK, "", false},
+		{"strict, optional, bad password", AuthStrict, true, password("wrong"), http.StatusUnauthorized, "", false},
+
+		{"permissive, none", AuthPermissive, false, none, http.StatusBadRequest, "", false},
+		{"permissive, bad password", AuthPermissive, false, password("wrong"), http.StatusOK, "", false},
+		{"permissive, bad token", AuthPermissive, false, bearer(forged), http.StatusOK, "", false},
+		{"permissive, good password", AuthPermissive, false, password("pass"), http.StatusOK, "Cornell_1", true},
+		{"permissive, good token", AuthPermissive, false, bearer(token), http.StatusOK, "Cornell_1", true},
+		{"permissive, optional, none", AuthPermissive, true, none, http.StatusOK, "", false},
+		{"permissive, optional, bad password", AuthPermissive, true, password("wrong"), http.StatusOK, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{userClient: users{}, SessionSecret: testSecret, AuthMode: tt.mode}
+
+			called := false
+			var gotUser string
+			var gotAuthenticated bool
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				gotUser, gotAuthenticated = authUser(r.Context())
+			})
+			h := s.requireAuth(next)
+			if tt.optional {
+				h = s.optionalAuth(next)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/reservation", nil)
+			tt.creds(r)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("got status %d, want %d", w.Code, tt.status)
+			}
+			if called != (tt.status == http.StatusOK) {
+				t.Errorf("handler called: %v", called)
+			}
+			if gotUser != tt.user || gotAuthenticated != tt.authenticated {
+				t.Errorf("got user %q authenticated %v, want %q %v", gotUser, gotAuthenticated, tt.user, tt.authenticated)
+			}
+		})
+	}
+}
+
+func TestSignedInUser(t *testing.T) {
+	s := &Server{userClient: users{}, SessionSecret: testSecret, AuthMode: AuthPermissive}
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{"bad password", "username=Cornell_1&password=wrong", http.StatusUnauthorized},
+		{"good password", "username=Cornell_1&password=pass", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := s.requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, ok := signedInUser(w, r); !ok {
+					return
+				}
+			}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reservation?"+tt.query, nil))
+			if w.Code != tt.status {
+				t.Errorf("got status %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	image "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/image/proto"
+	profile "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/profile/proto"
+	review "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/review/proto"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// maxImageBytes matches the upload limit of the image service
+const maxImageBytes = 3 << 20
+
+// uploadImageHandler stores the request body as an image of the hotel or
+// review given by the hotelId or reviewId param. default=true makes it the
+// image shown first. Review images can only be added by the author.
+func (s *Server) uploadImageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	ctx := r.Context()
+
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &image.UploadRequest{Default: r.URL.Query().Get("default") == "true"}
+	if hotelId := r.URL.Query().Get("hotelId"); hotelId != "" {
+		req.Owner, req.OwnerId = image.Owner_HOTEL, hotelId
+	} else if reviewId := r.URL.Query().Get("reviewId"); reviewId != "" {
+		req.Owner, req.OwnerId = image.Owner_REVIEW, reviewId
+	} else {
+		http.Error(w, "Please specify hotelId or reviewId params", http.StatusBadRequest)
+		return
+	}
+	if !s.checkImageOwner(w, r, req.Owner, req.OwnerId) {
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImageBytes))
+	if err != nil {
+		http.Error(w, "Image is larger than 3MB", http.StatusRequestEntityTooLarge)
+		return
+	}
+	req.Data = data
+
+	info, err := s.imageClient.Upload(ctx, req)
+	if err != nil {
+		logger.Error().Err(err).Msg("Image upload failed")
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
+		return
+	}
+
+	logger.Info().Msgf("Image uploaded: image_id=%s, owner_id=%s", info.Id, info.OwnerId)
+
+	res := map[string]interface{}{
+		"message": "Image uploaded!",
+		"image":   info,
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+// checkImageOwner answers with 401 and returns false for requests without
+// valid credentials, with 404 when the hotel or review an image is uploaded
+// for does not exist, and with 403 when the review was written by someone
+// else than the user of the request
+func (s *Server) checkImageOwner(w http.ResponseWriter, r *http.Request, owner image.Owner, ownerId string) bool {
+	ctx := r.Context()
+
+	username, ok := signedInUser(w, r)
+	if !ok {
+		return false
+	}
+
+	if owner == image.Owner_HOTEL {
+		profResp, err := s.profileClient.GetProfiles(ctx, &profile.Request{HotelIds: []string{ownerId}})
+		if err != nil {
+			http.Error(w, err.Error(), grpcToHTTPStatus(err))
+			return false
+		}
+		for _, h := range profResp.Hotels {
+			if h.Id == ownerId {
+				return true
+			}
+		}
+		http.Error(w, fmt.Sprintf("hotel %q not found", ownerId), http.StatusNotFound)
+		return false
+	}
+
+	rev, err := s.reviewClient.GetReview(ctx, &review.ReviewRequest{ReviewId: ownerId})
+	if err != nil {
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
+		return false
+	}
+	if rev.Name != username {
+		http.Error(w, "Images can only be added to your own reviews", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// imageHandler serves /images/<id> and /images/<id>/thumbnail, the latter
+// taking an optional size param with the longest side in pixels
+func (s *Server) imageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	ctx := r.Context()
+
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, variant, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/images/"), "/")
+	if id == "" {
+		http.Error(w, "Please specify an image id", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		img *image.ImageData
+		err error
+	)
+	switch variant {
+	case "":
+		img, err = s.imageClient.Fetch(ctx, &image.FetchRequest{Id: id})
+	case "thumbnail":
+		var size int
+		if sSize := r.URL.Query().Get("size"); sSize != "" {
+			if size, err = strconv.Atoi(sSize); err != nil {
+				http.Error(w, "Please specify a numeric size param", http.StatusBadRequest)
+				return
+			}
+		}
+		img, err = s.imageClient.Thumbnail(ctx, &image.ThumbnailRequest{Id: id, Size: int32(size)})
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Msgf("Get image failed: image_id=%s", id)
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
+		return
+	}
+
+	// images never change once uploaded
+	w.Header().Set("Content-Type", img.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(img.Data)))
+	w.Header().Set("Cache-Control", "public, max-age=86400, immutable")
+	w.Write(img.Data)
+}
@@ -0,0 +1,194 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	attractions "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/attractions/proto"
+	profile "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/profile/proto"
+	rate "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate/proto"
+	review "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/review/proto"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// the profile is needed to show anything, so it gets the most time
+	profileTimeout     = 1 * time.Second
+	reviewTimeout      = 500 * time.Millisecond
+	rateTimeout        = 800 * time.Millisecond
+	attractionsTimeout = 300 * time.Millisecond
+	// reviewPageSize is the number of reviews on a hotel page
+	reviewPageSize = 5
+)
+
+// itineraryPart is one call of a hotel page, answered by a single service.
+// A part that fails or runs out of time leaves its key out of the page.
+type itineraryPart struct {
+	name    string
+	timeout time.Duration
+	fetch   func(ctx context.Context) (interface{}, error)
+}
+
+// itineraryParts returns the calls making up the page of a hotel. Rates are
+// only asked for when the page is for a stay.
+func (s *Server) itineraryParts(hotelId string, q map[string][]string) []itineraryPart {
+	get := func(key string) string {
+		if v := q[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	parts := []itineraryPart{
+		{"reviews", reviewTimeout, func(ctx context.Context) (interface{}, error) {
+			res, err := s.reviewClient.GetReviews(ctx, &review.Request{HotelId: hotelId, PageSize: reviewPageSize})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"reviews": res.Reviews, "nextCursor": res.NextCursor}, nil
+		}},
+		{"rating", reviewTimeout, func(ctx context.Context) (interface{}, error) {
+			res, err := s.reviewClient.GetRatingSummary(ctx, &review.RatingSummaryRequest{HotelIds: []string{hotelId}})
+			if err != nil {
+				return nil, err
+			}
+			return res.Summaries[hotelId], nil
+		}},
+	}
+
+	if inDate, outDate := get("inDate"), get("outDate"); inDate != "" && outDate != "" {
+		parts = append(parts, itineraryPart{"rates", rateTimeout, func(ctx context.Context) (interface{}, error) {
+			res, err := s.rateClient.GetRates(ctx, &rate.Request{
+				HotelIds: []string{hotelId},
+				InDate:   inDate,
+				OutDate:  outDate,
+				Currency: strings.ToUpper(get("currency")),
+			})
+			if err != nil {
+				return nil, err
+			}
+			return res.RatePlans, nil
+		}})
+	}
+
+	// one call per category, so a slow index only costs its own section
+	for _, c := range []struct{ name, category string }{
+		{"restaurants", "restaurant"},
+		{"museums", "museum"},
+		{"cinemas", "cinema"},
+	} {
+		category := c.category
+		parts = append(parts, itineraryPart{c.name, attractionsTimeout, func(ctx context.Context) (interface{}, error) {
+			res, err := s.attractionsClient.Nearby(ctx, &attractions.NearbyRequest{HotelId: hotelId, Category: category})
+			if err != nil {
+				return nil, err
+			}
+			return res.Attractions, nil
+		}})
+	}
+	return parts
+}
+
+// hotelPageHandler serves GET /hotel/{id}: the profile of a hotel with its
+// latest reviews, rating summary, nearby attractions and, given inDate and
+// outDate, its rates. All services are called at once, each with its own
+// timeout; sections that fail are listed under "failed" instead of failing
+// the page. Only a missing or failed profile fails the request.
+func (s *Server) hotelPageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	ctx := r.Context()
+
+	// Get logger with trace context
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	span := trace.SpanFromContext(ctx)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hotelId := strings.Trim(strings.TrimPrefix(r.URL.Path, "/hotel/"), "/")
+	if hotelId == "" || strings.Contains(hotelId, "/") {
+		http.Error(w, "Please specify a hotel id", http.StatusBadRequest)
+		return
+	}
+	span.SetAttributes(attribute.String("hotel.id", hotelId))
+	logger.Info().Msgf("Getting hotel page: hotel_id=%s", hotelId)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		res     = map[string]interface{}{"hotelId": hotelId}
+		failed  = map[string]string{}
+		profErr error
+		hotel   *profile.Hotel
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		pctx, cancel := context.WithTimeout(ctx, profileTimeout)
+		defer cancel()
+		prof, err := s.profileClient.GetProfiles(pctx, &profile.Request{
+			HotelIds: []string{hotelId},
+			Locale:   r.URL.Query().Get("locale"),
+		})
+		if err != nil {
+			profErr = err
+		} else if len(prof.Hotels) > 0 {
+			hotel = prof.Hotels[0]
+		}
+	}()
+
+	for _, part := range s.itineraryParts(hotelId, r.URL.Query()) {
+		wg.Add(1)
+		go func(part itineraryPart) {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, part.timeout)
+			defer cancel()
+			v, err := part.fetch(pctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				logger.Warn().Msgf("Hotel page section failed: hotel_id=%s, section=%s, error=%v", hotelId, part.name, err)
+				failed[part.name] = err.Error()
+				return
+			}
+			res[part.name] = v
+		}(part)
+	}
+	wg.Wait()
+
+	if profErr != nil {
+		logger.Error().Msgf("Hotel page failed at profile: hotel_id=%s, error=%v", hotelId, profErr)
+		http.Error(w, "Failed to get profile: "+profErr.Error(), grpcToHTTPStatus(profErr))
+		return
+	}
+	if hotel == nil || hotel.Id == "" {
+		http.Error(w, "Hotel not found", http.StatusNotFound)
+		return
+	}
+	res["hotel"] = hotel
+
+	if len(failed) > 0 {
+		res["failed"] = failed
+		span.SetAttributes(attribute.Int("hotel.page.failed", len(failed)))
+	}
+
+	logger.Info().Msgf("Hotel page completed: hotel_id=%s, sections=%d, failed=%d", hotelId, len(res)-2, len(failed))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,181 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	attractions "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/attractions/proto"
+	geo "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/geo/proto"
+	profile "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/profile/proto"
+	rate "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate/proto"
+	recommendation "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/recommendation/proto"
+	reservation "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/reservation/proto"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newHotel is the body of a POST /hotel request
+type newHotel struct {
+	Profile *profile.Hotel `json:"profile"`
+	// rooms available per night
+	Capacity int32 `json:"capacity"`
+	// rating and price used by the recommendation service
+	Rating    float64          `json:"rating"`
+	Price     float64          `json:"price"`
+	RatePlans []*rate.RatePlan `json:"ratePlans"`
+}
+
+// onboardingStep writes one part of a new hotel to the service owning it
+type onboardingStep struct {
+	name string
+	run  func(ctx context.Context, h *newHotel) error
+	// last steps are skipped when any step before them failed
+	last bool
+}
+
+// onboardingSteps returns the writes done after the profile was created. Geo
+// goes last and is skipped after a failure, so a hotel only shows up in
+// searches once it can be priced and booked.
+func (s *Server) onboardingSteps() []onboardingStep {
+	return []onboardingStep{
+		{"rate", func(ctx context.Context, h *newHotel) error {
+			if len(h.RatePlans) == 0 {
+				return nil
+			}
+			_, err := s.rateClient.AddRatePlans(ctx, &rate.RatePlans{RatePlans: h.RatePlans})
+			return err
+		}, false},
+		{"reservation", func(ctx context.Context, h *newHotel) error {
+			_, err := s.reservationClient.SetCapacity(ctx, &reservation.Capacity{
+				HotelId:    h.Profile.Id,
+				RoomNumber: h.Capacity,
+			})
+			return err
+		}, false},
+		{"recommendation", func(ctx context.Context, h *newHotel) error {
+			_, err := s.recommendationClient.AddHotel(ctx, &recommendation.Hotel{
+				HotelId: h.Profile.Id,
+				Lat:     float64(h.Profile.Address.Lat),
+				Lon:     float64(h.Profile.Address.Lon),
+				Rate:    h.Rating,
+				Price:   h.Price,
+			})
+			return err
+		}, false},
+		{"attractions", func(ctx context.Context, h *newHotel) error {
+			_, err := s.attractionsClient.AddHotel(ctx, &attractions.HotelLocation{
+				HotelId: h.Profile.Id,
+				Lat:     float64(h.Profile.Address.Lat),
+				Lon:     float64(h.Profile.Address.Lon),
+			})
+			return err
+		}, false},
+		{"geo", func(ctx context.Context, h *newHotel) error {
+			_, err := s.geoClient.AddHotel(ctx, &geo.HotelLocation{
+				HotelId: h.Profile.Id,
+				Lat:     float64(h.Profile.Address.Lat),
+				Lon:     float64(h.Profile.Address.Lon),
+			})
+			return err
+		}, true},
+	}
+}
+
+// createHotelHandler onboards a hotel into every service that keeps data
+// about it. The profile is created first and is required, and ids that
+// already exist are rejected with a 409 before anything else is written, so
+// no rooms, rates or locations of an existing hotel can be overwritten.
+// Failures of the remaining writes are reported with a 207. Hotels are only
+// created with valid credentials, also in permissive mode, as no benchmark
+// workload creates them.
+func (s *Server) createHotelHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	ctx := r.Context()
+
+	// Get logger with trace context
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	span := trace.SpanFromContext(ctx)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, authenticated := authUser(ctx); !authenticated {
+		http.Error(w, "Please specify a valid session token or username and password", http.StatusUnauthorized)
+		return
+	}
+
+	h := new(newHotel)
+	if err := json.NewDecoder(r.Body).Decode(h); err != nil {
+		http.Error(w, "Malformed hotel: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.Profile == nil || h.Profile.Id == "" || h.Profile.Name == "" || h.Profile.Address == nil {
+		http.Error(w, "Please specify profile id, name and address", http.StatusBadRequest)
+		return
+	}
+	if h.Capacity <= 0 {
+		http.Error(w, "Please specify a positive capacity", http.StatusBadRequest)
+		return
+	}
+	for _, plan := range h.RatePlans {
+		if plan.HotelId != "" && plan.HotelId != h.Profile.Id {
+			http.Error(w, "Rate plans must belong to the new hotel", http.StatusBadRequest)
+			return
+		}
+		plan.HotelId = h.Profile.Id
+	}
+
+	hotelId := h.Profile.Id
+	span.SetAttributes(attribute.String("hotel.id", hotelId))
+	logger.Info().Msgf("Onboarding hotel: hotel_id=%s, capacity=%d, rate_plans=%d", hotelId, h.Capacity, len(h.RatePlans))
+
+	// without a profile the hotel cannot be shown anywhere, so stop here
+	if _, err := s.profileClient.AddProfile(ctx, h.Profile); err != nil {
+		logger.Error().Msgf("Onboarding failed at profile: hotel_id=%s, error=%v", hotelId, err)
+		http.Error(w, "Failed to store profile: "+err.Error(), grpcToHTTPStatus(err))
+		return
+	}
+
+	done := []string{"profile"}
+	failed := map[string]string{}
+	skipped := []string{}
+	for _, step := range s.onboardingSteps() {
+		if step.last && len(failed) > 0 {
+			logger.Warn().Msgf("Onboarding step skipped: hotel_id=%s, step=%s", hotelId, step.name)
+			skipped = append(skipped, step.name)
+			continue
+		}
+		if err := step.run(ctx, h); err != nil {
+			logger.Error().Msgf("Onboarding step failed: hotel_id=%s, step=%s, error=%v", hotelId, step.name, err)
+			failed[step.name] = err.Error()
+			continue
+		}
+		done = append(done, step.name)
+	}
+
+	res := map[string]interface{}{
+		"hotelId": hotelId,
+		"created": done,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if len(failed) > 0 {
+		res["failed"] = failed
+		res["skipped"] = skipped
+		span.SetAttributes(attribute.Int("hotel.onboarding.failed", len(failed)))
+		w.WriteHeader(http.StatusMultiStatus)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	logger.Info().Msgf("Onboarding completed: hotel_id=%s, created=%d, failed=%d", hotelId, len(done), len(failed))
+	json.NewEncoder(w).Encode(res)
+}
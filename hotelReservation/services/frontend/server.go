@@ -1,17 +1,22 @@
 package frontend
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
 	"io/fs"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/dialer"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
 	attractions "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/attractions/proto"
+	geo "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/geo/proto"
+	image "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/image/proto"
 	profile "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/profile/proto"
+	rate "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate/proto"
 	recommendation "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/recommendation/proto"
 	reservation "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/reservation/proto"
 	review "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/review/proto"
@@ -19,11 +24,12 @@ import (
 	user "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/user/proto"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tracing"
-	_ "github.com/mbobakov/grpc-consul-resolver"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -40,21 +46,37 @@ type Server struct {
 	reviewClient         review.ReviewClient
 	attractionsClient    attractions.AttractionsClient
 	reservationClient    reservation.ReservationClient
-
-	KnativeDns string
-	IpAddr     string
-	ConsulAddr string
-	Port       int
-	Tracer     trace.Tracer
-	Registry   *registry.Client
+	geoClient            geo.GeoClient
+	rateClient           rate.RateClient
+	imageClient          image.ImageClient
+
+	IpAddr        string
+	Port          int
+	Tracer        trace.Tracer
+	Registry      registry.Registry
+	SessionSecret []byte
+	// AuthMode is AuthStrict or AuthPermissive, see requireAuth
+	AuthMode string
+
+	httpServer *http.Server
 }
 
-// Run the server
-func (s *Server) Run() error {
+// Setup connects to the backend services and creates the http server. It is
+// called before Run, so Shutdown only reads what Setup wrote.
+func (s *Server) Setup() error {
 	if s.Port == 0 {
 		return fmt.Errorf("Server port must be set")
 	}
 
+	switch s.AuthMode {
+	case "", AuthPermissive, AuthStrict:
+	default:
+		return fmt.Errorf("unknown auth mode %q", s.AuthMode)
+	}
+	if len(s.SessionSecret) == 0 {
+		return fmt.Errorf("session secret must be set, see SESSION_SECRET")
+	}
+
 	log.Info().Msg("Loading static content...")
 	staticContent, err := fs.Sub(content, "static")
 	if err != nil {
@@ -90,19 +112,38 @@ func (s *Server) Run() error {
 		return err
 	}
 
+	if err := s.initGeoClient("srv-geo"); err != nil {
+		return err
+	}
+
+	if err := s.initRateClient("srv-rate"); err != nil {
+		return err
+	}
+
+	if err := s.initImageClient("srv-image"); err != nil {
+		return err
+	}
+
 	log.Info().Msg("Successful")
 
 	log.Trace().Msg("frontend before mux")
 	mux := tracing.NewServeMux(s.Tracer)
 	mux.Handle("/", http.FileServer(http.FS(staticContent)))
 	mux.Handle("/hotels", http.HandlerFunc(s.searchHandler))
-	mux.Handle("/recommendations", http.HandlerFunc(s.recommendHandler))
+	mux.Handle("/hotels/city", http.HandlerFunc(s.citySearchHandler))
+	mux.Handle("/recommendations", s.optionalAuth(http.HandlerFunc(s.recommendHandler)))
 	mux.Handle("/user", http.HandlerFunc(s.userHandler))
-	mux.Handle("/review", http.HandlerFunc(s.reviewHandler))
-	mux.Handle("/restaurants", http.HandlerFunc(s.restaurantHandler))
-	mux.Handle("/museums", http.HandlerFunc(s.museumHandler))
-	mux.Handle("/cinema", http.HandlerFunc(s.cinemaHandler))
-	mux.Handle("/reservation", http.HandlerFunc(s.reservationHandler))
+	mux.Handle("/login", http.HandlerFunc(s.loginHandler))
+	mux.Handle("/review", s.requireAuth(http.HandlerFunc(s.reviewHandler)))
+	mux.Handle("/restaurants", s.requireAuth(http.HandlerFunc(s.restaurantHandler)))
+	mux.Handle("/museums", s.requireAuth(http.HandlerFunc(s.museumHandler)))
+	mux.Handle("/cinema", s.requireAuth(http.HandlerFunc(s.cinemaHandler)))
+	mux.Handle("/attractions", s.requireAuth(http.HandlerFunc(s.attractionsHandler)))
+	mux.Handle("/reservation", s.requireAuth(http.HandlerFunc(s.reservationHandler)))
+	mux.Handle("/hotel", s.requireAuth(http.HandlerFunc(s.createHotelHandler)))
+	mux.Handle("/hotel/", s.requireAuth(http.HandlerFunc(s.hotelPageHandler)))
+	mux.Handle("/images", s.requireAuth(http.HandlerFunc(s.uploadImageHandler)))
+	mux.Handle("/images/", http.HandlerFunc(s.imageHandler))
 
 	log.Trace().Msg("frontend starts serving")
 
@@ -112,13 +153,29 @@ func (s *Server) Run() error {
 		Handler: mux,
 	}
 	if tlsconfig != nil {
-		log.Info().Msg("Serving https")
 		srv.TLSConfig = tlsconfig
-		return srv.ListenAndServeTLS("x509/server_cert.pem", "x509/server_key.pem")
-	} else {
-		log.Info().Msg("Serving http")
-		return srv.ListenAndServe()
 	}
+	s.httpServer = srv
+	return nil
+}
+
+// Run serves the http server set up by Setup until Shutdown
+func (s *Server) Run() error {
+	if s.httpServer.TLSConfig != nil {
+		log.Info().Msg("Serving https")
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
+	log.Info().Msg("Serving http")
+	return s.httpServer.ListenAndServe()
+}
+
+// Shutdown stops accepting requests and waits for the pending ones until ctx
+// is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Shutdown(ctx)
 }
 
 func (s *Server) initSearchClient(name string) error {
@@ -131,11 +188,7 @@ func (s *Server) initSearchClient(name string) error {
 }
 
 func (s *Server) initReviewClient(name string) error {
-	conn, err := dialer.Dial(
-		name,
-		dialer.WithTracer(s.Tracer),
-		dialer.WithBalancer(s.Registry.Client),
-	)
+	conn, err := s.getGprcConn(name)
 	if err != nil {
 		return fmt.Errorf("dialer error: %v", err)
 	}
@@ -188,22 +241,44 @@ func (s *Server) initReservation(name string) error {
 	return nil
 }
 
+func (s *Server) initGeoClient(name string) error {
+	conn, err := s.getGprcConn(name)
+	if err != nil {
+		return fmt.Errorf("dialer error: %v", err)
+	}
+	s.geoClient = geo.NewGeoClient(conn)
+	return nil
+}
+
+func (s *Server) initRateClient(name string) error {
+	conn, err := s.getGprcConn(name)
+	if err != nil {
+		return fmt.Errorf("dialer error: %v", err)
+	}
+	s.rateClient = rate.NewRateClient(conn)
+	return nil
+}
+
+func (s *Server) initImageClient(name string) error {
+	conn, err := s.getGprcConn(name)
+	if err != nil {
+		return fmt.Errorf("dialer error: %v", err)
+	}
+	s.imageClient = image.NewImageClient(conn)
+	return nil
+}
+
 func (s *Server) getGprcConn(name string) (*grpc.ClientConn, error) {
-	log.Info().Msg("get Grpc conn is :")
-	log.Info().Msg(s.KnativeDns)
-	log.Info().Msg(fmt.Sprintf("%s.%s", name, s.KnativeDns))
-
-	if s.KnativeDns != "" {
-		return dialer.Dial(
-			fmt.Sprintf("consul://%s/%s.%s", s.ConsulAddr, name, s.KnativeDns),
-			dialer.WithTracer(s.Tracer))
-	} else {
-		return dialer.Dial(
-			fmt.Sprintf("consul://%s/%s", s.ConsulAddr, name),
-			dialer.WithTracer(s.Tracer),
-			dialer.WithBalancer(s.Registry.Client),
-		)
+	target, err := s.Registry.Target(name)
+	if err != nil {
+		return nil, err
 	}
+	return dialer.Dial(
+		target,
+		dialer.WithTracer(s.Tracer),
+		dialer.WithBalancer(""),
+		dialer.WithEnvPolicies(),
+	)
 }
 
 func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
@@ -252,18 +327,54 @@ func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
 	Lon, _ := strconv.ParseFloat(sLon, 32)
 	lon := float32(Lon)
 
-	logger.Debug().Msgf("Querying search service: lat=%v, lon=%v, in_date=%s, out_date=%s", lat, lon, inDate, outDate)
+	// optional radius (km), limit, exclude and minRating params
+	nearbyReq := &search.NearbyRequest{
+		Lat:      lat,
+		Lon:      lon,
+		InDate:   inDate,
+		OutDate:  outDate,
+		Currency: r.URL.Query().Get("currency"),
+	}
+	if sRadius := r.URL.Query().Get("radius"); sRadius != "" {
+		radius, err := strconv.ParseFloat(sRadius, 32)
+		if err != nil || radius <= 0 {
+			http.Error(w, "Please specify a positive radius param", http.StatusBadRequest)
+			return
+		}
+		nearbyReq.Radius = float32(radius)
+	}
+	if sLimit := r.URL.Query().Get("limit"); sLimit != "" {
+		limit, err := strconv.Atoi(sLimit)
+		if err != nil || limit <= 0 {
+			http.Error(w, "Please specify a positive limit param", http.StatusBadRequest)
+			return
+		}
+		nearbyReq.Limit = int32(limit)
+	}
+	if sExclude := r.URL.Query().Get("exclude"); sExclude != "" {
+		for _, id := range strings.Split(sExclude, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				nearbyReq.ExcludeHotelIds = append(nearbyReq.ExcludeHotelIds, id)
+			}
+		}
+	}
+	if sMinRating := r.URL.Query().Get("minRating"); sMinRating != "" {
+		minRating, err := strconv.ParseFloat(sMinRating, 64)
+		if err != nil {
+			http.Error(w, "Please specify a numeric minRating param", http.StatusBadRequest)
+			return
+		}
+		nearbyReq.MinRating = minRating
+	}
+
+	logger.Debug().Msgf("Querying search service: lat=%v, lon=%v, in_date=%s, out_date=%s, radius=%v, limit=%v, exclude=%v, min_rating=%v",
+		lat, lon, inDate, outDate, nearbyReq.Radius, nearbyReq.Limit, nearbyReq.ExcludeHotelIds, nearbyReq.MinRating)
 
 	// search for best hotels
-	searchResp, err := s.searchClient.Nearby(ctx, &search.NearbyRequest{
-		Lat:     lat,
-		Lon:     lon,
-		InDate:  inDate,
-		OutDate: outDate,
-	})
+	searchResp, err := s.searchClient.Nearby(ctx, nearbyReq)
 	if err != nil {
 		logger.Error().Err(err).Msg("Search service failed")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
 		return
 	}
 
@@ -306,7 +417,107 @@ func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
 
 	logger.Info().Msgf("Search request completed: profiles_returned=%d", len(profileResp.Hotels))
 
-	json.NewEncoder(w).Encode(geoJSONResponse(profileResp.Hotels))
+	res := geoJSONResponse(profileResp.Hotels)
+	res["prices"] = hotelPrices(profileResp.Hotels, searchResp.Prices)
+	json.NewEncoder(w).Encode(res)
+}
+
+// citySearchHandler searches hotels by city, postal code or keyword instead of location
+func (s *Server) citySearchHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	ctx := r.Context()
+
+	// Get logger with trace context
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+	
+	// Extract trace information and add to logger
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		spanCtx := span.SpanContext()
+		if spanCtx.HasTraceID() {
+			newLogger := logger.With().Str("trace_id", spanCtx.TraceID().String()).Logger()
+			logger = &newLogger
+		}
+		if spanCtx.HasSpanID() {
+			newLogger := logger.With().Str("span_id", spanCtx.SpanID().String()).Logger()
+			logger = &newLogger
+		}
+	}
+
+	logger.Info().Msg("Processing city search request")
+
+	// in/out dates from query params
+	inDate, outDate := r.URL.Query().Get("inDate"), r.URL.Query().Get("outDate")
+	if inDate == "" || outDate == "" {
+		http.Error(w, "Please specify inDate/outDate params", http.StatusBadRequest)
+		return
+	}
+
+	city, postalCode, keyword := r.URL.Query().Get("city"), r.URL.Query().Get("postalCode"), r.URL.Query().Get("keyword")
+	if city == "" && postalCode == "" && keyword == "" {
+		http.Error(w, "Please specify city, postalCode or keyword params", http.StatusBadRequest)
+		return
+	}
+
+	logger.Debug().Msgf("Querying search service: city=%s, postal_code=%s, keyword=%s, in_date=%s, out_date=%s", city, postalCode, keyword, inDate, outDate)
+
+	searchResp, err := s.searchClient.City(ctx, &search.CityRequest{
+		City:       city,
+		PostalCode: postalCode,
+		Keyword:    keyword,
+		InDate:     inDate,
+		OutDate:    outDate,
+		Currency:   r.URL.Query().Get("currency"),
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Search service failed")
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
+		return
+	}
+
+	logger.Debug().Msgf("Search service completed: hotels_found=%d", len(searchResp.HotelIds))
+
+	// grab locale from query params or default to en
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = "en"
+	}
+
+	reservationResp, err := s.reservationClient.CheckAvailability(ctx, &reservation.Request{
+		CustomerName: "",
+		HotelId:      searchResp.HotelIds,
+		InDate:       inDate,
+		OutDate:      outDate,
+		RoomNumber:   1,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Reservation check availability failed")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Debug().Msgf("Checked availability: available_hotels=%d", len(reservationResp.HotelId))
+
+	// hotel profiles
+	profileResp, err := s.profileClient.GetProfiles(ctx, &profile.Request{
+		HotelIds: reservationResp.HotelId,
+		Locale:   locale,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Get profiles failed")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info().Msgf("City search request completed: profiles_returned=%d", len(profileResp.Hotels))
+
+	res := geoJSONResponse(profileResp.Hotels)
+	res["prices"] = hotelPrices(profileResp.Hotels, searchResp.Prices)
+	json.NewEncoder(w).Encode(res)
 }
 
 func (s *Server) recommendHandler(w http.ResponseWriter, r *http.Request) {
@@ -344,23 +555,52 @@ func (s *Server) recommendHandler(w http.ResponseWriter, r *http.Request) {
 	Lon, _ := strconv.ParseFloat(sLon, 64)
 	lon := float64(Lon)
 
+	// either a single require or weighted criteria, e.g. criteria=dis:2,price:1;
+	// signed in users also get hotels similar to their past bookings
+	username, _ := authUser(ctx)
 	require := r.URL.Query().Get("require")
-	if require != "dis" && require != "rate" && require != "price" {
+	criteria, err := parseCriteria(r.URL.Query().Get("criteria"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(criteria) == 0 && require != "dis" && require != "rate" && require != "price" && (require != "" || username == "") {
 		http.Error(w, "Please specify require params", http.StatusBadRequest)
 		return
 	}
 
-	logger.Info().Msgf("Processing recommendation request: lat=%v, lon=%v, require_type=%s", lat, lon, require)
+	recInput := recommendation.Request{
+		Require:  require,
+		Lat:      float64(lat),
+		Lon:      float64(lon),
+		Criteria: criteria,
+		Username: username,
+	}
+	if sK := r.URL.Query().Get("k"); sK != "" {
+		k, err := strconv.Atoi(sK)
+		if err != nil || k <= 0 {
+			http.Error(w, "Please specify a positive k param", http.StatusBadRequest)
+			return
+		}
+		recInput.K = int32(k)
+	}
+	if sRadius := r.URL.Query().Get("radius"); sRadius != "" {
+		radius, err := strconv.ParseFloat(sRadius, 64)
+		if err != nil || radius <= 0 {
+			http.Error(w, "Please specify a positive radius param", http.StatusBadRequest)
+			return
+		}
+		recInput.Radius = radius
+	}
+
+	logger.Info().Msgf("Processing recommendation request: lat=%v, lon=%v, require_type=%s, criteria=%d, k=%d, radius=%v, username=%s",
+		lat, lon, require, len(criteria), recInput.K, recInput.Radius, username)
 
 	// recommend hotels
-	recResp, err := s.recommendationClient.GetRecommendations(ctx, &recommendation.Request{
-		Require: require,
-		Lat:     float64(lat),
-		Lon:     float64(lon),
-	})
+	recResp, err := s.recommendationClient.GetRecommendations(ctx, &recInput)
 	if err != nil {
 		logger.Error().Err(err).Msg("Recommendation service failed")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
 		return
 	}
 
@@ -383,32 +623,52 @@ func (s *Server) recommendHandler(w http.ResponseWriter, r *http.Request) {
 
 	logger.Info().Msgf("Recommendation request completed: recommendations=%d", len(profileResp.Hotels))
 
-	json.NewEncoder(w).Encode(geoJSONResponse(profileResp.Hotels))
+	// features are unordered, so the ranking is returned next to them
+	res := geoJSONResponse(profileResp.Hotels)
+	res["ranking"] = recResp.Ranked
+	json.NewEncoder(w).Encode(res)
+}
+
+// parseCriteria parses comma separated name:weight pairs; a missing weight is 1
+func parseCriteria(param string) ([]*recommendation.Criterion, error) {
+	if param == "" {
+		return nil, nil
+	}
+	var criteria []*recommendation.Criterion
+	for _, field := range strings.Split(param, ",") {
+		name, sWeight, found := strings.Cut(field, ":")
+		weight := 1.0
+		if found {
+			var err error
+			if weight, err = strconv.ParseFloat(sWeight, 64); err != nil || weight < 0 {
+				return nil, fmt.Errorf("malformed weight in criteria param: %q", field)
+			}
+		}
+		if name != "dis" && name != "rate" && name != "price" && name != "similar" {
+			return nil, fmt.Errorf("unknown criterion in criteria param: %q", name)
+		}
+		criteria = append(criteria, &recommendation.Criterion{Name: name, Weight: weight})
+	}
+	return criteria, nil
 }
 
 func (s *Server) reviewHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	ctx := r.Context()
 
-	username, password := r.URL.Query().Get("username"), r.URL.Query().Get("password")
-	if username == "" || password == "" {
-		http.Error(w, "Please specify username and password", http.StatusBadRequest)
-		return
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
 	}
 
-	// Check username and password
-	recResp, err := s.userClient.CheckUser(ctx, &user.Request{
-		Username: username,
-		Password: password,
-	})
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	switch r.Method {
+	case http.MethodPost:
+		s.postReview(w, r, logger)
+		return
+	case http.MethodDelete:
+		s.deleteReview(w, r, logger)
 		return
-	}
-
-	str := "Logged-in successfully!"
-	if recResp.Correct == false {
-		str = "Failed. Please check your username and password. "
 	}
 
 	hotelId := r.URL.Query().Get("hotelId")
@@ -417,52 +677,138 @@ func (s *Server) reviewHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	revInput := review.Request{HotelId: hotelId}
+	// optional paging: pageSize, cursor, sort (rating|date) and order (asc|desc)
+	revInput := review.Request{
+		HotelId:   hotelId,
+		Cursor:    r.URL.Query().Get("cursor"),
+		Ascending: r.URL.Query().Get("order") == "asc",
+	}
+	if sPageSize := r.URL.Query().Get("pageSize"); sPageSize != "" {
+		pageSize, err := strconv.Atoi(sPageSize)
+		if err != nil || pageSize <= 0 {
+			http.Error(w, "Please specify a positive pageSize param", http.StatusBadRequest)
+			return
+		}
+		revInput.PageSize = int32(pageSize)
+	}
+	switch r.URL.Query().Get("sort") {
+	case "":
+	case "rating":
+		revInput.SortBy = review.SortBy_RATING
+	case "date":
+		revInput.SortBy = review.SortBy_DATE
+	default:
+		http.Error(w, "Please specify sort as rating or date", http.StatusBadRequest)
+		return
+	}
 
 	revResp, err := s.reviewClient.GetReviews(ctx, &revInput)
+	if err != nil {
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
+		return
+	}
 
-	str = "Have reviews = " + strconv.Itoa(len(revResp.Reviews))
+	str := "Have reviews = " + strconv.Itoa(len(revResp.Reviews))
 	if len(revResp.Reviews) == 0 {
 		str = "Failed. No Reviews. "
 	}
 
+	res := map[string]interface{}{
+		"message":    str,
+		"reviews":    revResp.Reviews,
+		"nextCursor": revResp.NextCursor,
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
+
+// postReview adds a review from hotelId, rating and description params,
+// signed with the user of the request. Requests without valid credentials are
+// answered with 401 and a name param naming someone else with 403.
+func (s *Server) postReview(w http.ResponseWriter, r *http.Request, logger *zerolog.Logger) {
+	ctx := r.Context()
+
+	hotelId := r.FormValue("hotelId")
+	if hotelId == "" {
+		http.Error(w, "Please specify hotelId params", http.StatusBadRequest)
+		return
+	}
+
+	rating, err := strconv.ParseFloat(r.FormValue("rating"), 32)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Please specify a numeric rating param", http.StatusBadRequest)
 		return
 	}
 
+	name, ok := signedInUser(w, r)
+	if !ok {
+		return
+	}
+	if other := r.FormValue("name"); other != "" && other != name {
+		http.Error(w, "Reviews can only be posted as the signed in user", http.StatusForbidden)
+		return
+	}
+
+	revResp, err := s.reviewClient.PostReview(ctx, &review.ReviewComm{
+		HotelId:     hotelId,
+		Name:        name,
+		Rating:      float32(rating),
+		Description: r.FormValue("description"),
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Post review failed")
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
+		return
+	}
+
+	logger.Info().Msgf("Review posted: hotel_id=%s, review_id=%s", hotelId, revResp.ReviewId)
+
 	res := map[string]interface{}{
-		"message": str,
+		"message": "Review posted!",
+		"review":  revResp,
 	}
 
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(res)
 }
 
-func (s *Server) restaurantHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	ctx := r.Context()
-
-	username, password := r.URL.Query().Get("username"), r.URL.Query().Get("password")
-	if username == "" || password == "" {
-		http.Error(w, "Please specify username and password", http.StatusBadRequest)
+// deleteReview removes the review given by the reviewId param, if the user of
+// the request wrote it. Requests without valid credentials get a 401.
+func (s *Server) deleteReview(w http.ResponseWriter, r *http.Request, logger *zerolog.Logger) {
+	reviewId := r.URL.Query().Get("reviewId")
+	if reviewId == "" {
+		http.Error(w, "Please specify reviewId params", http.StatusBadRequest)
 		return
 	}
 
-	// Check username and password
-	recResp, err := s.userClient.CheckUser(ctx, &user.Request{
-		Username: username,
-		Password: password,
+	username, ok := signedInUser(w, r)
+	if !ok {
+		return
+	}
+	revResp, err := s.reviewClient.DeleteReview(r.Context(), &review.DeleteRequest{
+		ReviewId: reviewId,
+		Name:     username,
 	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.Error().Err(err).Msg("Delete review failed")
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
 		return
 	}
 
-	str := "Logged-in successfully!"
-	if recResp.Correct == false {
-		str = "Failed. Please check your username and password. "
+	logger.Info().Msgf("Review deleted: hotel_id=%s, review_id=%s", revResp.HotelId, reviewId)
+
+	res := map[string]interface{}{
+		"message": "Review deleted!",
+		"review":  revResp,
 	}
 
+	json.NewEncoder(w).Encode(res)
+}
+
+func (s *Server) restaurantHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	ctx := r.Context()
+
 	hotelId := r.URL.Query().Get("hotelId")
 	if hotelId == "" {
 		http.Error(w, "Please specify hotelId params", http.StatusBadRequest)
@@ -481,7 +827,7 @@ func (s *Server) restaurantHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Trace().Msgf("NearbyRest success for hotelId %s: %d restaurants", hotelId, len(revResp.AttractionIds))
 
-	str = "Have restaurants = " + strconv.Itoa(len(revResp.AttractionIds))
+	str := "Have restaurants = " + strconv.Itoa(len(revResp.AttractionIds))
 	if len(revResp.AttractionIds) == 0 {
 		str = "Failed. No Restaurants. "
 	}
@@ -497,27 +843,6 @@ func (s *Server) museumHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	ctx := r.Context()
 
-	username, password := r.URL.Query().Get("username"), r.URL.Query().Get("password")
-	if username == "" || password == "" {
-		http.Error(w, "Please specify username and password", http.StatusBadRequest)
-		return
-	}
-
-	// Check username and password
-	recResp, err := s.userClient.CheckUser(ctx, &user.Request{
-		Username: username,
-		Password: password,
-	})
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	str := "Logged-in successfully!"
-	if recResp.Correct == false {
-		str = "Failed. Please check your username and password. "
-	}
-
 	hotelId := r.URL.Query().Get("hotelId")
 	if hotelId == "" {
 		http.Error(w, "Please specify hotelId params", http.StatusBadRequest)
@@ -536,7 +861,7 @@ func (s *Server) museumHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Trace().Msgf("NearbyMus success for hotelId %s: %d museums", hotelId, len(revResp.AttractionIds))
 
-	str = "Have museums = " + strconv.Itoa(len(revResp.AttractionIds))
+	str := "Have museums = " + strconv.Itoa(len(revResp.AttractionIds))
 	if len(revResp.AttractionIds) == 0 {
 		str = "Failed. No Museums. "
 	}
@@ -552,27 +877,6 @@ func (s *Server) cinemaHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	ctx := r.Context()
 
-	username, password := r.URL.Query().Get("username"), r.URL.Query().Get("password")
-	if username == "" || password == "" {
-		http.Error(w, "Please specify username and password", http.StatusBadRequest)
-		return
-	}
-
-	// Check username and password
-	recResp, err := s.userClient.CheckUser(ctx, &user.Request{
-		Username: username,
-		Password: password,
-	})
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	str := "Logged-in successfully!"
-	if recResp.Correct == false {
-		str = "Failed. Please check your username and password. "
-	}
-
 	hotelId := r.URL.Query().Get("hotelId")
 	if hotelId == "" {
 		http.Error(w, "Please specify hotelId params", http.StatusBadRequest)
@@ -591,7 +895,7 @@ func (s *Server) cinemaHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Trace().Msgf("NearbyCinema success for hotelId %s: %d cinemas", hotelId, len(revResp.AttractionIds))
 
-	str = "Have cinemas = " + strconv.Itoa(len(revResp.AttractionIds))
+	str := "Have cinemas = " + strconv.Itoa(len(revResp.AttractionIds))
 	if len(revResp.AttractionIds) == 0 {
 		str = "Failed. No Cinemas. "
 	}
@@ -603,6 +907,61 @@ func (s *Server) cinemaHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(res)
 }
 
+// attractionsHandler returns the attractions nearest to hotelId with their
+// details and distance, optionally limited to a category and narrowed by
+// radius (km) and limit
+func (s *Server) attractionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	ctx := r.Context()
+
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	hotelId := r.URL.Query().Get("hotelId")
+	if hotelId == "" {
+		http.Error(w, "Please specify hotelId params", http.StatusBadRequest)
+		return
+	}
+
+	req := &attractions.NearbyRequest{
+		HotelId:  hotelId,
+		Category: r.URL.Query().Get("category"),
+	}
+	if sRadius := r.URL.Query().Get("radius"); sRadius != "" {
+		radius, err := strconv.ParseFloat(sRadius, 64)
+		if err != nil {
+			http.Error(w, "Please specify a numeric radius param", http.StatusBadRequest)
+			return
+		}
+		req.Radius = radius
+	}
+	if sLimit := r.URL.Query().Get("limit"); sLimit != "" {
+		limit, err := strconv.Atoi(sLimit)
+		if err != nil {
+			http.Error(w, "Please specify a numeric limit param", http.StatusBadRequest)
+			return
+		}
+		req.Limit = int32(limit)
+	}
+
+	attrResp, err := s.attractionsClient.Nearby(ctx, req)
+	if err != nil {
+		logger.Error().Err(err).Msgf("Nearby attractions failed: hotel_id=%s", hotelId)
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
+		return
+	}
+
+	res := map[string]interface{}{
+		"message":     "Have attractions = " + strconv.Itoa(len(attrResp.Attractions)),
+		"attractions": attrResp.Attractions,
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
+
 func (s *Server) userHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	ctx := r.Context()
@@ -628,6 +987,11 @@ func (s *Server) userHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if r.Method == http.MethodPost {
+		s.registerUser(w, r, logger)
+		return
+	}
+
 	username, password := r.URL.Query().Get("username"), r.URL.Query().Get("password")
 	if username == "" || password == "" {
 		http.Error(w, "Please specify username and password", http.StatusBadRequest)
@@ -661,6 +1025,74 @@ func (s *Server) userHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(res)
 }
 
+// registerUser creates a user from username/password params
+func (s *Server) registerUser(w http.ResponseWriter, r *http.Request, logger *zerolog.Logger) {
+	username, password := r.FormValue("username"), r.FormValue("password")
+	if username == "" || password == "" {
+		http.Error(w, "Please specify username and password", http.StatusBadRequest)
+		return
+	}
+
+	logger.Info().Msgf("User registration request: username=%s", username)
+
+	_, err := s.userClient.RegisterUser(r.Context(), &user.Request{
+		Username: username,
+		Password: password,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("User registration failed")
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
+		return
+	}
+
+	res := map[string]interface{}{
+		"message":  "Registered successfully!",
+		"username": username,
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+// loginHandler exchanges username/password for a session token, to be sent
+// as "Authorization: Bearer <token>" on later requests
+func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	ctx := r.Context()
+
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	username, password := r.FormValue("username"), r.FormValue("password")
+	if username == "" || password == "" {
+		http.Error(w, "Please specify username and password", http.StatusBadRequest)
+		return
+	}
+
+	loginResp, err := s.userClient.Login(ctx, &user.Request{
+		Username: username,
+		Password: password,
+	})
+	if err != nil {
+		logger.Warn().Err(err).Msgf("Login failed: username=%s", username)
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
+		return
+	}
+
+	logger.Info().Msgf("Login completed: username=%s", username)
+
+	res := map[string]interface{}{
+		"message":   "Login successfully!",
+		"token":     loginResp.Token,
+		"expiresAt": loginResp.ExpiresAt,
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
+
 func (s *Server) reservationHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	ctx := r.Context()
@@ -686,6 +1118,18 @@ func (s *Server) reservationHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	switch r.Method {
+	case http.MethodGet:
+		s.getReservations(w, r, logger)
+		return
+	case http.MethodPut:
+		s.modifyReservation(w, r, logger)
+		return
+	case http.MethodDelete:
+		s.cancelReservation(w, r, logger)
+		return
+	}
+
 	inDate, outDate := r.URL.Query().Get("inDate"), r.URL.Query().Get("outDate")
 	if inDate == "" || outDate == "" {
 		http.Error(w, "Please specify inDate/outDate params", http.StatusBadRequest)
@@ -703,42 +1147,20 @@ func (s *Server) reservationHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	customerName := r.URL.Query().Get("customerName")
-	if customerName == "" {
-		http.Error(w, "Please specify customerName params", http.StatusBadRequest)
+	numberOfRoom, err := strconv.Atoi(r.URL.Query().Get("number"))
+	if err != nil || numberOfRoom <= 0 {
+		http.Error(w, "Please specify a positive number params", http.StatusBadRequest)
 		return
 	}
 
-	username, password := r.URL.Query().Get("username"), r.URL.Query().Get("password")
-	if username == "" || password == "" {
-		http.Error(w, "Please specify username and password", http.StatusBadRequest)
+	customerName, ok := customerOf(w, r)
+	if !ok {
 		return
 	}
 
-	numberOfRoom := 0
-	num := r.URL.Query().Get("number")
-	if num != "" {
-		numberOfRoom, _ = strconv.Atoi(num)
-	}
-
-	logger.Info().Msgf("Processing reservation request: hotel_id=%s, in_date=%s, out_date=%s, customer_name=%s, username=%s, room_number=%d", hotelId, inDate, outDate, customerName, username, numberOfRoom)
-
-	// Check username and password
-	recResp, err := s.userClient.CheckUser(ctx, &user.Request{
-		Username: username,
-		Password: password,
-	})
-	if err != nil {
-		logger.Error().Err(err).Msg("User authentication failed")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	logger.Info().Msgf("Processing reservation request: hotel_id=%s, in_date=%s, out_date=%s, customer_name=%s, room_number=%d", hotelId, inDate, outDate, customerName, numberOfRoom)
 
 	str := "Reserve successfully!"
-	if recResp.Correct == false {
-		str = "Failed. Please check your username and password. "
-		logger.Warn().Msgf("Authentication failed for reservation: username=%s", username)
-	}
 
 	// Make reservation
 	resResp, err := s.reservationClient.MakeReservation(ctx, &reservation.Request{
@@ -750,23 +1172,210 @@ func (s *Server) reservationHandler(w http.ResponseWriter, r *http.Request) {
 	})
 	if err != nil {
 		logger.Error().Err(err).Msg("Reservation failed")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
 		return
 	}
-	if len(resResp.HotelId) == 0 {
+	switch resResp.Failure {
+	case reservation.FailureReason_NO_CAPACITY:
 		str = "Failed. Already reserved. "
-		logger.Warn().Msgf("Hotel already reserved: hotel_id=%s", hotelId)
+	case reservation.FailureReason_INVALID_DATES:
+		str = "Failed. Invalid inDate/outDate. "
+	case reservation.FailureReason_UNKNOWN_HOTEL:
+		str = "Failed. Unknown hotel. "
+	}
+	if resResp.Failure != reservation.FailureReason_NONE {
+		logger.Warn().Msgf("Reservation failed: hotel_id=%s, reason=%s", hotelId, resResp.Failure)
 	} else {
-		logger.Info().Msgf("Reservation completed successfully: hotel_id=%s, success=true", hotelId)
+		logger.Info().Msgf("Reservation completed successfully: hotel_id=%s, reservation_id=%s, success=true", hotelId, resResp.ReservationId)
 	}
 
 	res := map[string]interface{}{
 		"message": str,
 	}
+	if resResp.Failure != reservation.FailureReason_NONE {
+		res["failureReason"] = resResp.Failure.String()
+	} else {
+		res["reservationId"] = resResp.ReservationId
+		res["nights"] = resResp.Nights
+		res["totalPrice"] = resResp.TotalPrice
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
+
+// customerOf returns the customer reservations are handled for, the signed in
+// user of the request. Requests without valid credentials are answered with
+// 401 and a customerName param naming someone else with 403.
+func customerOf(w http.ResponseWriter, r *http.Request) (string, bool) {
+	username, ok := signedInUser(w, r)
+	if !ok {
+		return "", false
+	}
+	if name := r.URL.Query().Get("customerName"); name != "" && name != username {
+		http.Error(w, "Reservations can only be handled for the signed in user", http.StatusForbidden)
+		return "", false
+	}
+	return username, true
+}
+
+// checkOwner answers with 404 and returns false when reservationId is not a
+// booking of the user of the request, so nobody can change or even probe the
+// bookings of others. Requests without valid credentials get a 401.
+func (s *Server) checkOwner(w http.ResponseWriter, r *http.Request, reservationId string) bool {
+	username, ok := signedInUser(w, r)
+	if !ok {
+		return false
+	}
+	bookings, err := s.reservationClient.GetReservationsByCustomer(r.Context(), &reservation.CustomerRequest{
+		CustomerName: username,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
+		return false
+	}
+	for _, b := range bookings.Bookings {
+		if b.ReservationId == reservationId {
+			return true
+		}
+	}
+	http.Error(w, fmt.Sprintf("reservation %q not found", reservationId), http.StatusNotFound)
+	return false
+}
+
+// getReservations lists the reservations of the user
+func (s *Server) getReservations(w http.ResponseWriter, r *http.Request, logger *zerolog.Logger) {
+	ctx := r.Context()
+
+	customerName, ok := customerOf(w, r)
+	if !ok {
+		return
+	}
+
+	logger.Info().Msgf("Processing reservation lookup: customer_name=%s", customerName)
+
+	resResp, err := s.reservationClient.GetReservationsByCustomer(ctx, &reservation.CustomerRequest{
+		CustomerName: customerName,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Reservation lookup failed")
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
+		return
+	}
+
+	logger.Info().Msgf("Reservation lookup completed: customer_name=%s, reservations=%d", customerName, len(resResp.Bookings))
+
+	res := map[string]interface{}{
+		"message":      "Have reservations = " + strconv.Itoa(len(resResp.Bookings)),
+		"reservations": resResp.Bookings,
+	}
 
 	json.NewEncoder(w).Encode(res)
 }
 
+// modifyReservation changes the dates or the number of rooms of a reservation
+// of the user
+func (s *Server) modifyReservation(w http.ResponseWriter, r *http.Request, logger *zerolog.Logger) {
+	ctx := r.Context()
+
+	reservationId := r.URL.Query().Get("reservationId")
+	if reservationId == "" {
+		http.Error(w, "Please specify reservationId params", http.StatusBadRequest)
+		return
+	}
+
+	inDate, outDate := r.URL.Query().Get("inDate"), r.URL.Query().Get("outDate")
+	if (inDate != "" && !checkDataFormat(inDate)) || (outDate != "" && !checkDataFormat(outDate)) {
+		http.Error(w, "Please check inDate/outDate format (YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	numberOfRoom := 0
+	num := r.URL.Query().Get("number")
+	if num != "" {
+		numberOfRoom, _ = strconv.Atoi(num)
+	}
+
+	logger.Info().Msgf("Processing reservation change: reservation_id=%s, in_date=%s, out_date=%s, room_number=%d", reservationId, inDate, outDate, numberOfRoom)
+
+	if !s.checkOwner(w, r, reservationId) {
+		return
+	}
+
+	booking, err := s.reservationClient.ModifyReservation(ctx, &reservation.ModifyRequest{
+		ReservationId: reservationId,
+		InDate:        inDate,
+		OutDate:       outDate,
+		RoomNumber:    int32(numberOfRoom),
+	})
+	if err != nil {
+		logger.Warn().Err(err).Msgf("Reservation change failed: reservation_id=%s", reservationId)
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
+		return
+	}
+
+	logger.Info().Msgf("Reservation change completed: reservation_id=%s", reservationId)
+
+	res := map[string]interface{}{
+		"message":     "Modify successfully!",
+		"reservation": booking,
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
+
+// cancelReservation cancels a reservation of the user
+func (s *Server) cancelReservation(w http.ResponseWriter, r *http.Request, logger *zerolog.Logger) {
+	ctx := r.Context()
+
+	reservationId := r.URL.Query().Get("reservationId")
+	if reservationId == "" {
+		http.Error(w, "Please specify reservationId params", http.StatusBadRequest)
+		return
+	}
+
+	logger.Info().Msgf("Processing reservation cancellation: reservation_id=%s", reservationId)
+
+	if !s.checkOwner(w, r, reservationId) {
+		return
+	}
+
+	booking, err := s.reservationClient.CancelReservation(ctx, &reservation.CancelRequest{
+		ReservationId: reservationId,
+	})
+	if err != nil {
+		logger.Warn().Err(err).Msgf("Reservation cancellation failed: reservation_id=%s", reservationId)
+		http.Error(w, err.Error(), grpcToHTTPStatus(err))
+		return
+	}
+
+	logger.Info().Msgf("Reservation cancellation completed: reservation_id=%s, hotel_id=%s", reservationId, booking.HotelId)
+
+	res := map[string]interface{}{
+		"message":     "Cancel successfully!",
+		"reservation": booking,
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
+
+// grpcToHTTPStatus maps the status code of a failed rpc to an HTTP status
+func grpcToHTTPStatus(err error) int {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.ResourceExhausted, codes.AlreadyExists:
+		return http.StatusConflict
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // return a geoJSON response that allows google map to plot points directly on map
 // https://developers.google.com/maps/documentation/javascript/datalayer#sample_geojson
 func geoJSONResponse(hs []*profile.Hotel) map[string]interface{} {
@@ -779,6 +1388,7 @@ func geoJSONResponse(hs []*profile.Hotel) map[string]interface{} {
 			"properties": map[string]string{
 				"name":         h.Name,
 				"phone_number": h.PhoneNumber,
+				"description":  h.Description,
 			},
 			"geometry": map[string]interface{}{
 				"type": "Point",
@@ -796,6 +1406,17 @@ func geoJSONResponse(hs []*profile.Hotel) map[string]interface{} {
 	}
 }
 
+// hotelPrices returns the prices of the given hotels by hotel id
+func hotelPrices(hs []*profile.Hotel, prices map[string]*search.Price) map[string]*search.Price {
+	res := make(map[string]*search.Price, len(hs))
+	for _, h := range hs {
+		if p, ok := prices[h.Id]; ok {
+			res[h.Id] = p
+		}
+	}
+	return res
+}
+
 func checkDataFormat(date string) bool {
 	if len(date) != 10 {
 		return false
@@ -21,13 +21,21 @@ const (
 )
 
 // The latitude and longitude of the current location.
+// Zero radius (km) and limit fall back to the server defaults, negative
+// values and ones above 100 are rejected.
 type Request struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Lat float32 `protobuf:"fixed32,1,opt,name=lat,proto3" json:"lat,omitempty"`
-	Lon float32 `protobuf:"fixed32,2,opt,name=lon,proto3" json:"lon,omitempty"`
+	Lat    float32 `protobuf:"fixed32,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lon    float32 `protobuf:"fixed32,2,opt,name=lon,proto3" json:"lon,omitempty"`
+	Radius float32 `protobuf:"fixed32,3,opt,name=radius,proto3" json:"radius,omitempty"`
+	Limit  int32   `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	// hotels that must not be returned
+	ExcludeHotelIds []string `protobuf:"bytes,5,rep,name=excludeHotelIds,proto3" json:"excludeHotelIds,omitempty"`
+	// minimum hotel rating, as known by the recommendation service
+	MinRating float64 `protobuf:"fixed64,6,opt,name=minRating,proto3" json:"minRating,omitempty"`
 }
 
 func (x *Request) Reset() {
@@ -76,6 +84,34 @@ func (x *Request) GetLon() float32 {
 	return 0
 }
 
+func (x *Request) GetRadius() float32 {
+	if x != nil {
+		return x.Radius
+	}
+	return 0
+}
+
+func (x *Request) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *Request) GetExcludeHotelIds() []string {
+	if x != nil {
+		return x.ExcludeHotelIds
+	}
+	return nil
+}
+
+func (x *Request) GetMinRating() float64 {
+	if x != nil {
+		return x.MinRating
+	}
+	return 0
+}
+
 type Result struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -123,25 +159,156 @@ func (x *Result) GetHotelIds() []string {
 	return nil
 }
 
+type HotelLocation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	HotelId string  `protobuf:"bytes,1,opt,name=hotelId,proto3" json:"hotelId,omitempty"`
+	Lat     float64 `protobuf:"fixed64,2,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lon     float64 `protobuf:"fixed64,3,opt,name=lon,proto3" json:"lon,omitempty"`
+}
+
+func (x *HotelLocation) Reset() {
+	*x = HotelLocation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_geo_proto_geo_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HotelLocation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HotelLocation) ProtoMessage() {}
+
+func (x *HotelLocation) ProtoReflect() protoreflect.Message {
+	mi := &file_services_geo_proto_geo_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HotelLocation.ProtoReflect.Descriptor instead.
+func (*HotelLocation) Descriptor() ([]byte, []int) {
+	return file_services_geo_proto_geo_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *HotelLocation) GetHotelId() string {
+	if x != nil {
+		return x.HotelId
+	}
+	return ""
+}
+
+func (x *HotelLocation) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *HotelLocation) GetLon() float64 {
+	if x != nil {
+		return x.Lon
+	}
+	return 0
+}
+
+type RemoveRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	HotelId string `protobuf:"bytes,1,opt,name=hotelId,proto3" json:"hotelId,omitempty"`
+}
+
+func (x *RemoveRequest) Reset() {
+	*x = RemoveRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_geo_proto_geo_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RemoveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveRequest) ProtoMessage() {}
+
+func (x *RemoveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_services_geo_proto_geo_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveRequest.ProtoReflect.Descriptor instead.
+func (*RemoveRequest) Descriptor() ([]byte, []int) {
+	return file_services_geo_proto_geo_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RemoveRequest) GetHotelId() string {
+	if x != nil {
+		return x.HotelId
+	}
+	return ""
+}
+
 var File_services_geo_proto_geo_proto protoreflect.FileDescriptor
 
 var file_services_geo_proto_geo_proto_rawDesc = []byte{
 	0x0a, 0x1c, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x2f, 0x67, 0x65, 0x6f, 0x2f, 0x70,
 	0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x67, 0x65, 0x6f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x03,
-	0x67, 0x65, 0x6f, 0x22, 0x2d, 0x0a, 0x07, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10,
-	0x0a, 0x03, 0x6c, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x02, 0x52, 0x03, 0x6c, 0x61, 0x74,
-	0x12, 0x10, 0x0a, 0x03, 0x6c, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x02, 0x52, 0x03, 0x6c,
-	0x6f, 0x6e, 0x22, 0x24, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x1a, 0x0a, 0x08,
-	0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08,
-	0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x73, 0x32, 0x2a, 0x0a, 0x03, 0x47, 0x65, 0x6f, 0x12,
-	0x23, 0x0a, 0x06, 0x4e, 0x65, 0x61, 0x72, 0x62, 0x79, 0x12, 0x0c, 0x2e, 0x67, 0x65, 0x6f, 0x2e,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e, 0x67, 0x65, 0x6f, 0x2e, 0x52, 0x65,
-	0x73, 0x75, 0x6c, 0x74, 0x42, 0x44, 0x5a, 0x42, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
-	0x6f, 0x6d, 0x2f, 0x64, 0x65, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x72, 0x6f, 0x75, 0x2f, 0x44, 0x65,
-	0x61, 0x74, 0x68, 0x53, 0x74, 0x61, 0x72, 0x42, 0x65, 0x6e, 0x63, 0x68, 0x2f, 0x68, 0x6f, 0x74,
-	0x65, 0x6c, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2f, 0x73, 0x65,
-	0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x2f, 0x67, 0x65, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	0x67, 0x65, 0x6f, 0x22, 0xa3, 0x01, 0x0a, 0x07, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x10, 0x0a, 0x03, 0x6c, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x02, 0x52, 0x03, 0x6c, 0x61,
+	0x74, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x02, 0x52, 0x03,
+	0x6c, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x61, 0x64, 0x69, 0x75, 0x73, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x02, 0x52, 0x06, 0x72, 0x61, 0x64, 0x69, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x6c,
+	0x69, 0x6d, 0x69, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69,
+	0x74, 0x12, 0x28, 0x0a, 0x0f, 0x65, 0x78, 0x63, 0x6c, 0x75, 0x64, 0x65, 0x48, 0x6f, 0x74, 0x65,
+	0x6c, 0x49, 0x64, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0f, 0x65, 0x78, 0x63, 0x6c,
+	0x75, 0x64, 0x65, 0x48, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x6d,
+	0x69, 0x6e, 0x52, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09,
+	0x6d, 0x69, 0x6e, 0x52, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x22, 0x24, 0x0a, 0x06, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x73, 0x22,
+	0x4d, 0x0a, 0x0d, 0x48, 0x6f, 0x74, 0x65, 0x6c, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x18, 0x0a, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x61,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x61, 0x74, 0x12, 0x10, 0x0a, 0x03,
+	0x6c, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x6f, 0x6e, 0x22, 0x29,
+	0x0a, 0x0d, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x18, 0x0a, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x32, 0x87, 0x01, 0x0a, 0x03, 0x47, 0x65,
+	0x6f, 0x12, 0x23, 0x0a, 0x06, 0x4e, 0x65, 0x61, 0x72, 0x62, 0x79, 0x12, 0x0c, 0x2e, 0x67, 0x65,
+	0x6f, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e, 0x67, 0x65, 0x6f, 0x2e,
+	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x2b, 0x0a, 0x08, 0x41, 0x64, 0x64, 0x48, 0x6f, 0x74,
+	0x65, 0x6c, 0x12, 0x12, 0x2e, 0x67, 0x65, 0x6f, 0x2e, 0x48, 0x6f, 0x74, 0x65, 0x6c, 0x4c, 0x6f,
+	0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x1a, 0x0b, 0x2e, 0x67, 0x65, 0x6f, 0x2e, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x12, 0x2e, 0x0a, 0x0b, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x48, 0x6f, 0x74,
+	0x65, 0x6c, 0x12, 0x12, 0x2e, 0x67, 0x65, 0x6f, 0x2e, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e, 0x67, 0x65, 0x6f, 0x2e, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x42, 0x50, 0x5a, 0x4e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x64, 0x65, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x72, 0x6f, 0x75, 0x2f, 0x44, 0x65, 0x61,
+	0x74, 0x68, 0x53, 0x74, 0x61, 0x72, 0x42, 0x65, 0x6e, 0x63, 0x68, 0x2f, 0x74, 0x72, 0x65, 0x65,
+	0x2f, 0x6d, 0x61, 0x73, 0x74, 0x65, 0x72, 0x2f, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x52, 0x65, 0x73,
+	0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x73, 0x2f, 0x67, 0x65, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -156,16 +323,22 @@ func file_services_geo_proto_geo_proto_rawDescGZIP() []byte {
 	return file_services_geo_proto_geo_proto_rawDescData
 }
 
-var file_services_geo_proto_geo_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_services_geo_proto_geo_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_services_geo_proto_geo_proto_goTypes = []interface{}{
-	(*Request)(nil), // 0: geo.Request
-	(*Result)(nil),  // 1: geo.Result
+	(*Request)(nil),       // 0: geo.Request
+	(*Result)(nil),        // 1: geo.Result
+	(*HotelLocation)(nil), // 2: geo.HotelLocation
+	(*RemoveRequest)(nil), // 3: geo.RemoveRequest
 }
 var file_services_geo_proto_geo_proto_depIdxs = []int32{
 	0, // 0: geo.Geo.Nearby:input_type -> geo.Request
-	1, // 1: geo.Geo.Nearby:output_type -> geo.Result
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
+	2, // 1: geo.Geo.AddHotel:input_type -> geo.HotelLocation
+	3, // 2: geo.Geo.RemoveHotel:input_type -> geo.RemoveRequest
+	1, // 3: geo.Geo.Nearby:output_type -> geo.Result
+	1, // 4: geo.Geo.AddHotel:output_type -> geo.Result
+	1, // 5: geo.Geo.RemoveHotel:output_type -> geo.Result
+	3, // [3:6] is the sub-list for method output_type
+	0, // [0:3] is the sub-list for method input_type
 	0, // [0:0] is the sub-list for extension type_name
 	0, // [0:0] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
@@ -201,6 +374,30 @@ func file_services_geo_proto_geo_proto_init() {
 				return nil
 			}
 		}
+		file_services_geo_proto_geo_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HotelLocation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_geo_proto_geo_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RemoveRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -208,7 +405,7 @@ func file_services_geo_proto_geo_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_services_geo_proto_geo_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,7 +19,9 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Geo_Nearby_FullMethodName = "/geo.Geo/Nearby"
+	Geo_Nearby_FullMethodName      = "/geo.Geo/Nearby"
+	Geo_AddHotel_FullMethodName    = "/geo.Geo/AddHotel"
+	Geo_RemoveHotel_FullMethodName = "/geo.Geo/RemoveHotel"
 )
 
 // GeoClient is the client API for Geo service.
@@ -28,6 +30,10 @@ const (
 type GeoClient interface {
 	// Finds the hotels contained nearby the current lat/lon.
 	Nearby(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
+	// Admin: adds a hotel to the index or moves an existing one.
+	AddHotel(ctx context.Context, in *HotelLocation, opts ...grpc.CallOption) (*Result, error)
+	// Admin: removes a hotel from the index.
+	RemoveHotel(ctx context.Context, in *RemoveRequest, opts ...grpc.CallOption) (*Result, error)
 }
 
 type geoClient struct {
@@ -47,12 +53,34 @@ func (c *geoClient) Nearby(ctx context.Context, in *Request, opts ...grpc.CallOp
 	return out, nil
 }
 
+func (c *geoClient) AddHotel(ctx context.Context, in *HotelLocation, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, Geo_AddHotel_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoClient) RemoveHotel(ctx context.Context, in *RemoveRequest, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, Geo_RemoveHotel_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GeoServer is the server API for Geo service.
 // All implementations must embed UnimplementedGeoServer
 // for forward compatibility
 type GeoServer interface {
 	// Finds the hotels contained nearby the current lat/lon.
 	Nearby(context.Context, *Request) (*Result, error)
+	// Admin: adds a hotel to the index or moves an existing one.
+	AddHotel(context.Context, *HotelLocation) (*Result, error)
+	// Admin: removes a hotel from the index.
+	RemoveHotel(context.Context, *RemoveRequest) (*Result, error)
 	mustEmbedUnimplementedGeoServer()
 }
 
@@ -63,6 +91,12 @@ type UnimplementedGeoServer struct {
 func (UnimplementedGeoServer) Nearby(context.Context, *Request) (*Result, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Nearby not implemented")
 }
+func (UnimplementedGeoServer) AddHotel(context.Context, *HotelLocation) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddHotel not implemented")
+}
+func (UnimplementedGeoServer) RemoveHotel(context.Context, *RemoveRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveHotel not implemented")
+}
 func (UnimplementedGeoServer) mustEmbedUnimplementedGeoServer() {}
 
 // UnsafeGeoServer may be embedded to opt out of forward compatibility for this service.
@@ -94,6 +128,42 @@ func _Geo_Nearby_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Geo_AddHotel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HotelLocation)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoServer).AddHotel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Geo_AddHotel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoServer).AddHotel(ctx, req.(*HotelLocation))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Geo_RemoveHotel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoServer).RemoveHotel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Geo_RemoveHotel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoServer).RemoveHotel(ctx, req.(*RemoveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Geo_ServiceDesc is the grpc.ServiceDesc for Geo service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -105,6 +175,14 @@ var Geo_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Nearby",
 			Handler:    _Geo_Nearby_Handler,
 		},
+		{
+			MethodName: "AddHotel",
+			Handler:    _Geo_AddHotel_Handler,
+		},
+		{
+			MethodName: "RemoveHotel",
+			Handler:    _Geo_RemoveHotel_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "services/geo/proto/geo.proto",
@@ -3,11 +3,16 @@ package geo
 import (
 	"context"
 	"fmt"
+	"math"
 	"net"
 	"time"
 
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/dialer"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/liveindex"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
 	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/geo/proto"
+	recommendation "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/recommendation/proto"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	"github.com/google/uuid"
 	"github.com/hailocab/go-geoindex"
@@ -15,34 +20,50 @@ import (
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	name             = "srv-geo"
-	maxSearchRadius  = 10
-	maxSearchResults = 5
+	name                 = "srv-geo"
+	defaultSearchRadius  = 10
+	defaultSearchResults = 5
+	// upper bounds for client supplied search parameters, larger values
+	// are rejected like in the attractions service
+	maxSearchRadius  = 100
+	maxSearchResults = 100
 )
 
 // Server implements the geo service
 type Server struct {
 	pb.UnimplementedGeoServer
 
-	index *geoindex.ClusteringIndex
-	uuid  string
+	index                *liveindex.Index
+	recommendationClient recommendation.RecommendationClient
+	uuid                 string
+	grpcServer           *grpc.Server
+	listener             net.Listener
+	health               *health.Server
+	stopWatch            context.CancelFunc
 
-	Registry    *registry.Client
+	Registry    registry.Registry
 	Tracer      trace.Tracer
 	Port        int
 	IpAddr      string
 	MongoClient *mongo.Client
 }
 
-// Run starts the server
-func (s *Server) Run() error {
+// Setup creates the server, listens on Port and registers in consul. It is
+// called before Run, so Shutdown only reads what Setup wrote.
+func (s *Server) Setup() error {
 	if s.Port == 0 {
 		return fmt.Errorf("server port must be set")
 	}
@@ -51,6 +72,11 @@ func (s *Server) Run() error {
 		s.index = newGeoIndex(s.MongoClient)
 	}
 
+	// pick up hotels added or removed in geo-db while running
+	var watchCtx context.Context
+	watchCtx, s.stopWatch = context.WithCancel(context.Background())
+	go s.index.Watch(watchCtx, geoCollection(s.MongoClient), decodePoint, liveindex.DefaultPollInterval)
+
 	s.uuid = uuid.New().String()
 
 	opts := []grpc.ServerOption{
@@ -61,18 +87,30 @@ func (s *Server) Run() error {
 			PermitWithoutStream: true,
 		}),
 		grpc.UnaryInterceptor(
-			otelgrpc.UnaryServerInterceptor(),
+			otelgrpc.UnaryServerInterceptor(
+				otelgrpc.WithInterceptorFilter(filters.Not(filters.HealthCheck())),
+			),
 		),
 	}
 
 	if tlsopt := tls.GetServerOpt(); tlsopt != nil {
-		opts = append(opts, tlsopt)
+		opts = append(opts, tlsopt, grpc.ChainUnaryInterceptor(tls.UnaryServerInterceptor()))
 	}
 
 	srv := grpc.NewServer(opts...)
+	s.grpcServer = srv
+
+	// answers the consul check, serving until Shutdown
+	s.health = health.NewServer()
+	healthpb.RegisterHealthServer(srv, s.health)
 
 	pb.RegisterGeoServer(srv, s)
 
+	// init grpc clients
+	if err := s.initRecommendationClient("srv-recommendation"); err != nil {
+		return err
+	}
+
 	// listener
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.Port))
 	if err != nil {
@@ -85,15 +123,53 @@ func (s *Server) Run() error {
 	}
 	log.Info().Msg("Successfully registered in consul")
 
-	return srv.Serve(lis)
+	s.listener = lis
+	return nil
 }
 
-// Shutdown cleans up any processes
-func (s *Server) Shutdown() {
-	s.Registry.Deregister(s.uuid)
+// Run serves the server set up by Setup until Shutdown
+func (s *Server) Run() error {
+	return s.grpcServer.Serve(s.listener)
 }
 
-// Nearby returns all hotels within a given distance.
+// Shutdown fails the health check and deregisters the service so no new
+// calls are routed here, then waits for pending calls until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+	if s.stopWatch != nil {
+		s.stopWatch()
+	}
+	err := s.Registry.Deregister(s.uuid)
+	shutdown.GRPC(ctx, s.grpcServer)
+	return err
+}
+
+func (s *Server) initRecommendationClient(name string) error {
+	conn, err := s.getGprcConn(name)
+	if err != nil {
+		return fmt.Errorf("dialer error: %v", err)
+	}
+	s.recommendationClient = recommendation.NewRecommendationClient(conn)
+	return nil
+}
+
+func (s *Server) getGprcConn(name string) (*grpc.ClientConn, error) {
+	target, err := s.Registry.Target(name)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.Dial(
+		target,
+		dialer.WithTracer(s.Tracer),
+		dialer.WithBalancer(""),
+		dialer.WithEnvPolicies(),
+	)
+}
+
+// Nearby returns the closest hotels within a given distance, optionally
+// skipping excluded hotels and hotels rated below req.MinRating.
 func (s *Server) Nearby(ctx context.Context, req *pb.Request) (*pb.Result, error) {
 	// Get logger with trace context
 	logger := zerolog.Ctx(ctx)
@@ -117,12 +193,54 @@ func (s *Server) Nearby(ctx context.Context, req *pb.Request) (*pb.Result, error
 		}
 	}
 	
-	logger.Info().Msgf("Searching nearby hotels: lat=%v, lon=%v", req.Lat, req.Lon)
+	logger.Info().Msgf("Searching nearby hotels: lat=%v, lon=%v, radius=%v, limit=%v, min_rating=%v",
+		req.Lat, req.Lon, req.Radius, req.Limit, req.MinRating)
 
-	var (
-		points = s.getNearbyPoints(ctx, float64(req.Lat), float64(req.Lon))
-		res    = &pb.Result{}
-	)
+	radius := float64(req.Radius)
+	if radius == 0 {
+		radius = defaultSearchRadius
+	}
+	if radius < 0 || radius > maxSearchRadius {
+		return nil, status.Errorf(codes.InvalidArgument, "radius must be between 0 and %d km", maxSearchRadius)
+	}
+
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultSearchResults
+	}
+	if limit < 0 || limit > maxSearchResults {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 0 and %d", maxSearchResults)
+	}
+
+	excluded := make(map[string]bool, len(req.ExcludeHotelIds))
+	for _, id := range req.ExcludeHotelIds {
+		excluded[id] = true
+	}
+
+	// The rating filter can only be applied after the lookup, so every
+	// candidate within the radius is fetched and trimmed afterwards.
+	k := limit
+	if req.MinRating > 0 {
+		k = math.MaxInt32
+	}
+
+	points := s.getNearbyPoints(ctx, float64(req.Lat), float64(req.Lon), radius, k, func(p geoindex.Point) bool {
+		return !excluded[p.Id()]
+	})
+
+	if req.MinRating > 0 {
+		var err error
+		points, err = s.filterByRating(ctx, points, req.MinRating)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to get ratings from recommendation service")
+			return nil, err
+		}
+		if len(points) > limit {
+			points = points[:limit]
+		}
+	}
+
+	res := &pb.Result{}
 
 	for _, p := range points {
 		logger.Trace().Msgf("Found nearby hotel: hotel_id=%s", p.Id())
@@ -134,7 +252,35 @@ func (s *Server) Nearby(ctx context.Context, req *pb.Request) (*pb.Result, error
 	return res, nil
 }
 
-func (s *Server) getNearbyPoints(ctx context.Context, lat, lon float64) []geoindex.Point {
+// filterByRating keeps the points whose hotel is rated at least minRating,
+// preserving their order. Hotels without a known rating are dropped.
+func (s *Server) filterByRating(ctx context.Context, points []geoindex.Point, minRating float64) ([]geoindex.Point, error) {
+	if len(points) == 0 {
+		return points, nil
+	}
+
+	hotelIds := make([]string, 0, len(points))
+	for _, p := range points {
+		hotelIds = append(hotelIds, p.Id())
+	}
+
+	ratings, err := s.recommendationClient.GetRatings(ctx, &recommendation.RatingRequest{
+		HotelIds: hotelIds,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := points[:0]
+	for _, p := range points {
+		if rating, ok := ratings.Ratings[p.Id()]; ok && rating >= minRating {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
+}
+
+func (s *Server) getNearbyPoints(ctx context.Context, lat, lon, radius float64, k int, accept func(p geoindex.Point) bool) []geoindex.Point {
 	// Get logger with trace context
 	logger := zerolog.Ctx(ctx)
 	if logger.GetLevel() == zerolog.Disabled {
@@ -159,6 +305,8 @@ func (s *Server) getNearbyPoints(ctx context.Context, lat, lon float64) []geoind
 	logger.Debug().
 		Float64("lat", lat).
 		Float64("lon", lon).
+		Float64("radius", radius).
+		Int("k", k).
 		Msg("Getting nearby points from geo index")
 
 	center := &geoindex.GeoPoint{
@@ -167,35 +315,85 @@ func (s *Server) getNearbyPoints(ctx context.Context, lat, lon float64) []geoind
 		Plon: lon,
 	}
 
-	return s.index.KNearest(
-		center,
-		maxSearchResults,
-		geoindex.Km(maxSearchRadius), func(p geoindex.Point) bool {
-			return true
-		},
-	)
+	return s.index.KNearest(center, k, geoindex.Km(radius), accept)
 }
 
-// newGeoIndex returns a geo index with points loaded
-func newGeoIndex(client *mongo.Client) *geoindex.ClusteringIndex {
-	log.Trace().Msg("new geo newGeoIndex")
+// AddHotel stores a hotel location and makes it visible to Nearby right away.
+// Adding an existing hotel moves it.
+func (s *Server) AddHotel(ctx context.Context, req *pb.HotelLocation) (*pb.Result, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
 
-	collection := client.Database("geo-db").Collection("geo")
-	curr, err := collection.Find(context.TODO(), bson.D{})
+	if req.HotelId == "" {
+		return nil, status.Error(codes.InvalidArgument, "hotelId must be set")
+	}
+	if req.Lat < -90 || req.Lat > 90 || req.Lon < -180 || req.Lon > 180 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid location %v,%v", req.Lat, req.Lon)
+	}
+
+	p := &point{Pid: req.HotelId, Plat: req.Lat, Plon: req.Lon}
+	_, err := geoCollection(s.MongoClient).UpdateOne(ctx,
+		bson.M{"hotelId": p.Pid},
+		bson.M{"$set": p},
+		options.Update().SetUpsert(true))
 	if err != nil {
-		log.Error().Msgf("Failed get geo data: ", err)
+		logger.Error().Msgf("Failed to store hotel %s location: %v", req.HotelId, err)
+		return nil, err
+	}
+	s.index.Add(p)
+
+	logger.Info().Msgf("Hotel added to geo index: hotel_id=%s, lat=%v, lon=%v", req.HotelId, req.Lat, req.Lon)
+	return &pb.Result{HotelIds: []string{req.HotelId}}, nil
+}
+
+// RemoveHotel deletes a hotel location so Nearby no longer returns it.
+func (s *Server) RemoveHotel(ctx context.Context, req *pb.RemoveRequest) (*pb.Result, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	if req.HotelId == "" {
+		return nil, status.Error(codes.InvalidArgument, "hotelId must be set")
 	}
 
-	var points []*point
-	curr.All(context.TODO(), &points)
+	deleted, err := geoCollection(s.MongoClient).DeleteMany(ctx, bson.M{"hotelId": req.HotelId})
 	if err != nil {
-		log.Error().Msgf("Failed get geo data: ", err)
+		logger.Error().Msgf("Failed to remove hotel %s location: %v", req.HotelId, err)
+		return nil, err
+	}
+	if deleted.DeletedCount == 0 {
+		return nil, status.Errorf(codes.NotFound, "hotel %s not found", req.HotelId)
+	}
+	s.index.Remove(req.HotelId)
+
+	logger.Info().Msgf("Hotel removed from geo index: hotel_id=%s", req.HotelId)
+	return &pb.Result{HotelIds: []string{req.HotelId}}, nil
+}
+
+func geoCollection(client *mongo.Client) *mongo.Collection {
+	return client.Database("geo-db").Collection("geo")
+}
+
+func decodePoint(raw bson.Raw) (geoindex.Point, error) {
+	p := new(point)
+	if err := bson.Unmarshal(raw, p); err != nil {
+		return nil, err
 	}
+	return p, nil
+}
 
-	// add points to index
-	index := geoindex.NewClusteringIndex()
-	for _, point := range points {
-		index.Add(point)
+// newGeoIndex returns a geo index with points loaded
+func newGeoIndex(client *mongo.Client) *liveindex.Index {
+	log.Trace().Msg("new geo newGeoIndex")
+
+	index, err := liveindex.Load(context.TODO(), geoCollection(client), decodePoint)
+	if err != nil {
+		log.Error().Msgf("Failed get geo data: %v", err)
 	}
 
 	return index
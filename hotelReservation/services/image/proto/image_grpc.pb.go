@@ -0,0 +1,228 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.0
+// source: services/image/proto/image.proto
+
+package image
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Image_Upload_FullMethodName    = "/image.Image/Upload"
+	Image_Fetch_FullMethodName     = "/image.Image/Fetch"
+	Image_Thumbnail_FullMethodName = "/image.Image/Thumbnail"
+	Image_List_FullMethodName      = "/image.Image/List"
+)
+
+// ImageClient is the client API for Image service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ImageClient interface {
+	// Upload stores an image of a hotel or review
+	Upload(ctx context.Context, in *UploadRequest, opts ...grpc.CallOption) (*ImageInfo, error)
+	// Fetch returns the original image
+	Fetch(ctx context.Context, in *FetchRequest, opts ...grpc.CallOption) (*ImageData, error)
+	// Thumbnail returns a downscaled copy of an image, created on first use
+	Thumbnail(ctx context.Context, in *ThumbnailRequest, opts ...grpc.CallOption) (*ImageData, error)
+	// List returns the images of hotels or reviews
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResult, error)
+}
+
+type imageClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewImageClient(cc grpc.ClientConnInterface) ImageClient {
+	return &imageClient{cc}
+}
+
+func (c *imageClient) Upload(ctx context.Context, in *UploadRequest, opts ...grpc.CallOption) (*ImageInfo, error) {
+	out := new(ImageInfo)
+	err := c.cc.Invoke(ctx, Image_Upload_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageClient) Fetch(ctx context.Context, in *FetchRequest, opts ...grpc.CallOption) (*ImageData, error) {
+	out := new(ImageData)
+	err := c.cc.Invoke(ctx, Image_Fetch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageClient) Thumbnail(ctx context.Context, in *ThumbnailRequest, opts ...grpc.CallOption) (*ImageData, error) {
+	out := new(ImageData)
+	err := c.cc.Invoke(ctx, Image_Thumbnail_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResult, error) {
+	out := new(ListResult)
+	err := c.cc.Invoke(ctx, Image_List_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ImageServer is the server API for Image service.
+// All implementations must embed UnimplementedImageServer
+// for forward compatibility
+type ImageServer interface {
+	// Upload stores an image of a hotel or review
+	Upload(context.Context, *UploadRequest) (*ImageInfo, error)
+	// Fetch returns the original image
+	Fetch(context.Context, *FetchRequest) (*ImageData, error)
+	// Thumbnail returns a downscaled copy of an image, created on first use
+	Thumbnail(context.Context, *ThumbnailRequest) (*ImageData, error)
+	// List returns the images of hotels or reviews
+	List(context.Context, *ListRequest) (*ListResult, error)
+	mustEmbedUnimplementedImageServer()
+}
+
+// UnimplementedImageServer must be embedded to have forward compatible implementations.
+type UnimplementedImageServer struct {
+}
+
+func (UnimplementedImageServer) Upload(context.Context, *UploadRequest) (*ImageInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Upload not implemented")
+}
+func (UnimplementedImageServer) Fetch(context.Context, *FetchRequest) (*ImageData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Fetch not implemented")
+}
+func (UnimplementedImageServer) Thumbnail(context.Context, *ThumbnailRequest) (*ImageData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Thumbnail not implemented")
+}
+func (UnimplementedImageServer) List(context.Context, *ListRequest) (*ListResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedImageServer) mustEmbedUnimplementedImageServer() {}
+
+// UnsafeImageServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ImageServer will
+// result in compilation errors.
+type UnsafeImageServer interface {
+	mustEmbedUnimplementedImageServer()
+}
+
+func RegisterImageServer(s grpc.ServiceRegistrar, srv ImageServer) {
+	s.RegisterService(&Image_ServiceDesc, srv)
+}
+
+func _Image_Upload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServer).Upload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Image_Upload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServer).Upload(ctx, req.(*UploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Image_Fetch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FetchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServer).Fetch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Image_Fetch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServer).Fetch(ctx, req.(*FetchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Image_Thumbnail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ThumbnailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServer).Thumbnail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Image_Thumbnail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServer).Thumbnail(ctx, req.(*ThumbnailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Image_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Image_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Image_ServiceDesc is the grpc.ServiceDesc for Image service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Image_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "image.Image",
+	HandlerType: (*ImageServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Upload",
+			Handler:    _Image_Upload_Handler,
+		},
+		{
+			MethodName: "Fetch",
+			Handler:    _Image_Fetch_Handler,
+		},
+		{
+			MethodName: "Thumbnail",
+			Handler:    _Image_Thumbnail_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _Image_List_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "services/image/proto/image.proto",
+}
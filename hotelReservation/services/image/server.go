@@ -0,0 +1,383 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
+	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/image/proto"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	name = "srv-image"
+	// MaxImageBytes keeps uploads below the 4MB default gRPC message size
+	MaxImageBytes = 3 << 20
+	// MaxImagePixels bounds width × height, thumbnails decode the whole
+	// image into memory at 4 bytes per pixel
+	MaxImagePixels = 4096 * 4096
+	// thumbnail sizes are the longest side in pixels
+	defaultThumbnailSize = 128
+	minThumbnailSize     = 16
+	maxThumbnailSize     = 512
+)
+
+// Server implements the image service
+type Server struct {
+	pb.UnimplementedImageServer
+
+	uuid       string
+	grpcServer *grpc.Server
+	listener   net.Listener
+	health     *health.Server
+
+	Tracer      trace.Tracer
+	Port        int
+	IpAddr      string
+	MongoClient *mongo.Client
+	Registry    registry.Registry
+}
+
+// Setup creates the server, listens on Port and registers in consul. It is
+// called before Run, so Shutdown only reads what Setup wrote.
+func (s *Server) Setup() error {
+	if s.Port == 0 {
+		return fmt.Errorf("server port must be set")
+	}
+
+	s.uuid = uuid.New().String()
+
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Timeout: 120 * time.Second,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			PermitWithoutStream: true,
+		}),
+		grpc.UnaryInterceptor(
+			otelgrpc.UnaryServerInterceptor(
+				otelgrpc.WithInterceptorFilter(filters.Not(filters.HealthCheck())),
+			),
+		),
+	}
+
+	if tlsopt := tls.GetServerOpt(); tlsopt != nil {
+		opts = append(opts, tlsopt, grpc.ChainUnaryInterceptor(tls.UnaryServerInterceptor()))
+	}
+
+	srv := grpc.NewServer(opts...)
+	s.grpcServer = srv
+
+	// answers the consul check, serving until Shutdown
+	s.health = health.NewServer()
+	healthpb.RegisterHealthServer(srv, s.health)
+
+	pb.RegisterImageServer(srv, s)
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
+	}
+
+	err = s.Registry.Register(name, s.uuid, s.IpAddr, s.Port)
+	if err != nil {
+		return fmt.Errorf("failed register: %v", err)
+	}
+	log.Info().Msg("Successfully registered in consul")
+
+	s.listener = lis
+	return nil
+}
+
+// Run serves the server set up by Setup until Shutdown
+func (s *Server) Run() error {
+	return s.grpcServer.Serve(s.listener)
+}
+
+// Shutdown fails the health check and deregisters the service so no new
+// calls are routed here, then waits for pending calls until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+	err := s.Registry.Deregister(s.uuid)
+	shutdown.GRPC(ctx, s.grpcServer)
+	return err
+}
+
+// ImageMeta is the stored description of an image; its bytes live in the
+// GridFS bucket under the image id
+type ImageMeta struct {
+	ImageId     string `bson:"imageId"`
+	Owner       string `bson:"owner"`
+	OwnerId     string `bson:"ownerId"`
+	ContentType string `bson:"contentType"`
+	Width       int32  `bson:"width"`
+	Height      int32  `bson:"height"`
+	Default     bool   `bson:"default"`
+	CreatedAt   int64  `bson:"createdAt"`
+}
+
+func (m *ImageMeta) toImageInfo() *pb.ImageInfo {
+	return &pb.ImageInfo{
+		Id:           m.ImageId,
+		Owner:        pb.Owner(pb.Owner_value[strings.ToUpper(m.Owner)]),
+		OwnerId:      m.OwnerId,
+		Url:          "/images/" + m.ImageId,
+		ThumbnailUrl: "/images/" + m.ImageId + "/thumbnail",
+		ContentType:  m.ContentType,
+		Width:        m.Width,
+		Height:       m.Height,
+		Default:      m.Default,
+	}
+}
+
+// Upload validates and stores an image. A new default image of an owner
+// replaces its previous default.
+func (s *Server) Upload(ctx context.Context, req *pb.UploadRequest) (*pb.ImageInfo, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	if req.OwnerId == "" {
+		return nil, status.Error(codes.InvalidArgument, "ownerId is required")
+	}
+	if len(req.Data) == 0 || len(req.Data) > MaxImageBytes {
+		return nil, status.Errorf(codes.InvalidArgument, "image must have 1 to %d bytes", MaxImageBytes)
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(req.Data))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported image: %v", err)
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return nil, status.Errorf(codes.InvalidArgument, "image must have at most %d pixels, got %dx%d", MaxImagePixels, config.Width, config.Height)
+	}
+
+	meta := &ImageMeta{
+		ImageId:     uuid.New().String(),
+		Owner:       strings.ToLower(req.Owner.String()),
+		OwnerId:     req.OwnerId,
+		ContentType: http.DetectContentType(req.Data),
+		Width:       int32(config.Width),
+		Height:      int32(config.Height),
+		Default:     req.Default,
+		CreatedAt:   time.Now().Unix(),
+	}
+
+	if err := s.store(ctx, meta.ImageId, req.Data); err != nil {
+		logger.Error().Msgf("Failed to store image: owner=%s, owner_id=%s, error=%v", meta.Owner, meta.OwnerId, err)
+		return nil, err
+	}
+
+	_, mongoSpan := s.Tracer.Start(ctx, "mongo_image_insert")
+	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	collection := s.MongoClient.Database("image-db").Collection("images")
+	if meta.Default {
+		_, err = collection.UpdateMany(ctx,
+			bson.M{"owner": meta.Owner, "ownerId": meta.OwnerId, "default": true},
+			bson.M{"$set": bson.M{"default": false}})
+	}
+	if err == nil {
+		_, err = collection.InsertOne(ctx, meta)
+	}
+	mongoSpan.End()
+	if err != nil {
+		logger.Error().Msgf("Failed to store image metadata: image_id=%s, error=%v", meta.ImageId, err)
+		return nil, err
+	}
+
+	logger.Info().Msgf("Image uploaded: image_id=%s, owner=%s, owner_id=%s, format=%s, size=%d", meta.ImageId, meta.Owner, meta.OwnerId, format, len(req.Data))
+	return meta.toImageInfo(), nil
+}
+
+// Fetch returns the original bytes of an image
+func (s *Server) Fetch(ctx context.Context, req *pb.FetchRequest) (*pb.ImageData, error) {
+	meta, err := s.findMeta(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.load(ctx, meta.ImageId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ImageData{Id: meta.ImageId, ContentType: meta.ContentType, Data: data}, nil
+}
+
+// Thumbnail returns an image scaled down to the requested size. Thumbnails
+// are stored next to the image the first time they are asked for.
+func (s *Server) Thumbnail(ctx context.Context, req *pb.ThumbnailRequest) (*pb.ImageData, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	size := int(req.Size)
+	if size == 0 {
+		size = defaultThumbnailSize
+	}
+	if size < minThumbnailSize || size > maxThumbnailSize {
+		return nil, status.Errorf(codes.InvalidArgument, "thumbnail size must be between %d and %d", minThumbnailSize, maxThumbnailSize)
+	}
+
+	meta, err := s.findMeta(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	thumbName := fmt.Sprintf("%s_%d", meta.ImageId, size)
+	data, err := s.load(ctx, thumbName)
+	if err == nil {
+		return &pb.ImageData{Id: meta.ImageId, ContentType: thumbnailType(meta.ContentType), Data: data}, nil
+	} else if status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+
+	original, err := s.load(ctx, meta.ImageId)
+	if err != nil {
+		return nil, err
+	}
+	_, span := s.Tracer.Start(ctx, "image_thumbnail")
+	data, err = thumbnail(original, size, thumbnailType(meta.ContentType))
+	span.End()
+	if err != nil {
+		logger.Error().Msgf("Failed to create thumbnail: image_id=%s, error=%v", meta.ImageId, err)
+		return nil, status.Errorf(codes.Internal, "create thumbnail: %v", err)
+	}
+
+	// concurrent requests may store the same thumbnail twice, which is harmless
+	if err := s.store(ctx, thumbName, data); err != nil {
+		logger.Warn().Msgf("Failed to store thumbnail: image_id=%s, error=%v", meta.ImageId, err)
+	}
+
+	logger.Debug().Msgf("Thumbnail created: image_id=%s, size=%d, bytes=%d", meta.ImageId, size, len(data))
+	return &pb.ImageData{Id: meta.ImageId, ContentType: thumbnailType(meta.ContentType), Data: data}, nil
+}
+
+// List returns the images of the given owners, default image first
+func (s *Server) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResult, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	res := &pb.ListResult{Images: make(map[string]*pb.ImageInfos)}
+	if len(req.OwnerIds) == 0 {
+		return res, nil
+	}
+
+	_, mongoSpan := s.Tracer.Start(ctx, "mongo_image_list")
+	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	defer mongoSpan.End()
+
+	curr, err := s.MongoClient.Database("image-db").Collection("images").Find(ctx,
+		bson.M{"owner": strings.ToLower(req.Owner.String()), "ownerId": bson.M{"$in": req.OwnerIds}},
+		options.Find().SetSort(bson.D{{Key: "default", Value: -1}, {Key: "createdAt", Value: 1}}))
+	if err != nil {
+		logger.Error().Msgf("Failed to list images: owners=%d, error=%v", len(req.OwnerIds), err)
+		return nil, err
+	}
+	var metas []ImageMeta
+	if err := curr.All(ctx, &metas); err != nil {
+		logger.Error().Msgf("Failed to decode images: owners=%d, error=%v", len(req.OwnerIds), err)
+		return nil, err
+	}
+
+	for i := range metas {
+		infos, ok := res.Images[metas[i].OwnerId]
+		if !ok {
+			infos = &pb.ImageInfos{}
+			res.Images[metas[i].OwnerId] = infos
+		}
+		infos.Images = append(infos.Images, metas[i].toImageInfo())
+	}
+
+	logger.Debug().Msgf("Images listed: owners=%d, images=%d", len(req.OwnerIds), len(metas))
+	return res, nil
+}
+
+func (s *Server) findMeta(ctx context.Context, imageId string) (*ImageMeta, error) {
+	if imageId == "" {
+		return nil, status.Error(codes.InvalidArgument, "image id is required")
+	}
+
+	_, mongoSpan := s.Tracer.Start(ctx, "mongo_image_find")
+	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	defer mongoSpan.End()
+
+	var meta ImageMeta
+	err := s.MongoClient.Database("image-db").Collection("images").
+		FindOne(ctx, bson.M{"imageId": imageId}).Decode(&meta)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.NotFound, "image %s not found", imageId)
+	} else if err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+func (s *Server) bucket() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(s.MongoClient.Database("image-db"), options.GridFSBucket().SetName("images"))
+}
+
+// store writes data to the GridFS file fileName
+func (s *Server) store(ctx context.Context, fileName string, data []byte) error {
+	_, span := s.Tracer.Start(ctx, "mongo_gridfs_upload")
+	span.SetAttributes(attribute.String("span.kind", "client"), attribute.Int("image.bytes", len(data)))
+	defer span.End()
+
+	bucket, err := s.bucket()
+	if err != nil {
+		return err
+	}
+	_, err = bucket.UploadFromStream(fileName, bytes.NewReader(data))
+	return err
+}
+
+// load reads the newest GridFS file named fileName
+func (s *Server) load(ctx context.Context, fileName string) ([]byte, error) {
+	_, span := s.Tracer.Start(ctx, "mongo_gridfs_download")
+	span.SetAttributes(attribute.String("span.kind", "client"))
+	defer span.End()
+
+	bucket, err := s.bucket()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	_, err = bucket.DownloadToStreamByName(fileName, &buf)
+	if err == gridfs.ErrFileNotFound {
+		return nil, status.Errorf(codes.NotFound, "file %s not found", fileName)
+	} else if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("image.bytes", buf.Len()))
+	return buf.Bytes(), nil
+}
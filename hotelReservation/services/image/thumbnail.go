@@ -0,0 +1,97 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// thumbnailType is the format thumbnails of an image are encoded in: PNG
+// keeps transparency, everything else becomes JPEG
+func thumbnailType(contentType string) string {
+	if contentType == "image/png" {
+		return "image/png"
+	}
+	return "image/jpeg"
+}
+
+// thumbnail decodes an image and scales it down so its longest side is at
+// most size pixels. Every target pixel is the average of the source pixels it
+// covers. Images that are already small enough are only re-encoded.
+func thumbnail(data []byte, size int, contentType string) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return nil, fmt.Errorf("image of %dx%d pixels is too large to scale", config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, h*size/w
+		} else {
+			tw, th = w*size/h, size
+		}
+		if tw < 1 {
+			tw = 1
+		}
+		if th < 1 {
+			th = 1
+		}
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := y*h/th, (y+1)*h/th
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < tw; x++ {
+			x0, x1 := x*w/tw, (x+1)*w/tw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			off := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[off+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if contentType == "image/png" {
+		err = png.Encode(&buf, dst)
+	} else {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
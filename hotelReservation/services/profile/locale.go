@@ -0,0 +1,64 @@
+package profile
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/data"
+	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/profile/proto"
+)
+
+// defaultLocale is the language the stored descriptions are written in
+const defaultLocale = "en"
+
+type localeEntry struct {
+	HotelId     string `json:"hotelId"`
+	Locale      string `json:"locale"`
+	Description string `json:"description"`
+}
+
+// loadLocales returns the translated descriptions of data/locales.json by
+// hotel id and lower-case locale
+func loadLocales() (map[string]map[string]string, error) {
+	var entries []localeEntry
+	if err := json.Unmarshal(data.MustAsset("data/locales.json"), &entries); err != nil {
+		return nil, err
+	}
+
+	locales := make(map[string]map[string]string)
+	for _, e := range entries {
+		if locales[e.HotelId] == nil {
+			locales[e.HotelId] = make(map[string]string)
+		}
+		locales[e.HotelId][strings.ToLower(e.Locale)] = e.Description
+	}
+	return locales, nil
+}
+
+// localize replaces the descriptions of hotels with their translation for
+// locale. A regional locale like "fr-CA" falls back to its language; hotels
+// without a translation keep the stored description.
+func (s *Server) localize(hotels []*pb.Hotel, locale string) int {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	language, _, _ := strings.Cut(locale, "-")
+	if language == "" || locale == defaultLocale {
+		return 0
+	}
+
+	translated := 0
+	for _, h := range hotels {
+		if h == nil {
+			continue
+		}
+		descriptions := s.locales[h.Id]
+		if d, ok := descriptions[locale]; ok {
+			h.Description = d
+		} else if d, ok := descriptions[language]; ok {
+			h.Description = d
+		} else {
+			continue
+		}
+		translated++
+	}
+	return translated
+}
@@ -19,7 +19,9 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Profile_GetProfiles_FullMethodName = "/profile.Profile/GetProfiles"
+	Profile_GetProfiles_FullMethodName     = "/profile.Profile/GetProfiles"
+	Profile_GetHotelsByCity_FullMethodName = "/profile.Profile/GetHotelsByCity"
+	Profile_AddProfile_FullMethodName      = "/profile.Profile/AddProfile"
 )
 
 // ProfileClient is the client API for Profile service.
@@ -27,6 +29,10 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ProfileClient interface {
 	GetProfiles(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
+	// GetHotelsByCity returns the ids of hotels matching a city, postal code or keyword
+	GetHotelsByCity(ctx context.Context, in *CityRequest, opts ...grpc.CallOption) (*CityResult, error)
+	// AddProfile creates a hotel profile; existing hotels are not replaced
+	AddProfile(ctx context.Context, in *Hotel, opts ...grpc.CallOption) (*Result, error)
 }
 
 type profileClient struct {
@@ -46,11 +52,33 @@ func (c *profileClient) GetProfiles(ctx context.Context, in *Request, opts ...gr
 	return out, nil
 }
 
+func (c *profileClient) GetHotelsByCity(ctx context.Context, in *CityRequest, opts ...grpc.CallOption) (*CityResult, error) {
+	out := new(CityResult)
+	err := c.cc.Invoke(ctx, Profile_GetHotelsByCity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileClient) AddProfile(ctx context.Context, in *Hotel, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, Profile_AddProfile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ProfileServer is the server API for Profile service.
 // All implementations must embed UnimplementedProfileServer
 // for forward compatibility
 type ProfileServer interface {
 	GetProfiles(context.Context, *Request) (*Result, error)
+	// GetHotelsByCity returns the ids of hotels matching a city, postal code or keyword
+	GetHotelsByCity(context.Context, *CityRequest) (*CityResult, error)
+	// AddProfile creates a hotel profile; existing hotels are not replaced
+	AddProfile(context.Context, *Hotel) (*Result, error)
 	mustEmbedUnimplementedProfileServer()
 }
 
@@ -61,6 +89,12 @@ type UnimplementedProfileServer struct {
 func (UnimplementedProfileServer) GetProfiles(context.Context, *Request) (*Result, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetProfiles not implemented")
 }
+func (UnimplementedProfileServer) GetHotelsByCity(context.Context, *CityRequest) (*CityResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHotelsByCity not implemented")
+}
+func (UnimplementedProfileServer) AddProfile(context.Context, *Hotel) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddProfile not implemented")
+}
 func (UnimplementedProfileServer) mustEmbedUnimplementedProfileServer() {}
 
 // UnsafeProfileServer may be embedded to opt out of forward compatibility for this service.
@@ -92,6 +126,42 @@ func _Profile_GetProfiles_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Profile_GetHotelsByCity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServer).GetHotelsByCity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Profile_GetHotelsByCity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServer).GetHotelsByCity(ctx, req.(*CityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Profile_AddProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Hotel)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServer).AddProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Profile_AddProfile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServer).AddProfile(ctx, req.(*Hotel))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Profile_ServiceDesc is the grpc.ServiceDesc for Profile service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -103,6 +173,14 @@ var Profile_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetProfiles",
 			Handler:    _Profile_GetProfiles_Handler,
 		},
+		{
+			MethodName: "GetHotelsByCity",
+			Handler:    _Profile_GetHotelsByCity_Handler,
+		},
+		{
+			MethodName: "AddProfile",
+			Handler:    _Profile_AddProfile_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "services/profile/proto/profile.proto",
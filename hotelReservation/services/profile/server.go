@@ -5,23 +5,34 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"regexp"
 	"sync"
 	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/dialer"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
+	image "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/image/proto"
 	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/profile/proto"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 )
 
 const name = "srv-profile"
@@ -30,22 +41,41 @@ const name = "srv-profile"
 type Server struct {
 	pb.UnimplementedProfileServer
 
-	uuid string
+	uuid       string
+	grpcServer *grpc.Server
+	listener   net.Listener
+	health     *health.Server
+	// hotel id -> locale -> description
+	locales     map[string]map[string]string
+	imageClient image.ImageClient
 
 	Tracer      trace.Tracer
 	Port        int
 	IpAddr      string
 	MongoClient *mongo.Client
-	Registry    *registry.Client
+	Registry    registry.Registry
 	MemcClient  *memcache.Client
 }
 
-// Run starts the server
-func (s *Server) Run() error {
+// Setup creates the server, listens on Port and registers in consul. It is
+// called before Run, so Shutdown only reads what Setup wrote.
+func (s *Server) Setup() error {
 	if s.Port == 0 {
 		return fmt.Errorf("server port must be set")
 	}
 
+	if s.locales == nil {
+		locales, err := loadLocales()
+		if err != nil {
+			return fmt.Errorf("failed to load locales: %v", err)
+		}
+		s.locales = locales
+	}
+
+	if err := s.initImageClient("srv-image"); err != nil {
+		return err
+	}
+
 	s.uuid = uuid.New().String()
 
 	log.Trace().Msgf("in run s.IpAddr = %s, port = %d", s.IpAddr, s.Port)
@@ -58,15 +88,22 @@ func (s *Server) Run() error {
 			PermitWithoutStream: true,
 		}),
 		grpc.UnaryInterceptor(
-			otelgrpc.UnaryServerInterceptor(),
+			otelgrpc.UnaryServerInterceptor(
+				otelgrpc.WithInterceptorFilter(filters.Not(filters.HealthCheck())),
+			),
 		),
 	}
 
 	if tlsopt := tls.GetServerOpt(); tlsopt != nil {
-		opts = append(opts, tlsopt)
+		opts = append(opts, tlsopt, grpc.ChainUnaryInterceptor(tls.UnaryServerInterceptor()))
 	}
 
 	srv := grpc.NewServer(opts...)
+	s.grpcServer = srv
+
+	// answers the consul check, serving until Shutdown
+	s.health = health.NewServer()
+	healthpb.RegisterHealthServer(srv, s.health)
 
 	pb.RegisterProfileServer(srv, s)
 
@@ -81,12 +118,46 @@ func (s *Server) Run() error {
 	}
 	log.Info().Msg("Successfully registered in consul")
 
-	return srv.Serve(lis)
+	s.listener = lis
+	return nil
 }
 
-// Shutdown cleans up any processes
-func (s *Server) Shutdown() {
-	s.Registry.Deregister(s.uuid)
+// Run serves the server set up by Setup until Shutdown
+func (s *Server) Run() error {
+	return s.grpcServer.Serve(s.listener)
+}
+
+// Shutdown fails the health check and deregisters the service so no new
+// calls are routed here, then waits for pending calls until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+	err := s.Registry.Deregister(s.uuid)
+	shutdown.GRPC(ctx, s.grpcServer)
+	return err
+}
+
+func (s *Server) initImageClient(name string) error {
+	conn, err := s.getGprcConn(name)
+	if err != nil {
+		return fmt.Errorf("dialer error: %v", err)
+	}
+	s.imageClient = image.NewImageClient(conn)
+	return nil
+}
+
+func (s *Server) getGprcConn(name string) (*grpc.ClientConn, error) {
+	target, err := s.Registry.Target(name)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.Dial(
+		target,
+		dialer.WithTracer(s.Tracer),
+		dialer.WithBalancer(""),
+		dialer.WithEnvPolicies(),
+	)
 }
 
 // GetProfiles returns hotel profiles for requested IDs
@@ -112,7 +183,7 @@ func (s *Server) GetProfiles(ctx context.Context, req *pb.Request) (*pb.Result,
 		}
 	}
 	
-	logger.Info().Msgf("Getting hotel profiles: hotel_count=%d", len(req.HotelIds))
+	logger.Info().Msgf("Getting hotel profiles: hotel_count=%d, locale=%s", len(req.HotelIds), req.Locale)
 
 	var wg sync.WaitGroup
 	var mutex sync.Mutex
@@ -152,6 +223,7 @@ func (s *Server) GetProfiles(ctx context.Context, req *pb.Request) (*pb.Result,
 		wg.Add(len(profileMap))
 		for hotelId := range profileMap {
 			go func(hotelId string) {
+				defer wg.Done()
 				var hotelProf *pb.Hotel
 
 				collection := s.MongoClient.Database("profile-db").Collection("hotels")
@@ -162,7 +234,9 @@ func (s *Server) GetProfiles(ctx context.Context, req *pb.Request) (*pb.Result,
 				mongoSpan.End()
 
 				if err != nil {
-					logger.Error().Msgf("Failed get hotels data: ", err)
+					// unknown hotels are left out instead of returned or cached as null
+					logger.Error().Msgf("Failed get hotels data: %v", err)
+					return
 				}
 
 				mutex.Lock()
@@ -177,13 +251,186 @@ func (s *Server) GetProfiles(ctx context.Context, req *pb.Request) (*pb.Result,
 
 				// write to memcached
 				go s.MemcClient.Set(&memcache.Item{Key: hotelId, Value: []byte(memcStr)})
-				defer wg.Done()
 			}(hotelId)
 		}
 	}
 	wg.Wait()
 
+	// cached profiles hold the stored description, translations are applied
+	// to the copies returned here
+	translated := s.localize(hotels, req.Locale)
+
+	// uploaded images replace the stored ones; without the image service
+	// the stored images are returned
+	if err := s.attachImages(ctx, hotels); err != nil {
+		logger.Warn().Msgf("Failed to get hotel images: error=%v", err)
+	}
+
 	res.Hotels = hotels
-	logger.Info().Msgf("Get profiles completed: profiles_returned=%d", len(hotels))
+	logger.Info().Msgf("Get profiles completed: profiles_returned=%d, translated=%d", len(hotels), translated)
 	return res, nil
 }
+
+// attachImages sets the images of every hotel that has images in the image
+// service, default image first
+func (s *Server) attachImages(ctx context.Context, hotels []*pb.Hotel) error {
+	ids := make([]string, 0, len(hotels))
+	for _, h := range hotels {
+		if h != nil {
+			ids = append(ids, h.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	list, err := s.imageClient.List(ctx, &image.ListRequest{Owner: image.Owner_HOTEL, OwnerIds: ids})
+	if err != nil {
+		return err
+	}
+	for _, h := range hotels {
+		if h == nil || list.Images[h.Id] == nil {
+			continue
+		}
+		h.Images = h.Images[:0]
+		for _, img := range list.Images[h.Id].Images {
+			h.Images = append(h.Images, &pb.Image{Url: img.Url, Default: img.Default})
+		}
+	}
+	return nil
+}
+
+// GetHotelsByCity returns the ids of hotels matching a city, postal code or keyword
+func (s *Server) GetHotelsByCity(ctx context.Context, req *pb.CityRequest) (*pb.CityResult, error) {
+	// Get logger with trace context
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+	
+	// Extract trace information and add to logger
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		spanCtx := span.SpanContext()
+		if spanCtx.HasTraceID() {
+			newLogger := logger.With().Str("trace_id", spanCtx.TraceID().String()).Logger()
+			logger = &newLogger
+		}
+		if spanCtx.HasSpanID() {
+			newLogger := logger.With().Str("span_id", spanCtx.SpanID().String()).Logger()
+			logger = &newLogger
+		}
+	}
+	
+	logger.Info().Msgf("Searching hotels by city: city=%s, postal_code=%s, keyword=%s", req.City, req.PostalCode, req.Keyword)
+
+	filter := bson.M{}
+	if req.City != "" {
+		// case-insensitive match on the whole city name
+		filter["address.city"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(req.City) + "$", Options: "i"}
+	}
+	if req.PostalCode != "" {
+		filter["address.postalCode"] = req.PostalCode
+	}
+	if req.Keyword != "" {
+		keyword := primitive.Regex{Pattern: regexp.QuoteMeta(req.Keyword), Options: "i"}
+		filter["$or"] = bson.A{
+			bson.M{"name": keyword},
+			bson.M{"description": keyword},
+		}
+	}
+	if len(filter) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "one of city, postalCode or keyword is required")
+	}
+
+	collection := s.MongoClient.Database("profile-db").Collection("hotels")
+
+	_, mongoSpan := s.Tracer.Start(ctx, "mongo_profile_city")
+	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	curr, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"id": 1}))
+	if err != nil {
+		mongoSpan.End()
+		logger.Error().Msgf("Failed to search hotels by city: error=%v", err)
+		return nil, err
+	}
+	var hotels []struct {
+		Id string `bson:"id"`
+	}
+	err = curr.All(ctx, &hotels)
+	mongoSpan.End()
+	if err != nil {
+		logger.Error().Msgf("Failed to decode hotels: error=%v", err)
+		return nil, err
+	}
+
+	res := new(pb.CityResult)
+	for _, h := range hotels {
+		res.HotelIds = append(res.HotelIds, h.Id)
+	}
+
+	logger.Info().Msgf("Search hotels by city completed: results_count=%d", len(res.HotelIds))
+	return res, nil
+}
+
+// AddProfile creates a hotel profile and drops any cached copy. Existing
+// hotels are never replaced, so nobody can take over one by onboarding it
+// again.
+func (s *Server) AddProfile(ctx context.Context, req *pb.Hotel) (*pb.Result, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	if req.Id == "" || req.Name == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "id and name are required")
+	}
+
+	// same field names as the seeded documents, so lookups by address work
+	doc := bson.M{
+		"id":          req.Id,
+		"name":        req.Name,
+		"phoneNumber": req.PhoneNumber,
+		"description": req.Description,
+	}
+	if a := req.Address; a != nil {
+		doc["address"] = bson.M{
+			"streetNumber": a.StreetNumber,
+			"streetName":   a.StreetName,
+			"city":         a.City,
+			"state":        a.State,
+			"country":      a.Country,
+			"postalCode":   a.PostalCode,
+			"lat":          a.Lat,
+			"lon":          a.Lon,
+		}
+	}
+	images := bson.A{}
+	for _, img := range req.Images {
+		images = append(images, bson.M{"url": img.Url, "default": img.Default})
+	}
+	doc["images"] = images
+
+	collection := s.MongoClient.Database("profile-db").Collection("hotels")
+
+	_, mongoSpan := s.Tracer.Start(ctx, "mongo_profile_add")
+	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	res, err := collection.UpdateOne(ctx, bson.M{"id": req.Id}, bson.M{"$setOnInsert": doc}, options.Update().SetUpsert(true))
+	mongoSpan.End()
+	if err != nil {
+		logger.Error().Msgf("Failed to store profile: hotel_id=%s, error=%v", req.Id, err)
+		return nil, err
+	}
+	if res.UpsertedCount == 0 {
+		return nil, status.Errorf(codes.AlreadyExists, "hotel %s already exists", req.Id)
+	}
+
+	if err := s.MemcClient.Delete(req.Id); err != nil && err != memcache.ErrCacheMiss {
+		logger.Error().Msgf("Failed to invalidate cached profile: hotel_id=%s, error=%v", req.Id, err)
+		return nil, err
+	}
+
+	logger.Info().Msgf("Profile stored: hotel_id=%s", req.Id)
+	return &pb.Result{Hotels: []*pb.Hotel{req}}, nil
+}
@@ -0,0 +1,72 @@
+package rate
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate/proto"
+)
+
+// DefaultCurrency is the currency of rates stored without one
+const DefaultCurrency = "USD"
+
+// ParseCurrencies parses a conversion table of comma separated CODE:rate
+// pairs, each rate giving the units of CODE per unit of base. The base
+// currency is always part of the table.
+func ParseCurrencies(base, table string) (map[string]float64, error) {
+	currencies := map[string]float64{strings.ToUpper(base): 1}
+	if table == "" {
+		return currencies, nil
+	}
+	for _, field := range strings.Split(table, ",") {
+		code, sRate, found := strings.Cut(strings.TrimSpace(field), ":")
+		if !found || code == "" {
+			return nil, fmt.Errorf("malformed currency %q", field)
+		}
+		rate, err := strconv.ParseFloat(sRate, 64)
+		if err != nil || !(rate > 0) || math.IsInf(rate, 0) {
+			return nil, fmt.Errorf("malformed rate of currency %q", code)
+		}
+		currencies[strings.ToUpper(code)] = rate
+	}
+	return currencies, nil
+}
+
+// convert expresses the rates of a plan in the currency to. Plans stored
+// without a currency are in base; plans are left unconverted when either
+// currency is missing from the table.
+func convert(plan *pb.RatePlan, currencies map[string]float64, base, to string) {
+	if plan.RoomType == nil {
+		return
+	}
+	from := strings.ToUpper(plan.RoomType.Currency)
+	if from == "" {
+		from = base
+	}
+	plan.RoomType.Currency = from
+	if from == to {
+		return
+	}
+	fromRate, ok := currencies[from]
+	if !ok {
+		return
+	}
+	toRate, ok := currencies[to]
+	if !ok {
+		return
+	}
+	factor := toRate / fromRate
+
+	rt := plan.RoomType
+	rt.BookableRate = roundCents(rt.BookableRate * factor)
+	rt.TotalRate = roundCents(rt.TotalRate * factor)
+	rt.TotalRateInclusive = roundCents(rt.TotalRateInclusive * factor)
+	rt.Currency = to
+	for _, n := range plan.Nights {
+		n.BookableRate = roundCents(n.BookableRate * factor)
+		n.TotalRate = roundCents(n.TotalRate * factor)
+		n.TotalRateInclusive = roundCents(n.TotalRateInclusive * factor)
+	}
+}
@@ -0,0 +1,103 @@
+package rate
+
+import (
+	"testing"
+
+	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate/proto"
+)
+
+func TestParseCurrencies(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		table   string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{name: "base only", base: "USD", table: "", want: map[string]float64{"USD": 1}},
+		{name: "lower case codes", base: "usd", table: "eur:0.92", want: map[string]float64{"USD": 1, "EUR": 0.92}},
+		{
+			name:  "spaces around pairs",
+			base:  "USD",
+			table: "EUR:0.92, GBP:0.79 ,JPY:151.5",
+			want:  map[string]float64{"USD": 1, "EUR": 0.92, "GBP": 0.79, "JPY": 151.5},
+		},
+		{name: "base in the table", base: "USD", table: "USD:2", want: map[string]float64{"USD": 2}},
+		{name: "missing rate", base: "USD", table: "EUR", wantErr: true},
+		{name: "missing code", base: "USD", table: ":0.92", wantErr: true},
+		{name: "empty pair", base: "USD", table: "EUR:0.92,", wantErr: true},
+		{name: "rate not a number", base: "USD", table: "EUR:abc", wantErr: true},
+		{name: "zero rate", base: "USD", table: "EUR:0", wantErr: true},
+		{name: "negative rate", base: "USD", table: "EUR:-1", wantErr: true},
+		{name: "NaN rate", base: "USD", table: "EUR:NaN", wantErr: true},
+		{name: "infinite rate", base: "USD", table: "EUR:+Inf", wantErr: true},
+		{name: "semicolon separated", base: "USD", table: "EUR:0.92;GBP:0.79", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCurrencies(tt.base, tt.table)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for code, rate := range tt.want {
+				if got[code] != rate {
+					t.Errorf("%s: got %v, want %v", code, got[code], rate)
+				}
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	currencies := map[string]float64{"USD": 1, "EUR": 0.92, "JPY": 151.5}
+
+	priced := func(currency string, rate float64) *pb.RatePlan {
+		return &pb.RatePlan{
+			RoomType: &pb.RoomType{Currency: currency, BookableRate: rate, TotalRate: rate, TotalRateInclusive: rate},
+			Nights:   []*pb.NightRate{{BookableRate: rate, TotalRate: rate, TotalRateInclusive: rate}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		plan     *pb.RatePlan
+		to       string
+		currency string
+		rate     float64
+	}{
+		{name: "identity", plan: priced("USD", 109), to: "USD", currency: "USD", rate: 109},
+		{name: "no currency is the base", plan: priced("", 100), to: "EUR", currency: "EUR", rate: 92},
+		{name: "base to other", plan: priced("USD", 109), to: "EUR", currency: "EUR", rate: 100.28},
+		{name: "other to base", plan: priced("EUR", 92), to: "USD", currency: "USD", rate: 100},
+		{name: "between two others", plan: priced("EUR", 92), to: "JPY", currency: "JPY", rate: 15150},
+		{name: "lower case stored currency", plan: priced("eur", 92), to: "EUR", currency: "EUR", rate: 92},
+		{name: "unknown stored currency", plan: priced("CHF", 50), to: "USD", currency: "CHF", rate: 50},
+		{name: "unknown target currency", plan: priced("USD", 50), to: "CHF", currency: "USD", rate: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			convert(tt.plan, currencies, "USD", tt.to)
+			rt := tt.plan.RoomType
+			if rt.Currency != tt.currency {
+				t.Errorf("got currency %s, want %s", rt.Currency, tt.currency)
+			}
+			if rt.BookableRate != tt.rate || rt.TotalRate != tt.rate || rt.TotalRateInclusive != tt.rate {
+				t.Errorf("got rates %v/%v/%v, want %v", rt.BookableRate, rt.TotalRate, rt.TotalRateInclusive, tt.rate)
+			}
+			if n := tt.plan.Nights[0]; n.TotalRateInclusive != tt.rate {
+				t.Errorf("got night rate %v, want %v", n.TotalRateInclusive, tt.rate)
+			}
+		})
+	}
+
+	// plans without a room type are left alone
+	convert(&pb.RatePlan{}, currencies, "USD", "EUR")
+}
@@ -0,0 +1,91 @@
+package rate
+
+import (
+	"fmt"
+	"math"
+
+	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate/proto"
+	reservation "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/reservation/proto"
+)
+
+// Policy decides how the rate of a night follows the occupancy of the hotel
+type Policy interface {
+	// Factor returns the multiplier for the rate of a night on which the
+	// given share (0 to 1) of the rooms is booked
+	Factor(occupancy float64) float64
+}
+
+// PolicyFunc adapts a function to a Policy
+type PolicyFunc func(occupancy float64) float64
+
+// Factor calls f
+func (f PolicyFunc) Factor(occupancy float64) float64 {
+	return f(occupancy)
+}
+
+// Surge raises rates by Markup once more than Threshold of the rooms are booked
+type Surge struct {
+	Threshold float64
+	Markup    float64
+}
+
+// Factor implements Policy
+func (p Surge) Factor(occupancy float64) float64 {
+	if occupancy > p.Threshold {
+		return 1 + p.Markup
+	}
+	return 1
+}
+
+// Linear moves rates from Min times the stored rate for an empty hotel up to
+// Max times for a full one
+type Linear struct {
+	Min float64
+	Max float64
+}
+
+// Factor implements Policy
+func (p Linear) Factor(occupancy float64) float64 {
+	return p.Min + (p.Max-p.Min)*math.Min(math.Max(occupancy, 0), 1)
+}
+
+// policies are the policies selectable by name; "static" keeps the stored rates
+var policies = map[string]Policy{
+	"static": nil,
+	"surge":  Surge{Threshold: 0.8, Markup: 0.25},
+	"linear": Linear{Min: 0.9, Max: 1.3},
+}
+
+// PolicyByName returns a built-in policy; "static" and "" return nil
+func PolicyByName(name string) (Policy, error) {
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := policies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown pricing policy %q", name)
+	}
+	return p, nil
+}
+
+// applyPolicy scales the nights of a priced stay by the factor the policy
+// gives for their occupancy and recomputes the totals of the stay.
+func applyPolicy(p Policy, plan *pb.RatePlan, occ *reservation.Occupancy) {
+	if occ == nil || occ.Capacity <= 0 || len(plan.Nights) == 0 {
+		return
+	}
+	plan.RoomType.BookableRate, plan.RoomType.TotalRate, plan.RoomType.TotalRateInclusive = 0, 0, 0
+	for _, n := range plan.Nights {
+		factor := p.Factor(float64(occ.Booked[n.Date]) / float64(occ.Capacity))
+		n.BookableRate = roundCents(n.BookableRate * factor)
+		n.TotalRate = roundCents(n.TotalRate * factor)
+		n.TotalRateInclusive = roundCents(n.TotalRateInclusive * factor)
+		plan.RoomType.BookableRate += n.BookableRate
+		plan.RoomType.TotalRate += n.TotalRate
+		plan.RoomType.TotalRateInclusive += n.TotalRateInclusive
+	}
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
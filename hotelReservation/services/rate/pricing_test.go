@@ -0,0 +1,158 @@
+package rate
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate/proto"
+	reservation "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/reservation/proto"
+	"github.com/rs/zerolog"
+)
+
+func TestPolicyFactor(t *testing.T) {
+	surge := Surge{Threshold: 0.8, Markup: 0.25}
+	linear := Linear{Min: 0.9, Max: 1.3}
+
+	tests := []struct {
+		name      string
+		policy    Policy
+		occupancy float64
+		want      float64
+	}{
+		{"surge, empty", surge, 0, 1},
+		{"surge, at the threshold", surge, 0.8, 1},
+		{"surge, above the threshold", surge, 0.81, 1.25},
+		{"surge, full", surge, 1, 1.25},
+		{"surge, overbooked", surge, 1.2, 1.25},
+		{"linear, empty", linear, 0, 0.9},
+		{"linear, half", linear, 0.5, 1.1},
+		{"linear, full", linear, 1, 1.3},
+		{"linear, overbooked is capped", linear, 1.5, 1.3},
+		{"linear, negative is capped", linear, -0.5, 0.9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Factor(tt.occupancy); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got factor %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// stay returns a plan priced at the given rate for each night
+func stay(hotelId string, rates ...float64) *pb.RatePlan {
+	plan := &pb.RatePlan{HotelId: hotelId, RoomType: &pb.RoomType{}}
+	for i, r := range rates {
+		plan.Nights = append(plan.Nights, &pb.NightRate{
+			Date:               []string{"2015-04-09", "2015-04-10", "2015-04-11"}[i],
+			BookableRate:       r,
+			TotalRate:          r,
+			TotalRateInclusive: r,
+		})
+		plan.RoomType.BookableRate += r
+		plan.RoomType.TotalRate += r
+		plan.RoomType.TotalRateInclusive += r
+	}
+	return plan
+}
+
+func TestApplyPolicy(t *testing.T) {
+	surge := Surge{Threshold: 0.8, Markup: 0.25}
+	linear := Linear{Min: 0.9, Max: 1.3}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		plan   *pb.RatePlan
+		occ    *reservation.Occupancy
+		nights []float64
+		total  float64
+	}{
+		{
+			name:   "surge only on nights above the threshold",
+			policy: surge,
+			plan:   stay("1", 100, 100, 100),
+			occ:    &reservation.Occupancy{Capacity: 10, Booked: map[string]int32{"2015-04-09": 8, "2015-04-10": 9}},
+			nights: []float64{100, 125, 100},
+			total:  325,
+		},
+		{
+			name:   "linear per night",
+			policy: linear,
+			plan:   stay("1", 100, 100),
+			occ:    &reservation.Occupancy{Capacity: 4, Booked: map[string]int32{"2015-04-09": 2, "2015-04-10": 4}},
+			nights: []float64{110, 130},
+			total:  240,
+		},
+		{
+			name:   "nights are rounded to cents before the total",
+			policy: surge,
+			plan:   stay("1", 99.99, 99.99),
+			occ:    &reservation.Occupancy{Capacity: 1, Booked: map[string]int32{"2015-04-09": 1, "2015-04-10": 1}},
+			nights: []float64{124.99, 124.99},
+			total:  249.98,
+		},
+		{
+			name:   "rounding half a cent",
+			policy: linear,
+			plan:   stay("1", 10.05),
+			occ:    &reservation.Occupancy{Capacity: 2, Booked: map[string]int32{"2015-04-09": 1}},
+			nights: []float64{11.06},
+			total:  11.06,
+		},
+		{
+			name:   "zero capacity keeps the stored rates",
+			policy: surge,
+			plan:   stay("1", 100, 100),
+			occ:    &reservation.Occupancy{Capacity: 0, Booked: map[string]int32{"2015-04-09": 5}},
+			nights: []float64{100, 100},
+			total:  200,
+		},
+		{
+			name:   "unknown occupancy keeps the stored rates",
+			policy: linear,
+			plan:   stay("1", 100),
+			occ:    nil,
+			nights: []float64{100},
+			total:  100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyPolicy(tt.policy, tt.plan, tt.occ)
+			for i, n := range tt.plan.Nights {
+				if n.TotalRateInclusive != tt.nights[i] || n.BookableRate != tt.nights[i] || n.TotalRate != tt.nights[i] {
+					t.Errorf("night %d: got %v, want %v", i, n.TotalRateInclusive, tt.nights[i])
+				}
+			}
+			if math.Abs(tt.plan.RoomType.TotalRateInclusive-tt.total) > 1e-9 {
+				t.Errorf("got total %v, want %v", tt.plan.RoomType.TotalRateInclusive, tt.total)
+			}
+		})
+	}
+}
+
+func TestApplyPricingWithCallerOccupancy(t *testing.T) {
+	// no reservation client: the occupancy must come from the request
+	s := &Server{Pricing: Surge{Threshold: 0.8, Markup: 0.25}}
+	logger := zerolog.Nop()
+
+	plans := RatePlans{stay("1", 100, 100), stay("2", 100, 100)}
+	s.applyPricing(context.Background(), plans, &pb.Request{
+		InDate:  "2015-04-09",
+		OutDate: "2015-04-11",
+		Occupancy: map[string]*pb.Occupancy{
+			"1": {Capacity: 10, Booked: map[string]int32{"2015-04-10": 9}},
+		},
+	}, &logger)
+
+	if got := plans[0].RoomType.TotalRateInclusive; got != 225 {
+		t.Errorf("hotel 1: got total %v, want 225", got)
+	}
+	if got := plans[1].RoomType.TotalRateInclusive; got != 200 {
+		t.Errorf("hotel 2 without occupancy: got total %v, want the stored 200", got)
+	}
+}
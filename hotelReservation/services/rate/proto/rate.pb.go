@@ -20,6 +20,8 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// inDate and outDate (YYYY-MM-DD) select the stay; without them the stored
+// rate plans are returned unpriced.
 type Request struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -28,6 +30,12 @@ type Request struct {
 	HotelIds []string `protobuf:"bytes,1,rep,name=hotelIds,proto3" json:"hotelIds,omitempty"`
 	InDate   string   `protobuf:"bytes,2,opt,name=inDate,proto3" json:"inDate,omitempty"`
 	OutDate  string   `protobuf:"bytes,3,opt,name=outDate,proto3" json:"outDate,omitempty"`
+	// ISO 4217 code to convert rates to, the base currency if empty
+	Currency string `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	// occupancy of the stay by hotel id, as known by the caller. When set, the
+	// reservation service is not asked for it, so that service can price its
+	// own bookings without being called back.
+	Occupancy map[string]*Occupancy `protobuf:"bytes,5,rep,name=occupancy,proto3" json:"occupancy,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 }
 
 func (x *Request) Reset() {
@@ -83,6 +91,77 @@ func (x *Request) GetOutDate() string {
 	return ""
 }
 
+func (x *Request) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Request) GetOccupancy() map[string]*Occupancy {
+	if x != nil {
+		return x.Occupancy
+	}
+	return nil
+}
+
+// Hotels without a capacity keep their stored rates.
+type Occupancy struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Capacity int32 `protobuf:"varint,1,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	// rooms booked per night, keyed by the date the night starts
+	Booked map[string]int32 `protobuf:"bytes,2,rep,name=booked,proto3" json:"booked,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+}
+
+func (x *Occupancy) Reset() {
+	*x = Occupancy{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_rate_proto_rate_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Occupancy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Occupancy) ProtoMessage() {}
+
+func (x *Occupancy) ProtoReflect() protoreflect.Message {
+	mi := &file_services_rate_proto_rate_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Occupancy.ProtoReflect.Descriptor instead.
+func (*Occupancy) Descriptor() ([]byte, []int) {
+	return file_services_rate_proto_rate_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Occupancy) GetCapacity() int32 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *Occupancy) GetBooked() map[string]int32 {
+	if x != nil {
+		return x.Booked
+	}
+	return nil
+}
+
 type Result struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -94,7 +173,7 @@ type Result struct {
 func (x *Result) Reset() {
 	*x = Result{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_services_rate_proto_rate_proto_msgTypes[1]
+		mi := &file_services_rate_proto_rate_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -107,7 +186,7 @@ func (x *Result) String() string {
 func (*Result) ProtoMessage() {}
 
 func (x *Result) ProtoReflect() protoreflect.Message {
-	mi := &file_services_rate_proto_rate_proto_msgTypes[1]
+	mi := &file_services_rate_proto_rate_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -120,7 +199,7 @@ func (x *Result) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Result.ProtoReflect.Descriptor instead.
 func (*Result) Descriptor() ([]byte, []int) {
-	return file_services_rate_proto_rate_proto_rawDescGZIP(), []int{1}
+	return file_services_rate_proto_rate_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *Result) GetRatePlans() []*RatePlan {
@@ -130,22 +209,26 @@ func (x *Result) GetRatePlans() []*RatePlan {
 	return nil
 }
 
+// A stored rate plan applies to the nights from inDate up to outDate. Plans
+// returned for a stay span the stay, carry the rates summed over its nights
+// and the rate of each night.
 type RatePlan struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	HotelId  string    `protobuf:"bytes,1,opt,name=hotelId,proto3" json:"hotelId,omitempty"`
-	Code     string    `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
-	InDate   string    `protobuf:"bytes,3,opt,name=inDate,proto3" json:"inDate,omitempty"`
-	OutDate  string    `protobuf:"bytes,4,opt,name=outDate,proto3" json:"outDate,omitempty"`
-	RoomType *RoomType `protobuf:"bytes,5,opt,name=roomType,proto3" json:"roomType,omitempty"`
+	HotelId  string       `protobuf:"bytes,1,opt,name=hotelId,proto3" json:"hotelId,omitempty"`
+	Code     string       `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	InDate   string       `protobuf:"bytes,3,opt,name=inDate,proto3" json:"inDate,omitempty"`
+	OutDate  string       `protobuf:"bytes,4,opt,name=outDate,proto3" json:"outDate,omitempty"`
+	RoomType *RoomType    `protobuf:"bytes,5,opt,name=roomType,proto3" json:"roomType,omitempty"`
+	Nights   []*NightRate `protobuf:"bytes,6,rep,name=nights,proto3" json:"nights,omitempty"`
 }
 
 func (x *RatePlan) Reset() {
 	*x = RatePlan{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_services_rate_proto_rate_proto_msgTypes[2]
+		mi := &file_services_rate_proto_rate_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -158,7 +241,7 @@ func (x *RatePlan) String() string {
 func (*RatePlan) ProtoMessage() {}
 
 func (x *RatePlan) ProtoReflect() protoreflect.Message {
-	mi := &file_services_rate_proto_rate_proto_msgTypes[2]
+	mi := &file_services_rate_proto_rate_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -171,7 +254,7 @@ func (x *RatePlan) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RatePlan.ProtoReflect.Descriptor instead.
 func (*RatePlan) Descriptor() ([]byte, []int) {
-	return file_services_rate_proto_rate_proto_rawDescGZIP(), []int{2}
+	return file_services_rate_proto_rate_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *RatePlan) GetHotelId() string {
@@ -209,6 +292,85 @@ func (x *RatePlan) GetRoomType() *RoomType {
 	return nil
 }
 
+func (x *RatePlan) GetNights() []*NightRate {
+	if x != nil {
+		return x.Nights
+	}
+	return nil
+}
+
+type NightRate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the night starting on this date
+	Date               string  `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	BookableRate       float64 `protobuf:"fixed64,2,opt,name=bookableRate,proto3" json:"bookableRate,omitempty"`
+	TotalRate          float64 `protobuf:"fixed64,3,opt,name=totalRate,proto3" json:"totalRate,omitempty"`
+	TotalRateInclusive float64 `protobuf:"fixed64,4,opt,name=totalRateInclusive,proto3" json:"totalRateInclusive,omitempty"`
+}
+
+func (x *NightRate) Reset() {
+	*x = NightRate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_rate_proto_rate_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NightRate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NightRate) ProtoMessage() {}
+
+func (x *NightRate) ProtoReflect() protoreflect.Message {
+	mi := &file_services_rate_proto_rate_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NightRate.ProtoReflect.Descriptor instead.
+func (*NightRate) Descriptor() ([]byte, []int) {
+	return file_services_rate_proto_rate_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *NightRate) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *NightRate) GetBookableRate() float64 {
+	if x != nil {
+		return x.BookableRate
+	}
+	return 0
+}
+
+func (x *NightRate) GetTotalRate() float64 {
+	if x != nil {
+		return x.TotalRate
+	}
+	return 0
+}
+
+func (x *NightRate) GetTotalRateInclusive() float64 {
+	if x != nil {
+		return x.TotalRateInclusive
+	}
+	return 0
+}
+
 type RoomType struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -225,7 +387,7 @@ type RoomType struct {
 func (x *RoomType) Reset() {
 	*x = RoomType{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_services_rate_proto_rate_proto_msgTypes[3]
+		mi := &file_services_rate_proto_rate_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -238,7 +400,7 @@ func (x *RoomType) String() string {
 func (*RoomType) ProtoMessage() {}
 
 func (x *RoomType) ProtoReflect() protoreflect.Message {
-	mi := &file_services_rate_proto_rate_proto_msgTypes[3]
+	mi := &file_services_rate_proto_rate_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -251,7 +413,7 @@ func (x *RoomType) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RoomType.ProtoReflect.Descriptor instead.
 func (*RoomType) Descriptor() ([]byte, []int) {
-	return file_services_rate_proto_rate_proto_rawDescGZIP(), []int{3}
+	return file_services_rate_proto_rate_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *RoomType) GetBookableRate() float64 {
@@ -296,52 +458,138 @@ func (x *RoomType) GetRoomDescription() string {
 	return ""
 }
 
+type RatePlans struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RatePlans []*RatePlan `protobuf:"bytes,1,rep,name=ratePlans,proto3" json:"ratePlans,omitempty"`
+}
+
+func (x *RatePlans) Reset() {
+	*x = RatePlans{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_rate_proto_rate_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RatePlans) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RatePlans) ProtoMessage() {}
+
+func (x *RatePlans) ProtoReflect() protoreflect.Message {
+	mi := &file_services_rate_proto_rate_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RatePlans.ProtoReflect.Descriptor instead.
+func (*RatePlans) Descriptor() ([]byte, []int) {
+	return file_services_rate_proto_rate_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RatePlans) GetRatePlans() []*RatePlan {
+	if x != nil {
+		return x.RatePlans
+	}
+	return nil
+}
+
 var File_services_rate_proto_rate_proto protoreflect.FileDescriptor
 
 var file_services_rate_proto_rate_proto_rawDesc = []byte{
 	0x0a, 0x1e, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x2f, 0x72, 0x61, 0x74, 0x65, 0x2f,
 	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x72, 0x61, 0x74, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x12, 0x04, 0x72, 0x61, 0x74, 0x65, 0x22, 0x57, 0x0a, 0x07, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x12, 0x1a, 0x0a, 0x08, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x73, 0x18, 0x01, 0x20,
-	0x03, 0x28, 0x09, 0x52, 0x08, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x73, 0x12, 0x16, 0x0a,
-	0x06, 0x69, 0x6e, 0x44, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x69,
-	0x6e, 0x44, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6f, 0x75, 0x74, 0x44, 0x61, 0x74, 0x65,
-	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6f, 0x75, 0x74, 0x44, 0x61, 0x74, 0x65, 0x22,
-	0x36, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x2c, 0x0a, 0x09, 0x72, 0x61, 0x74,
-	0x65, 0x50, 0x6c, 0x61, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x72,
-	0x61, 0x74, 0x65, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x50, 0x6c, 0x61, 0x6e, 0x52, 0x09, 0x72, 0x61,
-	0x74, 0x65, 0x50, 0x6c, 0x61, 0x6e, 0x73, 0x22, 0x96, 0x01, 0x0a, 0x08, 0x52, 0x61, 0x74, 0x65,
-	0x50, 0x6c, 0x61, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x12, 0x12,
-	0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x63, 0x6f,
-	0x64, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x6e, 0x44, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x06, 0x69, 0x6e, 0x44, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6f, 0x75,
-	0x74, 0x44, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6f, 0x75, 0x74,
-	0x44, 0x61, 0x74, 0x65, 0x12, 0x2a, 0x0a, 0x08, 0x72, 0x6f, 0x6f, 0x6d, 0x54, 0x79, 0x70, 0x65,
-	0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x72, 0x61, 0x74, 0x65, 0x2e, 0x52, 0x6f,
-	0x6f, 0x6d, 0x54, 0x79, 0x70, 0x65, 0x52, 0x08, 0x72, 0x6f, 0x6f, 0x6d, 0x54, 0x79, 0x70, 0x65,
-	0x22, 0xd6, 0x01, 0x0a, 0x08, 0x52, 0x6f, 0x6f, 0x6d, 0x54, 0x79, 0x70, 0x65, 0x12, 0x22, 0x0a,
-	0x0c, 0x62, 0x6f, 0x6f, 0x6b, 0x61, 0x62, 0x6c, 0x65, 0x52, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20,
+	0x12, 0x04, 0x72, 0x61, 0x74, 0x65, 0x22, 0xfe, 0x01, 0x0a, 0x07, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x73, 0x12, 0x16,
+	0x0a, 0x06, 0x69, 0x6e, 0x44, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x69, 0x6e, 0x44, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6f, 0x75, 0x74, 0x44, 0x61, 0x74,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6f, 0x75, 0x74, 0x44, 0x61, 0x74, 0x65,
+	0x12, 0x1a, 0x0a, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x3a, 0x0a, 0x09,
+	0x6f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x1c, 0x2e, 0x72, 0x61, 0x74, 0x65, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x4f,
+	0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x09, 0x6f,
+	0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79, 0x1a, 0x4d, 0x0a, 0x0e, 0x4f, 0x63, 0x63, 0x75,
+	0x70, 0x61, 0x6e, 0x63, 0x79, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x25, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x72, 0x61,
+	0x74, 0x65, 0x2e, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79, 0x52, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x97, 0x01, 0x0a, 0x09, 0x4f, 0x63, 0x63, 0x75,
+	0x70, 0x61, 0x6e, 0x63, 0x79, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74,
+	0x79, 0x12, 0x33, 0x0a, 0x06, 0x62, 0x6f, 0x6f, 0x6b, 0x65, 0x64, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x1b, 0x2e, 0x72, 0x61, 0x74, 0x65, 0x2e, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e,
+	0x63, 0x79, 0x2e, 0x42, 0x6f, 0x6f, 0x6b, 0x65, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06,
+	0x62, 0x6f, 0x6f, 0x6b, 0x65, 0x64, 0x1a, 0x39, 0x0a, 0x0b, 0x42, 0x6f, 0x6f, 0x6b, 0x65, 0x64,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
+	0x01, 0x22, 0x36, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x2c, 0x0a, 0x09, 0x72,
+	0x61, 0x74, 0x65, 0x50, 0x6c, 0x61, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e,
+	0x2e, 0x72, 0x61, 0x74, 0x65, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x50, 0x6c, 0x61, 0x6e, 0x52, 0x09,
+	0x72, 0x61, 0x74, 0x65, 0x50, 0x6c, 0x61, 0x6e, 0x73, 0x22, 0xbf, 0x01, 0x0a, 0x08, 0x52, 0x61,
+	0x74, 0x65, 0x50, 0x6c, 0x61, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64,
+	0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x63, 0x6f, 0x64, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x6e, 0x44, 0x61, 0x74, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x69, 0x6e, 0x44, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x6f, 0x75, 0x74, 0x44, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6f,
+	0x75, 0x74, 0x44, 0x61, 0x74, 0x65, 0x12, 0x2a, 0x0a, 0x08, 0x72, 0x6f, 0x6f, 0x6d, 0x54, 0x79,
+	0x70, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x72, 0x61, 0x74, 0x65, 0x2e,
+	0x52, 0x6f, 0x6f, 0x6d, 0x54, 0x79, 0x70, 0x65, 0x52, 0x08, 0x72, 0x6f, 0x6f, 0x6d, 0x54, 0x79,
+	0x70, 0x65, 0x12, 0x27, 0x0a, 0x06, 0x6e, 0x69, 0x67, 0x68, 0x74, 0x73, 0x18, 0x06, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x72, 0x61, 0x74, 0x65, 0x2e, 0x4e, 0x69, 0x67, 0x68, 0x74, 0x52,
+	0x61, 0x74, 0x65, 0x52, 0x06, 0x6e, 0x69, 0x67, 0x68, 0x74, 0x73, 0x22, 0x91, 0x01, 0x0a, 0x09,
+	0x4e, 0x69, 0x67, 0x68, 0x74, 0x52, 0x61, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x64, 0x61, 0x74, 0x65, 0x12, 0x22, 0x0a,
+	0x0c, 0x62, 0x6f, 0x6f, 0x6b, 0x61, 0x62, 0x6c, 0x65, 0x52, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20,
 	0x01, 0x28, 0x01, 0x52, 0x0c, 0x62, 0x6f, 0x6f, 0x6b, 0x61, 0x62, 0x6c, 0x65, 0x52, 0x61, 0x74,
-	0x65, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x52, 0x61, 0x74, 0x65, 0x18, 0x02,
+	0x65, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x52, 0x61, 0x74, 0x65, 0x18, 0x03,
 	0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x52, 0x61, 0x74, 0x65, 0x12,
 	0x2e, 0x0a, 0x12, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x52, 0x61, 0x74, 0x65, 0x49, 0x6e, 0x63, 0x6c,
-	0x75, 0x73, 0x69, 0x76, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x12, 0x74, 0x6f, 0x74,
-	0x61, 0x6c, 0x52, 0x61, 0x74, 0x65, 0x49, 0x6e, 0x63, 0x6c, 0x75, 0x73, 0x69, 0x76, 0x65, 0x12,
-	0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x63,
-	0x6f, 0x64, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x18,
-	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x12,
-	0x28, 0x0a, 0x0f, 0x72, 0x6f, 0x6f, 0x6d, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69,
-	0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x72, 0x6f, 0x6f, 0x6d, 0x44, 0x65,
-	0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x32, 0x2f, 0x0a, 0x04, 0x52, 0x61, 0x74,
-	0x65, 0x12, 0x27, 0x0a, 0x08, 0x47, 0x65, 0x74, 0x52, 0x61, 0x74, 0x65, 0x73, 0x12, 0x0d, 0x2e,
-	0x72, 0x61, 0x74, 0x65, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0c, 0x2e, 0x72,
-	0x61, 0x74, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x42, 0x45, 0x5a, 0x43, 0x67, 0x69,
-	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x64, 0x65, 0x6c, 0x69, 0x6d, 0x69, 0x74,
-	0x72, 0x6f, 0x75, 0x2f, 0x44, 0x65, 0x61, 0x74, 0x68, 0x53, 0x74, 0x61, 0x72, 0x42, 0x65, 0x6e,
-	0x63, 0x68, 0x2f, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74,
-	0x69, 0x6f, 0x6e, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x2f, 0x72, 0x61, 0x74,
-	0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x75, 0x73, 0x69, 0x76, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x12, 0x74, 0x6f, 0x74,
+	0x61, 0x6c, 0x52, 0x61, 0x74, 0x65, 0x49, 0x6e, 0x63, 0x6c, 0x75, 0x73, 0x69, 0x76, 0x65, 0x22,
+	0xd6, 0x01, 0x0a, 0x08, 0x52, 0x6f, 0x6f, 0x6d, 0x54, 0x79, 0x70, 0x65, 0x12, 0x22, 0x0a, 0x0c,
+	0x62, 0x6f, 0x6f, 0x6b, 0x61, 0x62, 0x6c, 0x65, 0x52, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x0c, 0x62, 0x6f, 0x6f, 0x6b, 0x61, 0x62, 0x6c, 0x65, 0x52, 0x61, 0x74, 0x65,
+	0x12, 0x1c, 0x0a, 0x09, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x52, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x09, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x52, 0x61, 0x74, 0x65, 0x12, 0x2e,
+	0x0a, 0x12, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x52, 0x61, 0x74, 0x65, 0x49, 0x6e, 0x63, 0x6c, 0x75,
+	0x73, 0x69, 0x76, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x12, 0x74, 0x6f, 0x74, 0x61,
+	0x6c, 0x52, 0x61, 0x74, 0x65, 0x49, 0x6e, 0x63, 0x6c, 0x75, 0x73, 0x69, 0x76, 0x65, 0x12, 0x12,
+	0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x63, 0x6f,
+	0x64, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x28,
+	0x0a, 0x0f, 0x72, 0x6f, 0x6f, 0x6d, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x72, 0x6f, 0x6f, 0x6d, 0x44, 0x65, 0x73,
+	0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x39, 0x0a, 0x09, 0x52, 0x61, 0x74, 0x65,
+	0x50, 0x6c, 0x61, 0x6e, 0x73, 0x12, 0x2c, 0x0a, 0x09, 0x72, 0x61, 0x74, 0x65, 0x50, 0x6c, 0x61,
+	0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x72, 0x61, 0x74, 0x65, 0x2e,
+	0x52, 0x61, 0x74, 0x65, 0x50, 0x6c, 0x61, 0x6e, 0x52, 0x09, 0x72, 0x61, 0x74, 0x65, 0x50, 0x6c,
+	0x61, 0x6e, 0x73, 0x32, 0x5e, 0x0a, 0x04, 0x52, 0x61, 0x74, 0x65, 0x12, 0x27, 0x0a, 0x08, 0x47,
+	0x65, 0x74, 0x52, 0x61, 0x74, 0x65, 0x73, 0x12, 0x0d, 0x2e, 0x72, 0x61, 0x74, 0x65, 0x2e, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0c, 0x2e, 0x72, 0x61, 0x74, 0x65, 0x2e, 0x52, 0x65,
+	0x73, 0x75, 0x6c, 0x74, 0x12, 0x2d, 0x0a, 0x0c, 0x41, 0x64, 0x64, 0x52, 0x61, 0x74, 0x65, 0x50,
+	0x6c, 0x61, 0x6e, 0x73, 0x12, 0x0f, 0x2e, 0x72, 0x61, 0x74, 0x65, 0x2e, 0x52, 0x61, 0x74, 0x65,
+	0x50, 0x6c, 0x61, 0x6e, 0x73, 0x1a, 0x0c, 0x2e, 0x72, 0x61, 0x74, 0x65, 0x2e, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x42, 0x51, 0x5a, 0x4f, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x64, 0x65, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x72, 0x6f, 0x75, 0x2f, 0x44, 0x65, 0x61,
+	0x74, 0x68, 0x53, 0x74, 0x61, 0x72, 0x42, 0x65, 0x6e, 0x63, 0x68, 0x2f, 0x74, 0x72, 0x65, 0x65,
+	0x2f, 0x6d, 0x61, 0x73, 0x74, 0x65, 0x72, 0x2f, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x52, 0x65, 0x73,
+	0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x73, 0x2f, 0x72, 0x61, 0x74, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -356,23 +604,35 @@ func file_services_rate_proto_rate_proto_rawDescGZIP() []byte {
 	return file_services_rate_proto_rate_proto_rawDescData
 }
 
-var file_services_rate_proto_rate_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_services_rate_proto_rate_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
 var file_services_rate_proto_rate_proto_goTypes = []interface{}{
-	(*Request)(nil),  // 0: rate.Request
-	(*Result)(nil),   // 1: rate.Result
-	(*RatePlan)(nil), // 2: rate.RatePlan
-	(*RoomType)(nil), // 3: rate.RoomType
+	(*Request)(nil),   // 0: rate.Request
+	(*Occupancy)(nil), // 1: rate.Occupancy
+	(*Result)(nil),    // 2: rate.Result
+	(*RatePlan)(nil),  // 3: rate.RatePlan
+	(*NightRate)(nil), // 4: rate.NightRate
+	(*RoomType)(nil),  // 5: rate.RoomType
+	(*RatePlans)(nil), // 6: rate.RatePlans
+	nil,               // 7: rate.Request.OccupancyEntry
+	nil,               // 8: rate.Occupancy.BookedEntry
 }
 var file_services_rate_proto_rate_proto_depIdxs = []int32{
-	2, // 0: rate.Result.ratePlans:type_name -> rate.RatePlan
-	3, // 1: rate.RatePlan.roomType:type_name -> rate.RoomType
-	0, // 2: rate.Rate.GetRates:input_type -> rate.Request
-	1, // 3: rate.Rate.GetRates:output_type -> rate.Result
-	3, // [3:4] is the sub-list for method output_type
-	2, // [2:3] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	7, // 0: rate.Request.occupancy:type_name -> rate.Request.OccupancyEntry
+	8, // 1: rate.Occupancy.booked:type_name -> rate.Occupancy.BookedEntry
+	3, // 2: rate.Result.ratePlans:type_name -> rate.RatePlan
+	5, // 3: rate.RatePlan.roomType:type_name -> rate.RoomType
+	4, // 4: rate.RatePlan.nights:type_name -> rate.NightRate
+	3, // 5: rate.RatePlans.ratePlans:type_name -> rate.RatePlan
+	1, // 6: rate.Request.OccupancyEntry.value:type_name -> rate.Occupancy
+	0, // 7: rate.Rate.GetRates:input_type -> rate.Request
+	6, // 8: rate.Rate.AddRatePlans:input_type -> rate.RatePlans
+	2, // 9: rate.Rate.GetRates:output_type -> rate.Result
+	2, // 10: rate.Rate.AddRatePlans:output_type -> rate.Result
+	9, // [9:11] is the sub-list for method output_type
+	7, // [7:9] is the sub-list for method input_type
+	7, // [7:7] is the sub-list for extension type_name
+	7, // [7:7] is the sub-list for extension extendee
+	0, // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_services_rate_proto_rate_proto_init() }
@@ -394,7 +654,7 @@ func file_services_rate_proto_rate_proto_init() {
 			}
 		}
 		file_services_rate_proto_rate_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Result); i {
+			switch v := v.(*Occupancy); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -406,7 +666,7 @@ func file_services_rate_proto_rate_proto_init() {
 			}
 		}
 		file_services_rate_proto_rate_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RatePlan); i {
+			switch v := v.(*Result); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -418,6 +678,30 @@ func file_services_rate_proto_rate_proto_init() {
 			}
 		}
 		file_services_rate_proto_rate_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RatePlan); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_rate_proto_rate_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NightRate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_rate_proto_rate_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*RoomType); i {
 			case 0:
 				return &v.state
@@ -429,6 +713,18 @@ func file_services_rate_proto_rate_proto_init() {
 				return nil
 			}
 		}
+		file_services_rate_proto_rate_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RatePlans); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -436,7 +732,7 @@ func file_services_rate_proto_rate_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_services_rate_proto_rate_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   9,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
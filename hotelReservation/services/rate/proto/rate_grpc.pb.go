@@ -19,15 +19,18 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Rate_GetRates_FullMethodName = "/rate.Rate/GetRates"
+	Rate_GetRates_FullMethodName     = "/rate.Rate/GetRates"
+	Rate_AddRatePlans_FullMethodName = "/rate.Rate/AddRatePlans"
 )
 
 // RateClient is the client API for Rate service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type RateClient interface {
-	// GetRates returns rate codes for hotels for a given date range
+	// GetRates returns the rate plans of hotels priced for a given stay
 	GetRates(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
+	// AddRatePlans replaces the rate plans of the hotels they belong to
+	AddRatePlans(ctx context.Context, in *RatePlans, opts ...grpc.CallOption) (*Result, error)
 }
 
 type rateClient struct {
@@ -47,12 +50,23 @@ func (c *rateClient) GetRates(ctx context.Context, in *Request, opts ...grpc.Cal
 	return out, nil
 }
 
+func (c *rateClient) AddRatePlans(ctx context.Context, in *RatePlans, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, Rate_AddRatePlans_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RateServer is the server API for Rate service.
 // All implementations must embed UnimplementedRateServer
 // for forward compatibility
 type RateServer interface {
-	// GetRates returns rate codes for hotels for a given date range
+	// GetRates returns the rate plans of hotels priced for a given stay
 	GetRates(context.Context, *Request) (*Result, error)
+	// AddRatePlans replaces the rate plans of the hotels they belong to
+	AddRatePlans(context.Context, *RatePlans) (*Result, error)
 	mustEmbedUnimplementedRateServer()
 }
 
@@ -63,6 +77,9 @@ type UnimplementedRateServer struct {
 func (UnimplementedRateServer) GetRates(context.Context, *Request) (*Result, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRates not implemented")
 }
+func (UnimplementedRateServer) AddRatePlans(context.Context, *RatePlans) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddRatePlans not implemented")
+}
 func (UnimplementedRateServer) mustEmbedUnimplementedRateServer() {}
 
 // UnsafeRateServer may be embedded to opt out of forward compatibility for this service.
@@ -94,6 +111,24 @@ func _Rate_GetRates_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Rate_AddRatePlans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RatePlans)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateServer).AddRatePlans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rate_AddRatePlans_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateServer).AddRatePlans(ctx, req.(*RatePlans))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Rate_ServiceDesc is the grpc.ServiceDesc for Rate service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -105,6 +140,10 @@ var Rate_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetRates",
 			Handler:    _Rate_GetRates_Handler,
 		},
+		{
+			MethodName: "AddRatePlans",
+			Handler:    _Rate_AddRatePlans_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "services/rate/proto/rate.proto",
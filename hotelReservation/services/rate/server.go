@@ -6,13 +6,16 @@ import (
 	"fmt"
 	"net"
 	"sort"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/dialer"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
 	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate/proto"
+	reservation "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/reservation/proto"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -20,34 +23,72 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 )
 
 const name = "srv-rate"
 
+// stayTTL bounds how long a cached stay lives, in seconds, in case the rate
+// version of its hotel gets evicted before it
+const stayTTL = 3600
+
 // Server implements the rate service
 type Server struct {
 	pb.UnimplementedRateServer
 
-	uuid string
+	uuid              string
+	grpcServer        *grpc.Server
+	listener          net.Listener
+	health            *health.Server
+	reservationClient reservation.ReservationClient
 
 	Tracer      trace.Tracer
 	Port        int
 	IpAddr      string
 	MongoClient *mongo.Client
-	Registry    *registry.Client
+	Registry    registry.Registry
 	MemcClient  *memcache.Client
+	// Pricing adjusts the rates of a stay to the occupancy reported by the
+	// reservation service; nil serves the stored rates
+	Pricing Policy
+	// BaseCurrency is the currency of rates stored without one, and
+	// Currencies the units of each currency per unit of it
+	BaseCurrency string
+	Currencies   map[string]float64
 }
 
-// Run starts the server
-func (s *Server) Run() error {
+// Setup creates the server, listens on Port and registers in consul. It is
+// called before Run, so Shutdown only reads what Setup wrote.
+func (s *Server) Setup() error {
 	if s.Port == 0 {
 		return fmt.Errorf("server port must be set")
 	}
 
+	if s.BaseCurrency == "" {
+		s.BaseCurrency = DefaultCurrency
+	}
+	s.BaseCurrency = strings.ToUpper(s.BaseCurrency)
+	if s.Currencies == nil {
+		s.Currencies = map[string]float64{s.BaseCurrency: 1}
+	}
+	if s.Currencies[s.BaseCurrency] != 1 {
+		return fmt.Errorf("base currency %s must have rate 1", s.BaseCurrency)
+	}
+
+	if s.Pricing != nil {
+		if err := s.initReservationClient("srv-reservation"); err != nil {
+			return err
+		}
+	}
+
 	s.uuid = uuid.New().String()
 
 	opts := []grpc.ServerOption{
@@ -58,15 +99,22 @@ func (s *Server) Run() error {
 			PermitWithoutStream: true,
 		}),
 		grpc.UnaryInterceptor(
-			otelgrpc.UnaryServerInterceptor(),
+			otelgrpc.UnaryServerInterceptor(
+				otelgrpc.WithInterceptorFilter(filters.Not(filters.HealthCheck())),
+			),
 		),
 	}
 
 	if tlsopt := tls.GetServerOpt(); tlsopt != nil {
-		opts = append(opts, tlsopt)
+		opts = append(opts, tlsopt, grpc.ChainUnaryInterceptor(tls.UnaryServerInterceptor()))
 	}
 
 	srv := grpc.NewServer(opts...)
+	s.grpcServer = srv
+
+	// answers the consul check, serving until Shutdown
+	s.health = health.NewServer()
+	healthpb.RegisterHealthServer(srv, s.health)
 
 	pb.RegisterRateServer(srv, s)
 
@@ -81,12 +129,46 @@ func (s *Server) Run() error {
 	}
 	log.Info().Msg("Successfully registered in consul")
 
-	return srv.Serve(lis)
+	s.listener = lis
+	return nil
+}
+
+// Run serves the server set up by Setup until Shutdown
+func (s *Server) Run() error {
+	return s.grpcServer.Serve(s.listener)
+}
+
+// Shutdown fails the health check and deregisters the service so no new
+// calls are routed here, then waits for pending calls until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+	err := s.Registry.Deregister(s.uuid)
+	shutdown.GRPC(ctx, s.grpcServer)
+	return err
+}
+
+func (s *Server) initReservationClient(name string) error {
+	conn, err := s.getGprcConn(name)
+	if err != nil {
+		return fmt.Errorf("dialer error: %v", err)
+	}
+	s.reservationClient = reservation.NewReservationClient(conn)
+	return nil
 }
 
-// Shutdown cleans up any processes
-func (s *Server) Shutdown() {
-	s.Registry.Deregister(s.uuid)
+func (s *Server) getGprcConn(name string) (*grpc.ClientConn, error) {
+	target, err := s.Registry.Target(name)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.Dial(
+		target,
+		dialer.WithTracer(s.Tracer),
+		dialer.WithBalancer(""),
+		dialer.WithEnvPolicies(),
+	)
 }
 
 // GetRates gets rates for hotels for specific date range.
@@ -116,98 +198,249 @@ func (s *Server) GetRates(ctx context.Context, req *pb.Request) (*pb.Result, err
 
 	logger.Info().Msgf("Getting hotel rates: hotel_count=%d, in_date=%s, out_date=%s", len(req.HotelIds), req.InDate, req.OutDate)
 
-	ratePlans := make(RatePlans, 0)
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = s.BaseCurrency
+	}
+	if _, ok := s.Currencies[currency]; !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported currency %q", req.Currency)
+	}
 
-	hotelIds := []string{}
-	rateMap := make(map[string]struct{})
-	for _, hotelID := range req.HotelIds {
-		hotelIds = append(hotelIds, hotelID)
-		rateMap[hotelID] = struct{}{}
+	var dates []string
+	if req.InDate != "" || req.OutDate != "" {
+		var err error
+		if dates, err = stayDates(req.InDate, req.OutDate); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 	}
-	// first check memcached(get-multi)
+
+	// cached stays are keyed by the rate version of their hotel, which
+	// AddRatePlans bumps, so replaced plans are never served
 	ctx, memSpan := s.Tracer.Start(ctx, "memcached_get_multi_rate")
 	memSpan.SetAttributes(attribute.String("span.kind", "client"))
-
-	resMap, err := s.MemcClient.GetMulti(hotelIds)
+	versionKeys := make([]string, 0, len(req.HotelIds))
+	for _, hotelId := range req.HotelIds {
+		versionKeys = append(versionKeys, versionKey(hotelId))
+	}
+	versions, err := s.MemcClient.GetMulti(versionKeys)
+	if err != nil {
+		memSpan.End()
+		logger.Error().Strs("hotel_ids", req.HotelIds).Err(err).Msg("Memcached error while getting rate versions")
+		return nil, err
+	}
+	stayKeys := make(map[string]string, len(req.HotelIds))
+	keys := make([]string, 0, len(req.HotelIds))
+	for _, hotelId := range req.HotelIds {
+		version := "0"
+		if item, ok := versions[versionKey(hotelId)]; ok {
+			version = string(item.Value)
+		}
+		stayKeys[hotelId] = stayKey(hotelId, version, req.InDate, req.OutDate)
+		keys = append(keys, stayKeys[hotelId])
+	}
+	resMap, err := s.MemcClient.GetMulti(keys)
 	memSpan.End()
+	if err != nil {
+		logger.Error().Strs("hotel_ids", req.HotelIds).Err(err).Msg("Memcached error while getting hotel rates")
+		return nil, err
+	}
 
-	var wg sync.WaitGroup
-	var mutex sync.Mutex
-	if err != nil && err != memcache.ErrCacheMiss {
-		logger.Panic().
-			Strs("hotel_ids", hotelIds).
-			Err(err).
-			Msg("Memcached error while getting hotel rates")
-	} else {
-		for hotelId, item := range resMap {
-			rateStrs := strings.Split(string(item.Value), "\n")
-			logger.Debug().Msgf("Rate cache hit: hotel_id=%s, rate_plans=%d", hotelId, len(rateStrs))
-
-			for _, rateStr := range rateStrs {
-				if len(rateStr) != 0 {
-					rateP := new(pb.RatePlan)
-					json.Unmarshal([]byte(rateStr), rateP)
-					ratePlans = append(ratePlans, rateP)
-				}
+	ratePlans := make(RatePlans, 0)
+	missing := make([]string, 0)
+	for _, hotelId := range req.HotelIds {
+		item, ok := resMap[stayKeys[hotelId]]
+		if !ok {
+			missing = append(missing, hotelId)
+			continue
+		}
+		rateStrs := strings.Split(string(item.Value), "\n")
+		logger.Debug().Msgf("Rate cache hit: hotel_id=%s, rate_plans=%d", hotelId, len(rateStrs)-1)
+		for _, rateStr := range rateStrs {
+			if len(rateStr) != 0 {
+				rateP := new(pb.RatePlan)
+				json.Unmarshal([]byte(rateStr), rateP)
+				ratePlans = append(ratePlans, rateP)
 			}
-
-			delete(rateMap, hotelId)
 		}
+	}
 
-		wg.Add(len(rateMap))
-		for hotelId := range rateMap {
-			go func(id string) {
-				logger.Debug().
-					Str("hotel_id", id).
-					Msg("Rate cache miss, fetching from database")
-
-				_, mongoSpan := s.Tracer.Start(ctx, "mongo_rate")
-				mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
-
-				// memcached miss, set up mongo connection
-				collection := s.MongoClient.Database("rate-db").Collection("inventory")
-				curr, err := collection.Find(context.TODO(), bson.D{})
-				if err != nil {
-					logger.Error().Msgf("Failed to get rate data from database: hotel_id=%s, error=%v", id, err)
-				}
+	if len(missing) > 0 {
+		logger.Debug().Strs("hotel_ids", missing).Msg("Rate cache miss, fetching from database")
 
-				tmpRatePlans := make(RatePlans, 0)
-				curr.All(context.TODO(), &tmpRatePlans)
-				if err != nil {
-					logger.Error().Msgf("Failed get rate data: ", err)
-				}
+		stored, err := s.findRatePlans(ctx, missing, req.InDate, req.OutDate)
+		if err != nil {
+			logger.Error().Msgf("Failed to get rate data from database: hotels=%d, error=%v", len(missing), err)
+			return nil, err
+		}
 
-				mongoSpan.End()
+		for _, hotelId := range missing {
+			plans := stored[hotelId]
+			if dates != nil {
+				plans = stayPlans(plans, dates, req.OutDate)
+			}
 
-				memcStr := ""
+			// hotels without plans for the stay are cached as well
+			memcStr := ""
+			for _, r := range plans {
+				ratePlans = append(ratePlans, r)
+				rateJson, err := json.Marshal(r)
 				if err != nil {
-					logger.Panic().Msgf("Tried to find hotelId [%v], but got error", id, err.Error())
-				} else {
-					for _, r := range tmpRatePlans {
-						mutex.Lock()
-						ratePlans = append(ratePlans, r)
-						mutex.Unlock()
-						rateJson, err := json.Marshal(r)
-						if err != nil {
-							logger.Error().Msgf("Failed to marshal plan [Code: %v] with error: %s", r.Code, err)
-						}
-						memcStr = memcStr + string(rateJson) + "\n"
-					}
+					logger.Error().Msgf("Failed to marshal plan [Code: %v] with error: %s", r.Code, err)
 				}
-				go s.MemcClient.Set(&memcache.Item{Key: id, Value: []byte(memcStr)})
-
-				defer wg.Done()
-			}(hotelId)
+				memcStr = memcStr + string(rateJson) + "\n"
+			}
+			go s.MemcClient.Set(&memcache.Item{Key: stayKeys[hotelId], Value: []byte(memcStr), Expiration: stayTTL})
 		}
 	}
-	wg.Wait()
+
+	if s.Pricing != nil && dates != nil && len(ratePlans) > 0 {
+		s.applyPricing(ctx, ratePlans, req, logger)
+	}
+	for _, p := range ratePlans {
+		convert(p, s.Currencies, s.BaseCurrency, currency)
+	}
 
 	sort.Sort(ratePlans)
 	res.RatePlans = ratePlans
 
+	logger.Debug().Msgf("Get rates completed: rate_plans=%d, cache_misses=%d", len(ratePlans), len(missing))
 	return res, nil
 }
 
+// applyPricing adjusts priced stays to the current occupancy of their hotels,
+// taken from the request if the caller sent it. Rates stay as stored if the
+// occupancy is unknown.
+func (s *Server) applyPricing(ctx context.Context, plans RatePlans, req *pb.Request, logger *zerolog.Logger) {
+	if req.Occupancy != nil {
+		for _, p := range plans {
+			if occ, ok := req.Occupancy[p.HotelId]; ok {
+				applyPolicy(s.Pricing, p, &reservation.Occupancy{Capacity: occ.Capacity, Booked: occ.Booked})
+			}
+		}
+		return
+	}
+
+	hotelIds := make([]string, 0, len(plans))
+	seen := make(map[string]bool)
+	for _, p := range plans {
+		if !seen[p.HotelId] {
+			seen[p.HotelId] = true
+			hotelIds = append(hotelIds, p.HotelId)
+		}
+	}
+
+	occupancy, err := s.reservationClient.GetOccupancy(ctx, &reservation.OccupancyRequest{
+		HotelIds: hotelIds,
+		InDate:   req.InDate,
+		OutDate:  req.OutDate,
+	})
+	if err != nil {
+		logger.Warn().Msgf("Failed to get occupancy, serving stored rates: hotels=%d, error=%v", len(hotelIds), err)
+		return
+	}
+	for _, p := range plans {
+		applyPolicy(s.Pricing, p, occupancy.Hotels[p.HotelId])
+	}
+}
+
+// findRatePlans returns the stored plans of the given hotels by hotel id,
+// limited to plans overlapping the stay if one is given
+func (s *Server) findRatePlans(ctx context.Context, hotelIds []string, inDate, outDate string) (map[string][]*pb.RatePlan, error) {
+	_, mongoSpan := s.Tracer.Start(ctx, "mongo_rate")
+	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	defer mongoSpan.End()
+
+	filter := bson.M{"hotelId": bson.M{"$in": hotelIds}}
+	if inDate != "" {
+		filter["inDate"] = bson.M{"$lt": outDate}
+		filter["outDate"] = bson.M{"$gt": inDate}
+	}
+
+	collection := s.MongoClient.Database("rate-db").Collection("inventory")
+	curr, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	plans := make(RatePlans, 0)
+	if err := curr.All(ctx, &plans); err != nil {
+		return nil, err
+	}
+
+	byHotel := make(map[string][]*pb.RatePlan)
+	for _, p := range plans {
+		byHotel[p.HotelId] = append(byHotel[p.HotelId], p)
+	}
+	return byHotel, nil
+}
+
+func versionKey(hotelId string) string {
+	return hotelId + "_rates"
+}
+
+func stayKey(hotelId, version, inDate, outDate string) string {
+	return fmt.Sprintf("%s_%s_%s_%s", hotelId, version, inDate, outDate)
+}
+
+// AddRatePlans replaces the stored rate plans of every hotel in the request
+// and invalidates their cached stays
+func (s *Server) AddRatePlans(ctx context.Context, req *pb.RatePlans) (*pb.Result, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	byHotel := make(map[string][]interface{})
+	for _, r := range req.RatePlans {
+		if r.HotelId == "" || r.Code == "" || r.RoomType == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "rate plans need a hotelId, code and roomType")
+		}
+		// same field names as the seeded documents
+		byHotel[r.HotelId] = append(byHotel[r.HotelId], bson.M{
+			"hotelId": r.HotelId,
+			"code":    r.Code,
+			"inDate":  r.InDate,
+			"outDate": r.OutDate,
+			"roomType": bson.M{
+				"bookableRate":       r.RoomType.BookableRate,
+				"code":               r.RoomType.Code,
+				"roomDescription":    r.RoomType.RoomDescription,
+				"totalRate":          r.RoomType.TotalRate,
+				"totalRateInclusive": r.RoomType.TotalRateInclusive,
+				"currency":           r.RoomType.Currency,
+			},
+		})
+	}
+	if len(byHotel) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "no rate plans given")
+	}
+
+	collection := s.MongoClient.Database("rate-db").Collection("inventory")
+	for hotelId, docs := range byHotel {
+		_, mongoSpan := s.Tracer.Start(ctx, "mongo_rate_add")
+		mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+		_, err := collection.DeleteMany(ctx, bson.M{"hotelId": hotelId})
+		if err == nil {
+			_, err = collection.InsertMany(ctx, docs)
+		}
+		mongoSpan.End()
+		if err != nil {
+			logger.Error().Msgf("Failed to store rate plans: hotel_id=%s, error=%v", hotelId, err)
+			return nil, err
+		}
+
+		// a new version makes every cached stay of the hotel unreachable
+		version := strconv.FormatInt(time.Now().UnixNano(), 36)
+		if err := s.MemcClient.Set(&memcache.Item{Key: versionKey(hotelId), Value: []byte(version)}); err != nil {
+			logger.Error().Msgf("Failed to invalidate cached rates: hotel_id=%s, error=%v", hotelId, err)
+			return nil, err
+		}
+		logger.Info().Msgf("Rate plans stored: hotel_id=%s, count=%d", hotelId, len(docs))
+	}
+
+	return &pb.Result{RatePlans: req.RatePlans}, nil
+}
+
 type RatePlans []*pb.RatePlan
 
 func (r RatePlans) Len() int {
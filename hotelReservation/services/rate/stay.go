@@ -0,0 +1,111 @@
+package rate
+
+import (
+	"fmt"
+	"time"
+
+	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate/proto"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	// maxStayNights bounds the stays rates are computed for
+	maxStayNights = 365
+)
+
+// stayDates returns the start date of every night between inDate and outDate
+func stayDates(inDate, outDate string) ([]string, error) {
+	in, err := time.Parse(dateLayout, inDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inDate %q", inDate)
+	}
+	out, err := time.Parse(dateLayout, outDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid outDate %q", outDate)
+	}
+	if !in.Before(out) {
+		return nil, fmt.Errorf("inDate %s is not before outDate %s", inDate, outDate)
+	}
+	if out.Sub(in) > maxStayNights*24*time.Hour {
+		return nil, fmt.Errorf("stays are limited to %d nights", maxStayNights)
+	}
+
+	var dates []string
+	for d := in; d.Before(out); d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d.Format(dateLayout))
+	}
+	return dates, nil
+}
+
+// covers reports whether a stored plan applies to the night starting on date.
+// Dates are YYYY-MM-DD, so they compare as strings.
+func covers(plan *pb.RatePlan, date string) bool {
+	return plan.InDate <= date && date < plan.OutDate
+}
+
+type planKey struct {
+	code     string
+	roomCode string
+}
+
+// stayPlans prices the stored plans of one hotel for the nights of a stay
+// ending on outDate. Plans with the same rate code and room type are combined,
+// taking the cheapest one for every night; combinations missing a night are
+// left out.
+func stayPlans(plans []*pb.RatePlan, dates []string, outDate string) []*pb.RatePlan {
+	groups := make(map[planKey][]*pb.RatePlan)
+	var order []planKey
+	for _, p := range plans {
+		if p.RoomType == nil {
+			continue
+		}
+		k := planKey{p.Code, p.RoomType.Code}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], p)
+	}
+
+	priced := make([]*pb.RatePlan, 0, len(order))
+	for _, k := range order {
+		group := groups[k]
+		plan := &pb.RatePlan{
+			HotelId: group[0].HotelId,
+			Code:    k.code,
+			InDate:  dates[0],
+			OutDate: outDate,
+			RoomType: &pb.RoomType{
+				Code:            k.roomCode,
+				Currency:        group[0].RoomType.Currency,
+				RoomDescription: group[0].RoomType.RoomDescription,
+			},
+		}
+
+		complete := true
+		for _, date := range dates {
+			var cheapest *pb.RatePlan
+			for _, p := range group {
+				if covers(p, date) && (cheapest == nil || p.RoomType.TotalRateInclusive < cheapest.RoomType.TotalRateInclusive) {
+					cheapest = p
+				}
+			}
+			if cheapest == nil {
+				complete = false
+				break
+			}
+			plan.Nights = append(plan.Nights, &pb.NightRate{
+				Date:               date,
+				BookableRate:       cheapest.RoomType.BookableRate,
+				TotalRate:          cheapest.RoomType.TotalRate,
+				TotalRateInclusive: cheapest.RoomType.TotalRateInclusive,
+			})
+			plan.RoomType.BookableRate += cheapest.RoomType.BookableRate
+			plan.RoomType.TotalRate += cheapest.RoomType.TotalRate
+			plan.RoomType.TotalRateInclusive += cheapest.RoomType.TotalRateInclusive
+		}
+		if complete {
+			priced = append(priced, plan)
+		}
+	}
+	return priced
+}
@@ -0,0 +1,198 @@
+package rate
+
+import (
+	"testing"
+
+	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate/proto"
+)
+
+func TestStayDates(t *testing.T) {
+	tests := []struct {
+		name    string
+		in, out string
+		nights  int
+		first   string
+		last    string
+		wantErr bool
+	}{
+		{name: "one night", in: "2015-04-09", out: "2015-04-10", nights: 1, first: "2015-04-09", last: "2015-04-09"},
+		{name: "across a month", in: "2015-04-29", out: "2015-05-02", nights: 3, first: "2015-04-29", last: "2015-05-01"},
+		{name: "leap day", in: "2016-02-28", out: "2016-03-01", nights: 2, first: "2016-02-28", last: "2016-02-29"},
+		{name: "365 nights", in: "2015-01-01", out: "2016-01-01", nights: 365, first: "2015-01-01", last: "2015-12-31"},
+		{name: "366 nights", in: "2015-01-01", out: "2016-01-02", wantErr: true},
+		{name: "out on the in date", in: "2015-04-09", out: "2015-04-09", wantErr: true},
+		{name: "out before the in date", in: "2015-04-10", out: "2015-04-09", wantErr: true},
+		{name: "malformed in date", in: "2015-4-9", out: "2015-04-10", wantErr: true},
+		{name: "malformed out date", in: "2015-04-09", out: "tomorrow", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dates, err := stayDates(tt.in, tt.out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(dates) != tt.nights {
+				t.Fatalf("got %d nights, want %d", len(dates), tt.nights)
+			}
+			if dates[0] != tt.first || dates[len(dates)-1] != tt.last {
+				t.Errorf("got nights %s to %s, want %s to %s", dates[0], dates[len(dates)-1], tt.first, tt.last)
+			}
+		})
+	}
+}
+
+func plan(code, room, in, out string, rate float64) *pb.RatePlan {
+	return &pb.RatePlan{
+		HotelId: "1",
+		Code:    code,
+		InDate:  in,
+		OutDate: out,
+		RoomType: &pb.RoomType{
+			Code:               room,
+			Currency:           "USD",
+			BookableRate:       rate,
+			TotalRate:          rate,
+			TotalRateInclusive: rate,
+		},
+	}
+}
+
+func TestStayPlans(t *testing.T) {
+	type priced struct {
+		code, room string
+		total      float64
+		nights     []float64
+	}
+
+	tests := []struct {
+		name    string
+		plans   []*pb.RatePlan
+		in, out string
+		want    []priced
+	}{
+		{
+			name:  "one night",
+			plans: []*pb.RatePlan{plan("RACK", "KNG", "2015-04-09", "2015-04-10", 109)},
+			in:    "2015-04-09", out: "2015-04-10",
+			want: []priced{{"RACK", "KNG", 109, []float64{109}}},
+		},
+		{
+			name:  "plan ending on the out date covers the last night",
+			plans: []*pb.RatePlan{plan("RACK", "KNG", "2015-04-09", "2015-04-12", 100)},
+			in:    "2015-04-09", out: "2015-04-12",
+			want: []priced{{"RACK", "KNG", 300, []float64{100, 100, 100}}},
+		},
+		{
+			name: "consecutive plans are combined",
+			plans: []*pb.RatePlan{
+				plan("RACK", "KNG", "2015-04-09", "2015-04-10", 100),
+				plan("RACK", "KNG", "2015-04-10", "2015-04-12", 150),
+			},
+			in: "2015-04-09", out: "2015-04-12",
+			want: []priced{{"RACK", "KNG", 400, []float64{100, 150, 150}}},
+		},
+		{
+			name: "cheapest overlapping plan per night",
+			plans: []*pb.RatePlan{
+				plan("RACK", "KNG", "2015-04-09", "2015-04-12", 150),
+				plan("RACK", "KNG", "2015-04-10", "2015-04-11", 90),
+			},
+			in: "2015-04-09", out: "2015-04-12",
+			want: []priced{{"RACK", "KNG", 390, []float64{150, 90, 150}}},
+		},
+		{
+			name: "gap in the plans leaves the combination out",
+			plans: []*pb.RatePlan{
+				plan("RACK", "KNG", "2015-04-09", "2015-04-10", 100),
+				plan("RACK", "KNG", "2015-04-11", "2015-04-12", 100),
+				plan("RACK", "QN", "2015-04-09", "2015-04-12", 80),
+			},
+			in: "2015-04-09", out: "2015-04-12",
+			want: []priced{{"RACK", "QN", 240, []float64{80, 80, 80}}},
+		},
+		{
+			name:  "plan ending before the out date",
+			plans: []*pb.RatePlan{plan("RACK", "KNG", "2015-04-09", "2015-04-11", 100)},
+			in:    "2015-04-09", out: "2015-04-12",
+			want: []priced{},
+		},
+		{
+			name: "rate codes and room types are priced apart",
+			plans: []*pb.RatePlan{
+				plan("RACK", "KNG", "2015-04-09", "2015-04-11", 100),
+				plan("PROMO", "KNG", "2015-04-09", "2015-04-11", 70),
+				plan("RACK", "QN", "2015-04-09", "2015-04-11", 90),
+			},
+			in: "2015-04-09", out: "2015-04-11",
+			want: []priced{
+				{"RACK", "KNG", 200, []float64{100, 100}},
+				{"PROMO", "KNG", 140, []float64{70, 70}},
+				{"RACK", "QN", 180, []float64{90, 90}},
+			},
+		},
+		{
+			name: "plans without a room type are skipped",
+			plans: []*pb.RatePlan{
+				{HotelId: "1", Code: "RACK", InDate: "2015-04-09", OutDate: "2015-04-10"},
+			},
+			in: "2015-04-09", out: "2015-04-10",
+			want: []priced{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dates, err := stayDates(tt.in, tt.out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := stayPlans(tt.plans, dates, tt.out)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d plans, want %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				p := got[i]
+				if p.Code != want.code || p.RoomType.Code != want.room {
+					t.Errorf("plan %d: got %s/%s, want %s/%s", i, p.Code, p.RoomType.Code, want.code, want.room)
+				}
+				if p.InDate != tt.in || p.OutDate != tt.out {
+					t.Errorf("plan %d: got stay %s to %s, want %s to %s", i, p.InDate, p.OutDate, tt.in, tt.out)
+				}
+				if p.RoomType.TotalRateInclusive != want.total || p.RoomType.BookableRate != want.total {
+					t.Errorf("plan %d: got total %v, want %v", i, p.RoomType.TotalRateInclusive, want.total)
+				}
+				if len(p.Nights) != len(want.nights) {
+					t.Fatalf("plan %d: got %d nights, want %d", i, len(p.Nights), len(want.nights))
+				}
+				for j, night := range p.Nights {
+					if night.Date != dates[j] || night.TotalRateInclusive != want.nights[j] {
+						t.Errorf("plan %d night %d: got %s at %v, want %s at %v", i, j, night.Date, night.TotalRateInclusive, dates[j], want.nights[j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStayPlans365Nights(t *testing.T) {
+	dates, err := stayDates("2015-01-01", "2016-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plans := []*pb.RatePlan{
+		plan("RACK", "KNG", "2015-01-01", "2015-07-01", 100),
+		plan("RACK", "KNG", "2015-07-01", "2016-01-01", 200),
+	}
+	got := stayPlans(plans, dates, "2016-01-01")
+	if len(got) != 1 || len(got[0].Nights) != 365 {
+		t.Fatalf("got %v", got)
+	}
+	// 181 nights in the first half of 2015, 184 in the second
+	if want := 181*100.0 + 184*200.0; got[0].RoomType.TotalRateInclusive != want {
+		t.Errorf("got total %v, want %v", got[0].RoomType.TotalRateInclusive, want)
+	}
+}
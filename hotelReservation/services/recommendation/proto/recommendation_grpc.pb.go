@@ -20,6 +20,8 @@ const _ = grpc.SupportPackageIsVersion7
 
 const (
 	Recommendation_GetRecommendations_FullMethodName = "/recommendation.Recommendation/GetRecommendations"
+	Recommendation_GetRatings_FullMethodName         = "/recommendation.Recommendation/GetRatings"
+	Recommendation_AddHotel_FullMethodName           = "/recommendation.Recommendation/AddHotel"
 )
 
 // RecommendationClient is the client API for Recommendation service.
@@ -28,6 +30,10 @@ const (
 type RecommendationClient interface {
 	// GetRecommendations returns recommended hotels for a given requirement
 	GetRecommendations(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
+	// GetRatings returns the rating of each known hotel
+	GetRatings(ctx context.Context, in *RatingRequest, opts ...grpc.CallOption) (*RatingResult, error)
+	// AddHotel creates or replaces the recommendation record of a hotel
+	AddHotel(ctx context.Context, in *Hotel, opts ...grpc.CallOption) (*Result, error)
 }
 
 type recommendationClient struct {
@@ -47,12 +53,34 @@ func (c *recommendationClient) GetRecommendations(ctx context.Context, in *Reque
 	return out, nil
 }
 
+func (c *recommendationClient) GetRatings(ctx context.Context, in *RatingRequest, opts ...grpc.CallOption) (*RatingResult, error) {
+	out := new(RatingResult)
+	err := c.cc.Invoke(ctx, Recommendation_GetRatings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *recommendationClient) AddHotel(ctx context.Context, in *Hotel, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, Recommendation_AddHotel_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RecommendationServer is the server API for Recommendation service.
 // All implementations must embed UnimplementedRecommendationServer
 // for forward compatibility
 type RecommendationServer interface {
 	// GetRecommendations returns recommended hotels for a given requirement
 	GetRecommendations(context.Context, *Request) (*Result, error)
+	// GetRatings returns the rating of each known hotel
+	GetRatings(context.Context, *RatingRequest) (*RatingResult, error)
+	// AddHotel creates or replaces the recommendation record of a hotel
+	AddHotel(context.Context, *Hotel) (*Result, error)
 	mustEmbedUnimplementedRecommendationServer()
 }
 
@@ -63,6 +91,12 @@ type UnimplementedRecommendationServer struct {
 func (UnimplementedRecommendationServer) GetRecommendations(context.Context, *Request) (*Result, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRecommendations not implemented")
 }
+func (UnimplementedRecommendationServer) GetRatings(context.Context, *RatingRequest) (*RatingResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRatings not implemented")
+}
+func (UnimplementedRecommendationServer) AddHotel(context.Context, *Hotel) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddHotel not implemented")
+}
 func (UnimplementedRecommendationServer) mustEmbedUnimplementedRecommendationServer() {}
 
 // UnsafeRecommendationServer may be embedded to opt out of forward compatibility for this service.
@@ -94,6 +128,42 @@ func _Recommendation_GetRecommendations_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Recommendation_GetRatings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RatingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RecommendationServer).GetRatings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Recommendation_GetRatings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RecommendationServer).GetRatings(ctx, req.(*RatingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Recommendation_AddHotel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Hotel)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RecommendationServer).AddHotel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Recommendation_AddHotel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RecommendationServer).AddHotel(ctx, req.(*Hotel))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Recommendation_ServiceDesc is the grpc.ServiceDesc for Recommendation service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -105,6 +175,14 @@ var Recommendation_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetRecommendations",
 			Handler:    _Recommendation_GetRecommendations_Handler,
 		},
+		{
+			MethodName: "GetRatings",
+			Handler:    _Recommendation_GetRatings_Handler,
+		},
+		{
+			MethodName: "AddHotel",
+			Handler:    _Recommendation_AddHotel_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "services/recommendation/proto/recommendation.proto",
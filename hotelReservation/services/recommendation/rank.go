@@ -0,0 +1,205 @@
+package recommendation
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+
+	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/recommendation/proto"
+	"github.com/hailocab/go-geoindex"
+)
+
+// criteria a hotel can be ranked by, in the order of candidate.values
+var criteriaNames = [...]string{"dis", "rate", "price", "similar"}
+
+const (
+	criterionDistance = iota
+	criterionRate
+	criterionPrice
+	criterionSimilar
+	numCriteria
+)
+
+// maxRecommendations caps the k of a request
+const maxRecommendations = 1000
+
+type candidate struct {
+	hotelId string
+	// raw distance (km), rate, price and similarity
+	values [numCriteria]float64
+	score  float64
+}
+
+// criteriaWeights returns the weight of each criterion, normalised to sum to 1
+func criteriaWeights(criteria []*pb.Criterion) ([numCriteria]float64, error) {
+	var weights [numCriteria]float64
+	total := 0.0
+	for _, c := range criteria {
+		i := criterionIndex(c.Name)
+		if i < 0 {
+			return weights, fmt.Errorf("unknown criterion %q", c.Name)
+		}
+		if c.Weight < 0 {
+			return weights, fmt.Errorf("negative weight for criterion %q", c.Name)
+		}
+		weights[i] += c.Weight
+		total += c.Weight
+	}
+	if total == 0 {
+		return weights, fmt.Errorf("at least one criterion needs a positive weight")
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+	return weights, nil
+}
+
+func criterionIndex(name string) int {
+	for i, n := range criteriaNames {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// distanceKm returns the distance between a hotel and a location
+func distanceKm(hotel Hotel, lat, lon float64) float64 {
+	return float64(geoindex.Distance(
+		&geoindex.GeoPoint{Plat: lat, Plon: lon},
+		&geoindex.GeoPoint{Plat: hotel.HLat, Plon: hotel.HLon},
+	)) / 1000
+}
+
+// score sets the score of every candidate to the weighted sum of its
+// criteria, each scaled to [0, 1] over all candidates with 1 the best value.
+// Lower distance and price are better, higher rate is better.
+func score(cands []candidate, weights [numCriteria]float64) {
+	if len(cands) == 0 {
+		return
+	}
+	var min, max [numCriteria]float64
+	min, max = cands[0].values, cands[0].values
+	for _, c := range cands[1:] {
+		for i, v := range c.values {
+			if v < min[i] {
+				min[i] = v
+			}
+			if v > max[i] {
+				max[i] = v
+			}
+		}
+	}
+
+	for j := range cands {
+		s := 0.0
+		for i, v := range cands[j].values {
+			if weights[i] == 0 {
+				continue
+			}
+			norm := 1.0
+			if max[i] > min[i] {
+				if i == criterionRate || i == criterionSimilar {
+					norm = (v - min[i]) / (max[i] - min[i])
+				} else {
+					norm = (max[i] - v) / (max[i] - min[i])
+				}
+			}
+			s += weights[i] * norm
+		}
+		cands[j].score = s
+	}
+}
+
+// better orders candidates by descending score, then by hotel id so the
+// ranking is stable
+func better(a, b candidate) bool {
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	return a.hotelId < b.hotelId
+}
+
+// worstFirst is a heap holding the worst kept candidate at its root
+type worstFirst []candidate
+
+func (h worstFirst) Len() int            { return len(h) }
+func (h worstFirst) Less(i, j int) bool  { return better(h[j], h[i]) }
+func (h worstFirst) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *worstFirst) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *worstFirst) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// topK returns the k best candidates, best first, in O(n log k)
+func topK(cands []candidate, k int) []candidate {
+	h := make(worstFirst, 0, k)
+	for _, c := range cands {
+		if len(h) < k {
+			heap.Push(&h, c)
+		} else if better(c, h[0]) {
+			h[0] = c
+			heap.Fix(&h, 0)
+		}
+	}
+	sort.Slice(h, func(i, j int) bool { return better(h[i], h[j]) })
+	return h
+}
+
+// bestTies returns every candidate sharing the best score
+func bestTies(cands []candidate) []candidate {
+	var best []candidate
+	for _, c := range cands {
+		if len(best) == 0 || c.score > best[0].score {
+			best = append(best[:0], c)
+		} else if c.score == best[0].score {
+			best = append(best, c)
+		}
+	}
+	sort.Slice(best, func(i, j int) bool { return better(best[i], best[j]) })
+	return best
+}
+
+// similarity sets the similarity value of every candidate from how far its
+// price is outside the band of booked prices and how far it is from the
+// nearest booked hotel, both scaled by the largest gap among the candidates.
+// Hotels matching the booking history score 1, the least similar 0.
+func similarity(cands []candidate, hotels map[string]Hotel, booked []Hotel) {
+	if len(booked) == 0 {
+		return
+	}
+	minPrice, maxPrice := booked[0].HPrice, booked[0].HPrice
+	for _, b := range booked[1:] {
+		minPrice = math.Min(minPrice, b.HPrice)
+		maxPrice = math.Max(maxPrice, b.HPrice)
+	}
+
+	priceGaps := make([]float64, len(cands))
+	geoGaps := make([]float64, len(cands))
+	maxPriceGap, maxGeoGap := 0.0, 0.0
+	for j, c := range cands {
+		hotel := hotels[c.hotelId]
+		priceGaps[j] = math.Max(0, math.Max(minPrice-hotel.HPrice, hotel.HPrice-maxPrice))
+		geoGaps[j] = math.MaxFloat64
+		for _, b := range booked {
+			geoGaps[j] = math.Min(geoGaps[j], distanceKm(hotel, b.HLat, b.HLon))
+		}
+		maxPriceGap = math.Max(maxPriceGap, priceGaps[j])
+		maxGeoGap = math.Max(maxGeoGap, geoGaps[j])
+	}
+
+	for j := range cands {
+		gap := 0.0
+		if maxPriceGap > 0 {
+			gap += priceGaps[j] / maxPriceGap
+		}
+		if maxGeoGap > 0 {
+			gap += geoGaps[j] / maxGeoGap
+		}
+		cands[j].values[criterionSimilar] = 1 - gap/2
+	}
+}
@@ -0,0 +1,359 @@
+package recommendation
+
+import (
+	"math"
+	"testing"
+
+	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/recommendation/proto"
+)
+
+const epsilon = 1e-9
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < epsilon
+}
+
+func hotelIds(cands []candidate) []string {
+	out := make([]string, len(cands))
+	for i, c := range cands {
+		out[i] = c.hotelId
+	}
+	return out
+}
+
+func sameIds(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCriteriaWeights(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria []*pb.Criterion
+		want     [numCriteria]float64
+		wantErr  bool
+	}{
+		{
+			name:     "single criterion",
+			criteria: []*pb.Criterion{{Name: "price", Weight: 3}},
+			want:     [numCriteria]float64{criterionPrice: 1},
+		},
+		{
+			name:     "normalised to sum to 1",
+			criteria: []*pb.Criterion{{Name: "dis", Weight: 2}, {Name: "rate", Weight: 1}, {Name: "price", Weight: 1}},
+			want:     [numCriteria]float64{criterionDistance: 0.5, criterionRate: 0.25, criterionPrice: 0.25},
+		},
+		{
+			name:     "repeated criterion adds up",
+			criteria: []*pb.Criterion{{Name: "rate", Weight: 1}, {Name: "rate", Weight: 2}, {Name: "similar", Weight: 1}},
+			want:     [numCriteria]float64{criterionRate: 0.75, criterionSimilar: 0.25},
+		},
+		{
+			name:     "zero weight is kept at zero",
+			criteria: []*pb.Criterion{{Name: "dis", Weight: 0}, {Name: "rate", Weight: 4}},
+			want:     [numCriteria]float64{criterionRate: 1},
+		},
+		{
+			name:     "unknown criterion",
+			criteria: []*pb.Criterion{{Name: "stars", Weight: 1}},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown criterion among known ones",
+			criteria: []*pb.Criterion{{Name: "dis", Weight: 1}, {Name: "Dis", Weight: 1}},
+			wantErr:  true,
+		},
+		{
+			name:     "negative weight",
+			criteria: []*pb.Criterion{{Name: "dis", Weight: 2}, {Name: "price", Weight: -1}},
+			wantErr:  true,
+		},
+		{
+			name:     "all weights zero",
+			criteria: []*pb.Criterion{{Name: "dis", Weight: 0}},
+			wantErr:  true,
+		},
+		{
+			name:    "no criteria",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := criteriaWeights(tt.criteria)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for i := range got {
+				if !near(got[i], tt.want[i]) {
+					t.Errorf("weight of %s: got %v, want %v", criteriaNames[i], got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  [][numCriteria]float64
+		weights [numCriteria]float64
+		want    []float64
+	}{
+		{
+			name:    "lower distance is better",
+			values:  [][numCriteria]float64{{criterionDistance: 1}, {criterionDistance: 3}, {criterionDistance: 2}},
+			weights: [numCriteria]float64{criterionDistance: 1},
+			want:    []float64{1, 0, 0.5},
+		},
+		{
+			name:    "higher rate is better",
+			values:  [][numCriteria]float64{{criterionRate: 3}, {criterionRate: 5}, {criterionRate: 4}},
+			weights: [numCriteria]float64{criterionRate: 1},
+			want:    []float64{0, 1, 0.5},
+		},
+		{
+			name:    "lower price is better",
+			values:  [][numCriteria]float64{{criterionPrice: 100}, {criterionPrice: 300}},
+			weights: [numCriteria]float64{criterionPrice: 1},
+			want:    []float64{1, 0},
+		},
+		{
+			name:    "higher similarity is better",
+			values:  [][numCriteria]float64{{criterionSimilar: 0.2}, {criterionSimilar: 1}},
+			weights: [numCriteria]float64{criterionSimilar: 1},
+			want:    []float64{0, 1},
+		},
+		{
+			name: "weighted sum",
+			values: [][numCriteria]float64{
+				{criterionDistance: 1, criterionRate: 3},
+				{criterionDistance: 2, criterionRate: 5},
+			},
+			weights: [numCriteria]float64{criterionDistance: 0.75, criterionRate: 0.25},
+			want:    []float64{0.75, 0.25},
+		},
+		{
+			name:    "all values equal score as the best",
+			values:  [][numCriteria]float64{{criterionPrice: 150}, {criterionPrice: 150}, {criterionPrice: 150}},
+			weights: [numCriteria]float64{criterionPrice: 1},
+			want:    []float64{1, 1, 1},
+		},
+		{
+			name: "criterion with all values equal adds its full weight",
+			values: [][numCriteria]float64{
+				{criterionDistance: 1, criterionRate: 4},
+				{criterionDistance: 3, criterionRate: 4},
+			},
+			weights: [numCriteria]float64{criterionDistance: 0.5, criterionRate: 0.5},
+			want:    []float64{1, 0.5},
+		},
+		{
+			name:    "single candidate",
+			values:  [][numCriteria]float64{{criterionDistance: 7, criterionPrice: 90}},
+			weights: [numCriteria]float64{criterionDistance: 0.5, criterionPrice: 0.5},
+			want:    []float64{1},
+		},
+		{
+			name:    "unweighted criteria are ignored",
+			values:  [][numCriteria]float64{{criterionDistance: 1, criterionPrice: 300}, {criterionDistance: 2, criterionPrice: 100}},
+			weights: [numCriteria]float64{criterionDistance: 1},
+			want:    []float64{1, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cands := make([]candidate, len(tt.values))
+			for i, v := range tt.values {
+				cands[i].values = v
+			}
+			score(cands, tt.weights)
+			for i, c := range cands {
+				if !near(c.score, tt.want[i]) {
+					t.Errorf("candidate %d: got score %v, want %v", i, c.score, tt.want[i])
+				}
+			}
+		})
+	}
+
+	// no candidates is a no-op
+	score(nil, [numCriteria]float64{criterionDistance: 1})
+}
+
+func TestTopK(t *testing.T) {
+	cands := []candidate{
+		{hotelId: "4", score: 0.5},
+		{hotelId: "2", score: 0.9},
+		{hotelId: "3", score: 0.5},
+		{hotelId: "1", score: 0.2},
+		{hotelId: "5", score: 0.9},
+	}
+
+	tests := []struct {
+		name string
+		k    int
+		want []string
+	}{
+		{"best first, ties by hotel id", 5, []string{"2", "5", "3", "4", "1"}},
+		{"cut inside a tie keeps the lower id", 3, []string{"2", "5", "3"}},
+		{"single best", 1, []string{"2"}},
+		{"k larger than the candidates", 10, []string{"2", "5", "3", "4", "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := make([]candidate, len(cands))
+			copy(in, cands)
+			if got := hotelIds(topK(in, tt.k)); !sameIds(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := topK(nil, 3); len(got) != 0 {
+		t.Errorf("got %v for no candidates", hotelIds(got))
+	}
+}
+
+func TestBestTies(t *testing.T) {
+	tests := []struct {
+		name  string
+		cands []candidate
+		want  []string
+	}{
+		{
+			name:  "single best",
+			cands: []candidate{{hotelId: "1", score: 0.4}, {hotelId: "2", score: 0.8}, {hotelId: "3", score: 0.1}},
+			want:  []string{"2"},
+		},
+		{
+			name: "ties sorted by hotel id",
+			cands: []candidate{
+				{hotelId: "9", score: 1}, {hotelId: "3", score: 0.5}, {hotelId: "10", score: 1}, {hotelId: "4", score: 1},
+			},
+			want: []string{"10", "4", "9"},
+		},
+		{
+			name:  "better score after a tie replaces it",
+			cands: []candidate{{hotelId: "1", score: 0.5}, {hotelId: "2", score: 0.5}, {hotelId: "3", score: 0.7}},
+			want:  []string{"3"},
+		},
+		{
+			name:  "all equal",
+			cands: []candidate{{hotelId: "2", score: 1}, {hotelId: "1", score: 1}},
+			want:  []string{"1", "2"},
+		},
+		{
+			name: "no candidates",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hotelIds(bestTies(tt.cands)); !sameIds(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	hotels := map[string]Hotel{
+		"booked1": {HId: "booked1", HLat: 37.78, HLon: -122.41, HPrice: 100},
+		"booked2": {HId: "booked2", HLat: 37.80, HLon: -122.41, HPrice: 200},
+		// inside the booked band, next to a booked hotel
+		"inBand": {HId: "inBand", HLat: 37.80, HLon: -122.41, HPrice: 150},
+		// half the largest price gap below the band, next to a booked hotel
+		"cheap": {HId: "cheap", HLat: 37.78, HLon: -122.41, HPrice: 50},
+		// largest price gap, farthest away
+		"far": {HId: "far", HLat: 37.90, HLon: -122.41, HPrice: 300},
+		// inside the band, halfway to the farthest hotel
+		"between": {HId: "between", HLat: 37.85, HLon: -122.41, HPrice: 120},
+	}
+	booked := []Hotel{hotels["booked1"], hotels["booked2"]}
+
+	geoGap := func(id string) float64 {
+		h := hotels[id]
+		return math.Min(distanceKm(h, 37.78, -122.41), distanceKm(h, 37.80, -122.41))
+	}
+
+	tests := []struct {
+		name   string
+		ids    []string
+		booked []Hotel
+		want   []float64
+	}{
+		{
+			name:   "no booking history",
+			ids:    []string{"inBand", "far"},
+			booked: nil,
+			want:   []float64{0, 0},
+		},
+		{
+			name:   "booked hotels match themselves",
+			ids:    []string{"booked1", "booked2", "far"},
+			booked: booked,
+			want:   []float64{1, 1, 0},
+		},
+		{
+			name:   "price inside the booked band",
+			ids:    []string{"inBand", "far"},
+			booked: booked,
+			want:   []float64{1, 0},
+		},
+		{
+			name:   "price and geo gaps at their maximum",
+			ids:    []string{"far", "cheap"},
+			booked: booked,
+			want:   []float64{0, 0.75},
+		},
+		{
+			name:   "geo gap scaled by the largest",
+			ids:    []string{"between", "far"},
+			booked: booked,
+			want:   []float64{1 - geoGap("between")/geoGap("far")/2, 0},
+		},
+		{
+			name:   "every candidate matches",
+			ids:    []string{"inBand", "booked1"},
+			booked: booked,
+			want:   []float64{1, 1},
+		},
+		{
+			name:   "single booking is a band of one price",
+			ids:    []string{"booked1", "cheap", "inBand"},
+			booked: booked[:1],
+			// inBand is now as far above the band as cheap is below and
+			// the farthest candidate
+			want: []float64{1, 0.5, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cands := make([]candidate, len(tt.ids))
+			for i, id := range tt.ids {
+				cands[i].hotelId = id
+			}
+			similarity(cands, hotels, tt.booked)
+			for i, c := range cands {
+				if got := c.values[criterionSimilar]; !near(got, tt.want[i]) {
+					t.Errorf("%s: got similarity %v, want %v", c.hotelId, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -3,43 +3,70 @@ package recommendation
 import (
 	"context"
 	"fmt"
-	"math"
 	"net"
+	"sync"
 	"time"
 
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/dialer"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
 	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/recommendation/proto"
+	reservation "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/reservation/proto"
+	review "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/review/proto"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 "github.com/rs/zerolog"
 	"github.com/google/uuid"
-	"github.com/hailocab/go-geoindex"
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 )
 
 const name = "srv-recommendation"
 
+const (
+	// RatingsStatic ranks hotels by the rate seeded into recommendation-db
+	RatingsStatic = "static"
+	// RatingsReview ranks hotels by the mean rating of their reviews
+	RatingsReview = "review"
+)
+
 // Server implements the recommendation service
 type Server struct {
 	pb.UnimplementedRecommendationServer
 
-	hotels map[string]Hotel
-	uuid   string
+	// hotelsMu guards hotels, which AddHotel updates at runtime
+	hotelsMu   sync.RWMutex
+	hotels     map[string]Hotel
+	uuid       string
+	grpcServer *grpc.Server
+	listener   net.Listener
+	health     *health.Server
+
+	reviewClient      review.ReviewClient
+	reservationClient reservation.ReservationClient
 
 	Tracer      trace.Tracer
 	Port        int
 	IpAddr      string
 	MongoClient *mongo.Client
-	Registry    *registry.Client
+	Registry    registry.Registry
+	// RatingSource is RatingsStatic (default) or RatingsReview
+	RatingSource string
 }
 
-// Run starts the server
-func (s *Server) Run() error {
+// Setup creates the server, listens on Port and registers in consul. It is
+// called before Run, so Shutdown only reads what Setup wrote.
+func (s *Server) Setup() error {
 	if s.Port == 0 {
 		return fmt.Errorf("server port must be set")
 	}
@@ -48,6 +75,20 @@ func (s *Server) Run() error {
 		s.hotels = loadRecommendations(s.MongoClient)
 	}
 
+	switch s.RatingSource {
+	case "", RatingsStatic:
+	case RatingsReview:
+		if err := s.initReviewClient("srv-review"); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown rating source %q", s.RatingSource)
+	}
+
+	if err := s.initReservationClient("srv-reservation"); err != nil {
+		return err
+	}
+
 	s.uuid = uuid.New().String()
 
 	opts := []grpc.ServerOption{
@@ -58,15 +99,22 @@ func (s *Server) Run() error {
 			PermitWithoutStream: true,
 		}),
 		grpc.UnaryInterceptor(
-			otelgrpc.UnaryServerInterceptor(),
+			otelgrpc.UnaryServerInterceptor(
+				otelgrpc.WithInterceptorFilter(filters.Not(filters.HealthCheck())),
+			),
 		),
 	}
 
 	if tlsopt := tls.GetServerOpt(); tlsopt != nil {
-		opts = append(opts, tlsopt)
+		opts = append(opts, tlsopt, grpc.ChainUnaryInterceptor(tls.UnaryServerInterceptor()))
 	}
 
 	srv := grpc.NewServer(opts...)
+	s.grpcServer = srv
+
+	// answers the consul check, serving until Shutdown
+	s.health = health.NewServer()
+	healthpb.RegisterHealthServer(srv, s.health)
 
 	pb.RegisterRecommendationServer(srv, s)
 
@@ -81,15 +129,59 @@ func (s *Server) Run() error {
 	}
 	log.Info().Msg("Successfully registered in consul")
 
-	return srv.Serve(lis)
+	s.listener = lis
+	return nil
 }
 
-// Shutdown cleans up any processes
-func (s *Server) Shutdown() {
-	s.Registry.Deregister(s.uuid)
+// Run serves the server set up by Setup until Shutdown
+func (s *Server) Run() error {
+	return s.grpcServer.Serve(s.listener)
 }
 
-// GiveRecommendation returns recommendations within a given requirement.
+// Shutdown fails the health check and deregisters the service so no new
+// calls are routed here, then waits for pending calls until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+	err := s.Registry.Deregister(s.uuid)
+	shutdown.GRPC(ctx, s.grpcServer)
+	return err
+}
+
+func (s *Server) initReviewClient(name string) error {
+	conn, err := s.getGprcConn(name)
+	if err != nil {
+		return fmt.Errorf("dialer error: %v", err)
+	}
+	s.reviewClient = review.NewReviewClient(conn)
+	return nil
+}
+
+func (s *Server) initReservationClient(name string) error {
+	conn, err := s.getGprcConn(name)
+	if err != nil {
+		return fmt.Errorf("dialer error: %v", err)
+	}
+	s.reservationClient = reservation.NewReservationClient(conn)
+	return nil
+}
+
+func (s *Server) getGprcConn(name string) (*grpc.ClientConn, error) {
+	target, err := s.Registry.Target(name)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.Dial(
+		target,
+		dialer.WithTracer(s.Tracer),
+		dialer.WithBalancer(""),
+		dialer.WithEnvPolicies(),
+	)
+}
+
+// GetRecommendations returns the hotels scoring best on the weighted
+// criteria of the request, best first.
 func (s *Server) GetRecommendations(ctx context.Context, req *pb.Request) (*pb.Result, error) {
 	// Get logger with trace context
 	logger := zerolog.Ctx(ctx)
@@ -113,70 +205,200 @@ func (s *Server) GetRecommendations(ctx context.Context, req *pb.Request) (*pb.R
 	}
 	
 	res := new(pb.Result)
-	require := req.Require
-	
-	// Log the recommendation request with context
-	logger.Info().Msgf("Processing recommendation request: require_type=%s, lat=%v, lon=%v", require, req.Lat, req.Lon)
-	
-	if require == "dis" {
-		p1 := &geoindex.GeoPoint{
-			Pid:  "",
-			Plat: req.Lat,
-			Plon: req.Lon,
+
+	criteria := req.Criteria
+	if len(criteria) == 0 && !(req.Require == "" && req.Username != "") {
+		if i := criterionIndex(req.Require); i < 0 || i == criterionSimilar {
+			logger.Warn().Msgf("Invalid recommendation requirement parameter: require_type=%s", req.Require)
+			return res, nil
 		}
-		min := math.MaxFloat64
-		for _, hotel := range s.hotels {
-			tmp := float64(geoindex.Distance(p1, &geoindex.GeoPoint{
-				Pid:  "",
-				Plat: hotel.HLat,
-				Plon: hotel.HLon,
-			})) / 1000
-			if tmp < min {
-				min = tmp
+		criteria = []*pb.Criterion{{Name: req.Require, Weight: 1}}
+	}
+	if req.Username != "" && !hasCriterion(criteria, "similar") {
+		criteria = append(criteria, &pb.Criterion{Name: "similar", Weight: 1})
+	}
+	weights, err := criteriaWeights(criteria)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if weights[criterionSimilar] > 0 && req.Username == "" {
+		return nil, status.Error(codes.InvalidArgument, "the similar criterion needs a username")
+	}
+	if req.K < 0 || req.Radius < 0 {
+		return nil, status.Error(codes.InvalidArgument, "k and radius must not be negative")
+	}
+	k := int(req.K)
+	if k > maxRecommendations {
+		k = maxRecommendations
+	}
+
+	// Log the recommendation request with context
+	logger.Info().Msgf("Processing recommendation request: criteria=%v, k=%d, radius=%v, lat=%v, lon=%v", weights, k, req.Radius, req.Lat, req.Lon)
+
+	var ratings map[string]float64
+	if weights[criterionRate] > 0 {
+		ratings = s.hotelRatings(ctx, nil)
+	}
+	var bookedIds []string
+	if weights[criterionSimilar] > 0 {
+		bookedIds = s.bookedHotels(ctx, req.Username)
+	}
+
+	s.hotelsMu.RLock()
+	cands := make([]candidate, 0, len(s.hotels))
+	for _, hotel := range s.hotels {
+		c := candidate{hotelId: hotel.HId}
+		if weights[criterionDistance] > 0 || req.Radius > 0 {
+			c.values[criterionDistance] = distanceKm(hotel, req.Lat, req.Lon)
+			if req.Radius > 0 && c.values[criterionDistance] > req.Radius {
+				continue
 			}
 		}
-		for _, hotel := range s.hotels {
-			tmp := float64(geoindex.Distance(p1, &geoindex.GeoPoint{
-				Pid:  "",
-				Plat: hotel.HLat,
-				Plon: hotel.HLon,
-			})) / 1000
-			if tmp == min {
-				res.HotelIds = append(res.HotelIds, hotel.HId)
+		c.values[criterionRate] = ratings[hotel.HId]
+		c.values[criterionPrice] = hotel.HPrice
+		cands = append(cands, c)
+	}
+	if len(bookedIds) > 0 {
+		booked := make([]Hotel, 0, len(bookedIds))
+		for _, id := range bookedIds {
+			if hotel, ok := s.hotels[id]; ok {
+				booked = append(booked, hotel)
 			}
 		}
-		logger.Debug().Msgf("Distance-based recommendations completed: require_type=distance, results_count=%d, min_distance_km=%v", len(res.HotelIds), min)
-	} else if require == "rate" {
-		max := 0.0
-		for _, hotel := range s.hotels {
-			if hotel.HRate > max {
-				max = hotel.HRate
-			}
+		similarity(cands, s.hotels, booked)
+	}
+	s.hotelsMu.RUnlock()
+
+	score(cands, weights)
+
+	var ranked []candidate
+	if k == 0 {
+		ranked = bestTies(cands)
+	} else {
+		ranked = topK(cands, k)
+	}
+	for _, c := range ranked {
+		res.HotelIds = append(res.HotelIds, c.hotelId)
+		res.Ranked = append(res.Ranked, &pb.Ranked{HotelId: c.hotelId, Score: c.score})
+	}
+
+	logger.Debug().Msgf("Recommendations completed: candidates=%d, booked_hotels=%d, results_count=%d", len(cands), len(bookedIds), len(res.HotelIds))
+	return res, nil
+}
+
+// GetRatings returns the rating of each requested hotel. Unknown hotels are
+// left out of the result.
+func (s *Server) GetRatings(ctx context.Context, req *pb.RatingRequest) (*pb.RatingResult, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	res := &pb.RatingResult{Ratings: make(map[string]float64, len(req.HotelIds))}
+	if len(req.HotelIds) > 0 {
+		res.Ratings = s.hotelRatings(ctx, req.HotelIds)
+	}
+
+	logger.Debug().Msgf("Ratings lookup completed: requested=%d, found=%d", len(req.HotelIds), len(res.Ratings))
+	return res, nil
+}
+
+// bookedHotels returns the distinct hotels a user has reservations at. Without
+// a reachable reservation service the ranking is not personalised.
+func (s *Server) bookedHotels(ctx context.Context, username string) []string {
+	bookings, err := s.reservationClient.GetReservationsByCustomer(ctx, &reservation.CustomerRequest{CustomerName: username})
+	if err != nil {
+		log.Warn().Msgf("Failed to get booking history, not personalising: username=%s, error=%v", username, err)
+		return nil
+	}
+	seen := make(map[string]bool)
+	var hotelIds []string
+	for _, b := range bookings.Bookings {
+		if !seen[b.HotelId] {
+			seen[b.HotelId] = true
+			hotelIds = append(hotelIds, b.HotelId)
 		}
-		for _, hotel := range s.hotels {
-			if hotel.HRate == max {
-				res.HotelIds = append(res.HotelIds, hotel.HId)
-			}
+	}
+	return hotelIds
+}
+
+func hasCriterion(criteria []*pb.Criterion, name string) bool {
+	for _, c := range criteria {
+		if c.Name == name {
+			return true
 		}
-		logger.Debug().Msgf("Rate-based recommendations completed: require_type=rate, results_count=%d, max_rate=%v", len(res.HotelIds), max)
-	} else if require == "price" {
-		min := math.MaxFloat64
-		for _, hotel := range s.hotels {
-			if hotel.HPrice < min {
-				min = hotel.HPrice
+	}
+	return false
+}
+
+// hotelRatings returns the rating of the given hotels, or of every hotel if
+// hotelIds is nil. With RatingsReview it is the mean review rating and hotels
+// without reviews are left out; if the review service cannot be reached the
+// static rates are used instead. Must not be called with hotelsMu held.
+func (s *Server) hotelRatings(ctx context.Context, hotelIds []string) map[string]float64 {
+	if s.RatingSource == RatingsReview {
+		summaries, err := s.reviewClient.GetRatingSummary(ctx, &review.RatingSummaryRequest{HotelIds: hotelIds})
+		if err == nil {
+			ratings := make(map[string]float64, len(summaries.Summaries))
+			for id, summary := range summaries.Summaries {
+				ratings[id] = summary.Mean
 			}
+			return ratings
 		}
-		for _, hotel := range s.hotels {
-			if hotel.HPrice == min {
-				res.HotelIds = append(res.HotelIds, hotel.HId)
-			}
+		log.Warn().Msgf("Failed to get review ratings, using static rates: error=%v", err)
+	}
+
+	s.hotelsMu.RLock()
+	defer s.hotelsMu.RUnlock()
+
+	ratings := make(map[string]float64)
+	if hotelIds == nil {
+		for id, hotel := range s.hotels {
+			ratings[id] = hotel.HRate
+		}
+		return ratings
+	}
+	for _, id := range hotelIds {
+		if hotel, ok := s.hotels[id]; ok {
+			ratings[id] = hotel.HRate
 		}
-		logger.Debug().Msgf("Price-based recommendations completed: require_type=price, results_count=%d, min_price=%v", len(res.HotelIds), min)
-	} else {
-		logger.Warn().Msgf("Invalid recommendation requirement parameter: require_type=%s", require)
 	}
+	return ratings
+}
 
-	return res, nil
+// AddHotel creates or replaces the recommendation record of a hotel
+func (s *Server) AddHotel(ctx context.Context, req *pb.Hotel) (*pb.Result, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	if req.HotelId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "hotelId is required")
+	}
+
+	hotel := Hotel{
+		HId:    req.HotelId,
+		HLat:   req.Lat,
+		HLon:   req.Lon,
+		HRate:  req.Rate,
+		HPrice: req.Price,
+	}
+	collection := s.MongoClient.Database("recommendation-db").Collection("recommendation")
+	_, err := collection.ReplaceOne(ctx, bson.M{"hotelId": hotel.HId}, hotel, options.Replace().SetUpsert(true))
+	if err != nil {
+		logger.Error().Msgf("Failed to store recommendation record: hotel_id=%s, error=%v", hotel.HId, err)
+		return nil, err
+	}
+
+	s.hotelsMu.Lock()
+	s.hotels[hotel.HId] = hotel
+	s.hotelsMu.Unlock()
+
+	logger.Info().Msgf("Recommendation record stored: hotel_id=%s", hotel.HId)
+	return &pb.Result{HotelIds: []string{hotel.HId}}, nil
 }
 
 // loadRecommendations loads hotel recommendations from mongodb.
@@ -20,35 +20,566 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// FailureReason tells why MakeReservation did not book anything.
+type FailureReason int32
+
+const (
+	FailureReason_NONE          FailureReason = 0
+	FailureReason_NO_CAPACITY   FailureReason = 1
+	FailureReason_INVALID_DATES FailureReason = 2
+	FailureReason_UNKNOWN_HOTEL FailureReason = 3
+)
+
+// Enum value maps for FailureReason.
+var (
+	FailureReason_name = map[int32]string{
+		0: "NONE",
+		1: "NO_CAPACITY",
+		2: "INVALID_DATES",
+		3: "UNKNOWN_HOTEL",
+	}
+	FailureReason_value = map[string]int32{
+		"NONE":          0,
+		"NO_CAPACITY":   1,
+		"INVALID_DATES": 2,
+		"UNKNOWN_HOTEL": 3,
+	}
+)
+
+func (x FailureReason) Enum() *FailureReason {
+	p := new(FailureReason)
+	*p = x
+	return p
+}
+
+func (x FailureReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FailureReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_services_reservation_proto_reservation_proto_enumTypes[0].Descriptor()
+}
+
+func (FailureReason) Type() protoreflect.EnumType {
+	return &file_services_reservation_proto_reservation_proto_enumTypes[0]
+}
+
+func (x FailureReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FailureReason.Descriptor instead.
+func (FailureReason) EnumDescriptor() ([]byte, []int) {
+	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{0}
+}
+
 type Request struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	CustomerName string   `protobuf:"bytes,1,opt,name=customerName,proto3" json:"customerName,omitempty"`
-	HotelId      []string `protobuf:"bytes,2,rep,name=hotelId,proto3" json:"hotelId,omitempty"`
-	InDate       string   `protobuf:"bytes,3,opt,name=inDate,proto3" json:"inDate,omitempty"`
-	OutDate      string   `protobuf:"bytes,4,opt,name=outDate,proto3" json:"outDate,omitempty"`
-	RoomNumber   int32    `protobuf:"varint,5,opt,name=roomNumber,proto3" json:"roomNumber,omitempty"`
+	CustomerName string   `protobuf:"bytes,1,opt,name=customerName,proto3" json:"customerName,omitempty"`
+	HotelId      []string `protobuf:"bytes,2,rep,name=hotelId,proto3" json:"hotelId,omitempty"`
+	InDate       string   `protobuf:"bytes,3,opt,name=inDate,proto3" json:"inDate,omitempty"`
+	OutDate      string   `protobuf:"bytes,4,opt,name=outDate,proto3" json:"outDate,omitempty"`
+	RoomNumber   int32    `protobuf:"varint,5,opt,name=roomNumber,proto3" json:"roomNumber,omitempty"`
+}
+
+func (x *Request) Reset() {
+	*x = Request{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_reservation_proto_reservation_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Request) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Request) ProtoMessage() {}
+
+func (x *Request) ProtoReflect() protoreflect.Message {
+	mi := &file_services_reservation_proto_reservation_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Request.ProtoReflect.Descriptor instead.
+func (*Request) Descriptor() ([]byte, []int) {
+	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Request) GetCustomerName() string {
+	if x != nil {
+		return x.CustomerName
+	}
+	return ""
+}
+
+func (x *Request) GetHotelId() []string {
+	if x != nil {
+		return x.HotelId
+	}
+	return nil
+}
+
+func (x *Request) GetInDate() string {
+	if x != nil {
+		return x.InDate
+	}
+	return ""
+}
+
+func (x *Request) GetOutDate() string {
+	if x != nil {
+		return x.OutDate
+	}
+	return ""
+}
+
+func (x *Request) GetRoomNumber() int32 {
+	if x != nil {
+		return x.RoomNumber
+	}
+	return 0
+}
+
+type Result struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	HotelId []string `protobuf:"bytes,1,rep,name=hotelId,proto3" json:"hotelId,omitempty"`
+	// the fields below are only set by MakeReservation
+	ReservationId string        `protobuf:"bytes,2,opt,name=reservationId,proto3" json:"reservationId,omitempty"`
+	Nights        []*Night      `protobuf:"bytes,3,rep,name=nights,proto3" json:"nights,omitempty"`
+	TotalPrice    float64       `protobuf:"fixed64,4,opt,name=totalPrice,proto3" json:"totalPrice,omitempty"`
+	Failure       FailureReason `protobuf:"varint,5,opt,name=failure,proto3,enum=reservation.FailureReason" json:"failure,omitempty"`
+}
+
+func (x *Result) Reset() {
+	*x = Result{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_reservation_proto_reservation_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Result) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Result) ProtoMessage() {}
+
+func (x *Result) ProtoReflect() protoreflect.Message {
+	mi := &file_services_reservation_proto_reservation_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Result.ProtoReflect.Descriptor instead.
+func (*Result) Descriptor() ([]byte, []int) {
+	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Result) GetHotelId() []string {
+	if x != nil {
+		return x.HotelId
+	}
+	return nil
+}
+
+func (x *Result) GetReservationId() string {
+	if x != nil {
+		return x.ReservationId
+	}
+	return ""
+}
+
+func (x *Result) GetNights() []*Night {
+	if x != nil {
+		return x.Nights
+	}
+	return nil
+}
+
+func (x *Result) GetTotalPrice() float64 {
+	if x != nil {
+		return x.TotalPrice
+	}
+	return 0
+}
+
+func (x *Result) GetFailure() FailureReason {
+	if x != nil {
+		return x.Failure
+	}
+	return FailureReason_NONE
+}
+
+type Night struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	InDate     string  `protobuf:"bytes,1,opt,name=inDate,proto3" json:"inDate,omitempty"`
+	OutDate    string  `protobuf:"bytes,2,opt,name=outDate,proto3" json:"outDate,omitempty"`
+	RoomNumber int32   `protobuf:"varint,3,opt,name=roomNumber,proto3" json:"roomNumber,omitempty"`
+	Price      float64 `protobuf:"fixed64,4,opt,name=price,proto3" json:"price,omitempty"`
+}
+
+func (x *Night) Reset() {
+	*x = Night{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_reservation_proto_reservation_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Night) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Night) ProtoMessage() {}
+
+func (x *Night) ProtoReflect() protoreflect.Message {
+	mi := &file_services_reservation_proto_reservation_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Night.ProtoReflect.Descriptor instead.
+func (*Night) Descriptor() ([]byte, []int) {
+	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Night) GetInDate() string {
+	if x != nil {
+		return x.InDate
+	}
+	return ""
+}
+
+func (x *Night) GetOutDate() string {
+	if x != nil {
+		return x.OutDate
+	}
+	return ""
+}
+
+func (x *Night) GetRoomNumber() int32 {
+	if x != nil {
+		return x.RoomNumber
+	}
+	return 0
+}
+
+func (x *Night) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+type CancelRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ReservationId string `protobuf:"bytes,1,opt,name=reservationId,proto3" json:"reservationId,omitempty"`
+}
+
+func (x *CancelRequest) Reset() {
+	*x = CancelRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_reservation_proto_reservation_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CancelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRequest) ProtoMessage() {}
+
+func (x *CancelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_services_reservation_proto_reservation_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRequest.ProtoReflect.Descriptor instead.
+func (*CancelRequest) Descriptor() ([]byte, []int) {
+	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CancelRequest) GetReservationId() string {
+	if x != nil {
+		return x.ReservationId
+	}
+	return ""
+}
+
+type CustomerRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	CustomerName string `protobuf:"bytes,1,opt,name=customerName,proto3" json:"customerName,omitempty"`
+}
+
+func (x *CustomerRequest) Reset() {
+	*x = CustomerRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_reservation_proto_reservation_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CustomerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CustomerRequest) ProtoMessage() {}
+
+func (x *CustomerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_services_reservation_proto_reservation_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CustomerRequest.ProtoReflect.Descriptor instead.
+func (*CustomerRequest) Descriptor() ([]byte, []int) {
+	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CustomerRequest) GetCustomerName() string {
+	if x != nil {
+		return x.CustomerName
+	}
+	return ""
+}
+
+type ModifyRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ReservationId string `protobuf:"bytes,1,opt,name=reservationId,proto3" json:"reservationId,omitempty"`
+	InDate        string `protobuf:"bytes,2,opt,name=inDate,proto3" json:"inDate,omitempty"`
+	OutDate       string `protobuf:"bytes,3,opt,name=outDate,proto3" json:"outDate,omitempty"`
+	RoomNumber    int32  `protobuf:"varint,4,opt,name=roomNumber,proto3" json:"roomNumber,omitempty"`
+}
+
+func (x *ModifyRequest) Reset() {
+	*x = ModifyRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_reservation_proto_reservation_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ModifyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModifyRequest) ProtoMessage() {}
+
+func (x *ModifyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_services_reservation_proto_reservation_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModifyRequest.ProtoReflect.Descriptor instead.
+func (*ModifyRequest) Descriptor() ([]byte, []int) {
+	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ModifyRequest) GetReservationId() string {
+	if x != nil {
+		return x.ReservationId
+	}
+	return ""
+}
+
+func (x *ModifyRequest) GetInDate() string {
+	if x != nil {
+		return x.InDate
+	}
+	return ""
+}
+
+func (x *ModifyRequest) GetOutDate() string {
+	if x != nil {
+		return x.OutDate
+	}
+	return ""
+}
+
+func (x *ModifyRequest) GetRoomNumber() int32 {
+	if x != nil {
+		return x.RoomNumber
+	}
+	return 0
+}
+
+type Booking struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ReservationId string `protobuf:"bytes,1,opt,name=reservationId,proto3" json:"reservationId,omitempty"`
+	CustomerName  string `protobuf:"bytes,2,opt,name=customerName,proto3" json:"customerName,omitempty"`
+	HotelId       string `protobuf:"bytes,3,opt,name=hotelId,proto3" json:"hotelId,omitempty"`
+	InDate        string `protobuf:"bytes,4,opt,name=inDate,proto3" json:"inDate,omitempty"`
+	OutDate       string `protobuf:"bytes,5,opt,name=outDate,proto3" json:"outDate,omitempty"`
+	RoomNumber    int32  `protobuf:"varint,6,opt,name=roomNumber,proto3" json:"roomNumber,omitempty"`
+}
+
+func (x *Booking) Reset() {
+	*x = Booking{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_reservation_proto_reservation_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Booking) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Booking) ProtoMessage() {}
+
+func (x *Booking) ProtoReflect() protoreflect.Message {
+	mi := &file_services_reservation_proto_reservation_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Booking.ProtoReflect.Descriptor instead.
+func (*Booking) Descriptor() ([]byte, []int) {
+	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Booking) GetReservationId() string {
+	if x != nil {
+		return x.ReservationId
+	}
+	return ""
+}
+
+func (x *Booking) GetCustomerName() string {
+	if x != nil {
+		return x.CustomerName
+	}
+	return ""
+}
+
+func (x *Booking) GetHotelId() string {
+	if x != nil {
+		return x.HotelId
+	}
+	return ""
+}
+
+func (x *Booking) GetInDate() string {
+	if x != nil {
+		return x.InDate
+	}
+	return ""
+}
+
+func (x *Booking) GetOutDate() string {
+	if x != nil {
+		return x.OutDate
+	}
+	return ""
+}
+
+func (x *Booking) GetRoomNumber() int32 {
+	if x != nil {
+		return x.RoomNumber
+	}
+	return 0
+}
+
+type Bookings struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Bookings []*Booking `protobuf:"bytes,1,rep,name=bookings,proto3" json:"bookings,omitempty"`
 }
 
-func (x *Request) Reset() {
-	*x = Request{}
+func (x *Bookings) Reset() {
+	*x = Bookings{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_services_reservation_proto_reservation_proto_msgTypes[0]
+		mi := &file_services_reservation_proto_reservation_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Request) String() string {
+func (x *Bookings) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Request) ProtoMessage() {}
+func (*Bookings) ProtoMessage() {}
 
-func (x *Request) ProtoReflect() protoreflect.Message {
-	mi := &file_services_reservation_proto_reservation_proto_msgTypes[0]
+func (x *Bookings) ProtoReflect() protoreflect.Message {
+	mi := &file_services_reservation_proto_reservation_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -59,71 +590,211 @@ func (x *Request) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Request.ProtoReflect.Descriptor instead.
-func (*Request) Descriptor() ([]byte, []int) {
-	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use Bookings.ProtoReflect.Descriptor instead.
+func (*Bookings) Descriptor() ([]byte, []int) {
+	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *Request) GetCustomerName() string {
+func (x *Bookings) GetBookings() []*Booking {
 	if x != nil {
-		return x.CustomerName
+		return x.Bookings
 	}
-	return ""
+	return nil
 }
 
-func (x *Request) GetHotelId() []string {
+type Capacity struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	HotelId    string `protobuf:"bytes,1,opt,name=hotelId,proto3" json:"hotelId,omitempty"`
+	RoomNumber int32  `protobuf:"varint,2,opt,name=roomNumber,proto3" json:"roomNumber,omitempty"`
+}
+
+func (x *Capacity) Reset() {
+	*x = Capacity{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_reservation_proto_reservation_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Capacity) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Capacity) ProtoMessage() {}
+
+func (x *Capacity) ProtoReflect() protoreflect.Message {
+	mi := &file_services_reservation_proto_reservation_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Capacity.ProtoReflect.Descriptor instead.
+func (*Capacity) Descriptor() ([]byte, []int) {
+	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Capacity) GetHotelId() string {
 	if x != nil {
 		return x.HotelId
 	}
+	return ""
+}
+
+func (x *Capacity) GetRoomNumber() int32 {
+	if x != nil {
+		return x.RoomNumber
+	}
+	return 0
+}
+
+type OccupancyRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	HotelIds []string `protobuf:"bytes,1,rep,name=hotelIds,proto3" json:"hotelIds,omitempty"`
+	InDate   string   `protobuf:"bytes,2,opt,name=inDate,proto3" json:"inDate,omitempty"`
+	OutDate  string   `protobuf:"bytes,3,opt,name=outDate,proto3" json:"outDate,omitempty"`
+}
+
+func (x *OccupancyRequest) Reset() {
+	*x = OccupancyRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_reservation_proto_reservation_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OccupancyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OccupancyRequest) ProtoMessage() {}
+
+func (x *OccupancyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_services_reservation_proto_reservation_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OccupancyRequest.ProtoReflect.Descriptor instead.
+func (*OccupancyRequest) Descriptor() ([]byte, []int) {
+	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *OccupancyRequest) GetHotelIds() []string {
+	if x != nil {
+		return x.HotelIds
+	}
 	return nil
 }
 
-func (x *Request) GetInDate() string {
+func (x *OccupancyRequest) GetInDate() string {
 	if x != nil {
 		return x.InDate
 	}
 	return ""
 }
 
-func (x *Request) GetOutDate() string {
+func (x *OccupancyRequest) GetOutDate() string {
 	if x != nil {
 		return x.OutDate
 	}
 	return ""
 }
 
-func (x *Request) GetRoomNumber() int32 {
+// Hotels without a known capacity are left out.
+type OccupancyResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hotels map[string]*Occupancy `protobuf:"bytes,1,rep,name=hotels,proto3" json:"hotels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *OccupancyResult) Reset() {
+	*x = OccupancyResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_reservation_proto_reservation_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OccupancyResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OccupancyResult) ProtoMessage() {}
+
+func (x *OccupancyResult) ProtoReflect() protoreflect.Message {
+	mi := &file_services_reservation_proto_reservation_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OccupancyResult.ProtoReflect.Descriptor instead.
+func (*OccupancyResult) Descriptor() ([]byte, []int) {
+	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *OccupancyResult) GetHotels() map[string]*Occupancy {
 	if x != nil {
-		return x.RoomNumber
+		return x.Hotels
 	}
-	return 0
+	return nil
 }
 
-type Result struct {
+type Occupancy struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	HotelId []string `protobuf:"bytes,1,rep,name=hotelId,proto3" json:"hotelId,omitempty"`
+	Capacity int32 `protobuf:"varint,1,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	// rooms booked per night, keyed by the date the night starts
+	Booked map[string]int32 `protobuf:"bytes,2,rep,name=booked,proto3" json:"booked,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
 }
 
-func (x *Result) Reset() {
-	*x = Result{}
+func (x *Occupancy) Reset() {
+	*x = Occupancy{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_services_reservation_proto_reservation_proto_msgTypes[1]
+		mi := &file_services_reservation_proto_reservation_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Result) String() string {
+func (x *Occupancy) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Result) ProtoMessage() {}
+func (*Occupancy) ProtoMessage() {}
 
-func (x *Result) ProtoReflect() protoreflect.Message {
-	mi := &file_services_reservation_proto_reservation_proto_msgTypes[1]
+func (x *Occupancy) ProtoReflect() protoreflect.Message {
+	mi := &file_services_reservation_proto_reservation_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -134,14 +805,21 @@ func (x *Result) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Result.ProtoReflect.Descriptor instead.
-func (*Result) Descriptor() ([]byte, []int) {
-	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use Occupancy.ProtoReflect.Descriptor instead.
+func (*Occupancy) Descriptor() ([]byte, []int) {
+	return file_services_reservation_proto_reservation_proto_rawDescGZIP(), []int{11}
 }
 
-func (x *Result) GetHotelId() []string {
+func (x *Occupancy) GetCapacity() int32 {
 	if x != nil {
-		return x.HotelId
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *Occupancy) GetBooked() map[string]int32 {
+	if x != nil {
+		return x.Booked
 	}
 	return nil
 }
@@ -162,23 +840,132 @@ var file_services_reservation_proto_reservation_proto_rawDesc = []byte{
 	0x07, 0x6f, 0x75, 0x74, 0x44, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
 	0x6f, 0x75, 0x74, 0x44, 0x61, 0x74, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x4e,
 	0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x72, 0x6f, 0x6f,
-	0x6d, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x22, 0x22, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c,
-	0x74, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x18, 0x01, 0x20, 0x03,
-	0x28, 0x09, 0x52, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x32, 0x8b, 0x01, 0x0a, 0x0b,
-	0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x3c, 0x0a, 0x0f, 0x4d,
-	0x61, 0x6b, 0x65, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x14,
-	0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69,
-	0x6f, 0x6e, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x3e, 0x0a, 0x11, 0x43, 0x68, 0x65,
-	0x63, 0x6b, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x14,
-	0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69,
-	0x6f, 0x6e, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x42, 0x4c, 0x5a, 0x4a, 0x67, 0x69, 0x74,
+	0x6d, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x22, 0xca, 0x01, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x12, 0x24, 0x0a, 0x0d,
+	0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0d, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x49, 0x64, 0x12, 0x2a, 0x0a, 0x06, 0x6e, 0x69, 0x67, 0x68, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x12, 0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x2e, 0x4e, 0x69, 0x67, 0x68, 0x74, 0x52, 0x06, 0x6e, 0x69, 0x67, 0x68, 0x74, 0x73, 0x12, 0x1e,
+	0x0a, 0x0a, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x50, 0x72, 0x69, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x0a, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x34,
+	0x0a, 0x07, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x1a, 0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x46, 0x61,
+	0x69, 0x6c, 0x75, 0x72, 0x65, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x52, 0x07, 0x66, 0x61, 0x69,
+	0x6c, 0x75, 0x72, 0x65, 0x22, 0x6f, 0x0a, 0x05, 0x4e, 0x69, 0x67, 0x68, 0x74, 0x12, 0x16, 0x0a,
+	0x06, 0x69, 0x6e, 0x44, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x69,
+	0x6e, 0x44, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6f, 0x75, 0x74, 0x44, 0x61, 0x74, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6f, 0x75, 0x74, 0x44, 0x61, 0x74, 0x65, 0x12,
+	0x1e, 0x0a, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12,
+	0x14, 0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05,
+	0x70, 0x72, 0x69, 0x63, 0x65, 0x22, 0x35, 0x0a, 0x0d, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x24, 0x0a, 0x0d, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x72,
+	0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x22, 0x35, 0x0a, 0x0f,
+	0x43, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x22, 0x0a, 0x0c, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x4e,
+	0x61, 0x6d, 0x65, 0x22, 0x87, 0x01, 0x0a, 0x0d, 0x4d, 0x6f, 0x64, 0x69, 0x66, 0x79, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x24, 0x0a, 0x0d, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x72, 0x65,
+	0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x69,
+	0x6e, 0x44, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x69, 0x6e, 0x44,
+	0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6f, 0x75, 0x74, 0x44, 0x61, 0x74, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6f, 0x75, 0x74, 0x44, 0x61, 0x74, 0x65, 0x12, 0x1e, 0x0a,
+	0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x22, 0xbf, 0x01,
+	0x0a, 0x07, 0x42, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x12, 0x24, 0x0a, 0x0d, 0x72, 0x65, 0x73,
+	0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0d, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12,
+	0x22, 0x0a, 0x0c, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x4e,
+	0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x49, 0x64, 0x12, 0x16, 0x0a,
+	0x06, 0x69, 0x6e, 0x44, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x69,
+	0x6e, 0x44, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6f, 0x75, 0x74, 0x44, 0x61, 0x74, 0x65,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6f, 0x75, 0x74, 0x44, 0x61, 0x74, 0x65, 0x12,
+	0x1e, 0x0a, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x22,
+	0x3c, 0x0a, 0x08, 0x42, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x30, 0x0a, 0x08, 0x62,
+	0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e,
+	0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x42, 0x6f, 0x6f, 0x6b,
+	0x69, 0x6e, 0x67, 0x52, 0x08, 0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x73, 0x22, 0x44, 0x0a,
+	0x08, 0x43, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x6f, 0x74,
+	0x65, 0x6c, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x68, 0x6f, 0x74, 0x65,
+	0x6c, 0x49, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x4e, 0x75, 0x6d, 0x62, 0x65,
+	0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x4e, 0x75, 0x6d,
+	0x62, 0x65, 0x72, 0x22, 0x60, 0x0a, 0x10, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x68, 0x6f, 0x74, 0x65, 0x6c,
+	0x49, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x68, 0x6f, 0x74, 0x65, 0x6c,
+	0x49, 0x64, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x6e, 0x44, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x69, 0x6e, 0x44, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6f,
+	0x75, 0x74, 0x44, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6f, 0x75,
+	0x74, 0x44, 0x61, 0x74, 0x65, 0x22, 0xa6, 0x01, 0x0a, 0x0f, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61,
+	0x6e, 0x63, 0x79, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x40, 0x0a, 0x06, 0x68, 0x6f, 0x74,
+	0x65, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x72, 0x65, 0x73, 0x65,
+	0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63,
+	0x79, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x48, 0x6f, 0x74, 0x65, 0x6c, 0x73, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x52, 0x06, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x73, 0x1a, 0x51, 0x0a, 0x0b, 0x48,
+	0x6f, 0x74, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x2c, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x72, 0x65,
+	0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61,
+	0x6e, 0x63, 0x79, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x9e,
+	0x01, 0x0a, 0x09, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79, 0x12, 0x1a, 0x0a, 0x08,
+	0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08,
+	0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x12, 0x3a, 0x0a, 0x06, 0x62, 0x6f, 0x6f, 0x6b,
+	0x65, 0x64, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x72, 0x65, 0x73, 0x65, 0x72,
+	0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79,
+	0x2e, 0x42, 0x6f, 0x6f, 0x6b, 0x65, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x62, 0x6f,
+	0x6f, 0x6b, 0x65, 0x64, 0x1a, 0x39, 0x0a, 0x0b, 0x42, 0x6f, 0x6f, 0x6b, 0x65, 0x64, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x2a,
+	0x50, 0x0a, 0x0d, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e,
+	0x12, 0x08, 0x0a, 0x04, 0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x4e, 0x4f,
+	0x5f, 0x43, 0x41, 0x50, 0x41, 0x43, 0x49, 0x54, 0x59, 0x10, 0x01, 0x12, 0x11, 0x0a, 0x0d, 0x49,
+	0x4e, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x5f, 0x44, 0x41, 0x54, 0x45, 0x53, 0x10, 0x02, 0x12, 0x11,
+	0x0a, 0x0d, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x5f, 0x48, 0x4f, 0x54, 0x45, 0x4c, 0x10,
+	0x03, 0x32, 0xf5, 0x03, 0x0a, 0x0b, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x3c, 0x0a, 0x0f, 0x4d, 0x61, 0x6b, 0x65, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x72, 0x65, 0x73,
+	0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12,
+	0x3e, 0x0a, 0x11, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x69,
+	0x6c, 0x69, 0x74, 0x79, 0x12, 0x14, 0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x72, 0x65, 0x73,
+	0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12,
+	0x45, 0x0a, 0x11, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x14, 0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x42,
+	0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x12, 0x50, 0x0a, 0x19, 0x47, 0x65, 0x74, 0x52, 0x65, 0x73,
+	0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x42, 0x79, 0x43, 0x75, 0x73, 0x74, 0x6f,
+	0x6d, 0x65, 0x72, 0x12, 0x1c, 0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x2e, 0x43, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x15, 0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e,
+	0x42, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x45, 0x0a, 0x11, 0x4d, 0x6f, 0x64, 0x69,
+	0x66, 0x79, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x2e,
+	0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x4d, 0x6f, 0x64, 0x69,
+	0x66, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x72, 0x65, 0x73, 0x65,
+	0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x42, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x12,
+	0x3b, 0x0a, 0x0b, 0x53, 0x65, 0x74, 0x43, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x12, 0x15,
+	0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x43, 0x61, 0x70,
+	0x61, 0x63, 0x69, 0x74, 0x79, 0x1a, 0x15, 0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x2e, 0x43, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x12, 0x4b, 0x0a, 0x0c,
+	0x47, 0x65, 0x74, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79, 0x12, 0x1d, 0x2e, 0x72,
+	0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x4f, 0x63, 0x63, 0x75, 0x70,
+	0x61, 0x6e, 0x63, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x72, 0x65,
+	0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61,
+	0x6e, 0x63, 0x79, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x42, 0x58, 0x5a, 0x56, 0x67, 0x69, 0x74,
 	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x64, 0x65, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x72,
 	0x6f, 0x75, 0x2f, 0x44, 0x65, 0x61, 0x74, 0x68, 0x53, 0x74, 0x61, 0x72, 0x42, 0x65, 0x6e, 0x63,
-	0x68, 0x2f, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69,
-	0x6f, 0x6e, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x2f, 0x72, 0x65, 0x73, 0x65,
-	0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x68, 0x2f, 0x74, 0x72, 0x65, 0x65, 0x2f, 0x6d, 0x61, 0x73, 0x74, 0x65, 0x72, 0x2f, 0x68, 0x6f,
+	0x74, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2f, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x2f, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -193,21 +980,51 @@ func file_services_reservation_proto_reservation_proto_rawDescGZIP() []byte {
 	return file_services_reservation_proto_reservation_proto_rawDescData
 }
 
-var file_services_reservation_proto_reservation_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_services_reservation_proto_reservation_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_services_reservation_proto_reservation_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_services_reservation_proto_reservation_proto_goTypes = []interface{}{
-	(*Request)(nil), // 0: reservation.Request
-	(*Result)(nil),  // 1: reservation.Result
+	(FailureReason)(0),       // 0: reservation.FailureReason
+	(*Request)(nil),          // 1: reservation.Request
+	(*Result)(nil),           // 2: reservation.Result
+	(*Night)(nil),            // 3: reservation.Night
+	(*CancelRequest)(nil),    // 4: reservation.CancelRequest
+	(*CustomerRequest)(nil),  // 5: reservation.CustomerRequest
+	(*ModifyRequest)(nil),    // 6: reservation.ModifyRequest
+	(*Booking)(nil),          // 7: reservation.Booking
+	(*Bookings)(nil),         // 8: reservation.Bookings
+	(*Capacity)(nil),         // 9: reservation.Capacity
+	(*OccupancyRequest)(nil), // 10: reservation.OccupancyRequest
+	(*OccupancyResult)(nil),  // 11: reservation.OccupancyResult
+	(*Occupancy)(nil),        // 12: reservation.Occupancy
+	nil,                      // 13: reservation.OccupancyResult.HotelsEntry
+	nil,                      // 14: reservation.Occupancy.BookedEntry
 }
 var file_services_reservation_proto_reservation_proto_depIdxs = []int32{
-	0, // 0: reservation.Reservation.MakeReservation:input_type -> reservation.Request
-	0, // 1: reservation.Reservation.CheckAvailability:input_type -> reservation.Request
-	1, // 2: reservation.Reservation.MakeReservation:output_type -> reservation.Result
-	1, // 3: reservation.Reservation.CheckAvailability:output_type -> reservation.Result
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	3,  // 0: reservation.Result.nights:type_name -> reservation.Night
+	0,  // 1: reservation.Result.failure:type_name -> reservation.FailureReason
+	7,  // 2: reservation.Bookings.bookings:type_name -> reservation.Booking
+	13, // 3: reservation.OccupancyResult.hotels:type_name -> reservation.OccupancyResult.HotelsEntry
+	14, // 4: reservation.Occupancy.booked:type_name -> reservation.Occupancy.BookedEntry
+	12, // 5: reservation.OccupancyResult.HotelsEntry.value:type_name -> reservation.Occupancy
+	1,  // 6: reservation.Reservation.MakeReservation:input_type -> reservation.Request
+	1,  // 7: reservation.Reservation.CheckAvailability:input_type -> reservation.Request
+	4,  // 8: reservation.Reservation.CancelReservation:input_type -> reservation.CancelRequest
+	5,  // 9: reservation.Reservation.GetReservationsByCustomer:input_type -> reservation.CustomerRequest
+	6,  // 10: reservation.Reservation.ModifyReservation:input_type -> reservation.ModifyRequest
+	9,  // 11: reservation.Reservation.SetCapacity:input_type -> reservation.Capacity
+	10, // 12: reservation.Reservation.GetOccupancy:input_type -> reservation.OccupancyRequest
+	2,  // 13: reservation.Reservation.MakeReservation:output_type -> reservation.Result
+	2,  // 14: reservation.Reservation.CheckAvailability:output_type -> reservation.Result
+	7,  // 15: reservation.Reservation.CancelReservation:output_type -> reservation.Booking
+	8,  // 16: reservation.Reservation.GetReservationsByCustomer:output_type -> reservation.Bookings
+	7,  // 17: reservation.Reservation.ModifyReservation:output_type -> reservation.Booking
+	9,  // 18: reservation.Reservation.SetCapacity:output_type -> reservation.Capacity
+	11, // 19: reservation.Reservation.GetOccupancy:output_type -> reservation.OccupancyResult
+	13, // [13:20] is the sub-list for method output_type
+	6,  // [6:13] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_services_reservation_proto_reservation_proto_init() }
@@ -240,19 +1057,140 @@ func file_services_reservation_proto_reservation_proto_init() {
 				return nil
 			}
 		}
+		file_services_reservation_proto_reservation_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Night); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_reservation_proto_reservation_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CancelRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_reservation_proto_reservation_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CustomerRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_reservation_proto_reservation_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ModifyRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_reservation_proto_reservation_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Booking); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_reservation_proto_reservation_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Bookings); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_reservation_proto_reservation_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Capacity); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_reservation_proto_reservation_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OccupancyRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_reservation_proto_reservation_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OccupancyResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_reservation_proto_reservation_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Occupancy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_services_reservation_proto_reservation_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      1,
+			NumMessages:   14,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_services_reservation_proto_reservation_proto_goTypes,
 		DependencyIndexes: file_services_reservation_proto_reservation_proto_depIdxs,
+		EnumInfos:         file_services_reservation_proto_reservation_proto_enumTypes,
 		MessageInfos:      file_services_reservation_proto_reservation_proto_msgTypes,
 	}.Build()
 	File_services_reservation_proto_reservation_proto = out.File
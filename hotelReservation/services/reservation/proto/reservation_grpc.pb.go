@@ -19,8 +19,13 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Reservation_MakeReservation_FullMethodName   = "/reservation.Reservation/MakeReservation"
-	Reservation_CheckAvailability_FullMethodName = "/reservation.Reservation/CheckAvailability"
+	Reservation_MakeReservation_FullMethodName           = "/reservation.Reservation/MakeReservation"
+	Reservation_CheckAvailability_FullMethodName         = "/reservation.Reservation/CheckAvailability"
+	Reservation_CancelReservation_FullMethodName         = "/reservation.Reservation/CancelReservation"
+	Reservation_GetReservationsByCustomer_FullMethodName = "/reservation.Reservation/GetReservationsByCustomer"
+	Reservation_ModifyReservation_FullMethodName         = "/reservation.Reservation/ModifyReservation"
+	Reservation_SetCapacity_FullMethodName               = "/reservation.Reservation/SetCapacity"
+	Reservation_GetOccupancy_FullMethodName              = "/reservation.Reservation/GetOccupancy"
 )
 
 // ReservationClient is the client API for Reservation service.
@@ -31,6 +36,16 @@ type ReservationClient interface {
 	MakeReservation(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
 	// CheckAvailability checks if given information is available
 	CheckAvailability(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
+	// CancelReservation cancels a reservation and gives its rooms back
+	CancelReservation(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*Booking, error)
+	// GetReservationsByCustomer returns all reservations made by a customer
+	GetReservationsByCustomer(ctx context.Context, in *CustomerRequest, opts ...grpc.CallOption) (*Bookings, error)
+	// ModifyReservation changes the dates or the number of rooms of a reservation
+	ModifyReservation(ctx context.Context, in *ModifyRequest, opts ...grpc.CallOption) (*Booking, error)
+	// SetCapacity sets the number of rooms of a hotel
+	SetCapacity(ctx context.Context, in *Capacity, opts ...grpc.CallOption) (*Capacity, error)
+	// GetOccupancy returns the rooms booked per night of a stay
+	GetOccupancy(ctx context.Context, in *OccupancyRequest, opts ...grpc.CallOption) (*OccupancyResult, error)
 }
 
 type reservationClient struct {
@@ -59,6 +74,51 @@ func (c *reservationClient) CheckAvailability(ctx context.Context, in *Request,
 	return out, nil
 }
 
+func (c *reservationClient) CancelReservation(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*Booking, error) {
+	out := new(Booking)
+	err := c.cc.Invoke(ctx, Reservation_CancelReservation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reservationClient) GetReservationsByCustomer(ctx context.Context, in *CustomerRequest, opts ...grpc.CallOption) (*Bookings, error) {
+	out := new(Bookings)
+	err := c.cc.Invoke(ctx, Reservation_GetReservationsByCustomer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reservationClient) ModifyReservation(ctx context.Context, in *ModifyRequest, opts ...grpc.CallOption) (*Booking, error) {
+	out := new(Booking)
+	err := c.cc.Invoke(ctx, Reservation_ModifyReservation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reservationClient) SetCapacity(ctx context.Context, in *Capacity, opts ...grpc.CallOption) (*Capacity, error) {
+	out := new(Capacity)
+	err := c.cc.Invoke(ctx, Reservation_SetCapacity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reservationClient) GetOccupancy(ctx context.Context, in *OccupancyRequest, opts ...grpc.CallOption) (*OccupancyResult, error) {
+	out := new(OccupancyResult)
+	err := c.cc.Invoke(ctx, Reservation_GetOccupancy_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ReservationServer is the server API for Reservation service.
 // All implementations must embed UnimplementedReservationServer
 // for forward compatibility
@@ -67,6 +127,16 @@ type ReservationServer interface {
 	MakeReservation(context.Context, *Request) (*Result, error)
 	// CheckAvailability checks if given information is available
 	CheckAvailability(context.Context, *Request) (*Result, error)
+	// CancelReservation cancels a reservation and gives its rooms back
+	CancelReservation(context.Context, *CancelRequest) (*Booking, error)
+	// GetReservationsByCustomer returns all reservations made by a customer
+	GetReservationsByCustomer(context.Context, *CustomerRequest) (*Bookings, error)
+	// ModifyReservation changes the dates or the number of rooms of a reservation
+	ModifyReservation(context.Context, *ModifyRequest) (*Booking, error)
+	// SetCapacity sets the number of rooms of a hotel
+	SetCapacity(context.Context, *Capacity) (*Capacity, error)
+	// GetOccupancy returns the rooms booked per night of a stay
+	GetOccupancy(context.Context, *OccupancyRequest) (*OccupancyResult, error)
 	mustEmbedUnimplementedReservationServer()
 }
 
@@ -80,6 +150,21 @@ func (UnimplementedReservationServer) MakeReservation(context.Context, *Request)
 func (UnimplementedReservationServer) CheckAvailability(context.Context, *Request) (*Result, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CheckAvailability not implemented")
 }
+func (UnimplementedReservationServer) CancelReservation(context.Context, *CancelRequest) (*Booking, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelReservation not implemented")
+}
+func (UnimplementedReservationServer) GetReservationsByCustomer(context.Context, *CustomerRequest) (*Bookings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReservationsByCustomer not implemented")
+}
+func (UnimplementedReservationServer) ModifyReservation(context.Context, *ModifyRequest) (*Booking, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ModifyReservation not implemented")
+}
+func (UnimplementedReservationServer) SetCapacity(context.Context, *Capacity) (*Capacity, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCapacity not implemented")
+}
+func (UnimplementedReservationServer) GetOccupancy(context.Context, *OccupancyRequest) (*OccupancyResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOccupancy not implemented")
+}
 func (UnimplementedReservationServer) mustEmbedUnimplementedReservationServer() {}
 
 // UnsafeReservationServer may be embedded to opt out of forward compatibility for this service.
@@ -129,6 +214,96 @@ func _Reservation_CheckAvailability_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Reservation_CancelReservation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReservationServer).CancelReservation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reservation_CancelReservation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReservationServer).CancelReservation(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reservation_GetReservationsByCustomer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CustomerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReservationServer).GetReservationsByCustomer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reservation_GetReservationsByCustomer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReservationServer).GetReservationsByCustomer(ctx, req.(*CustomerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reservation_ModifyReservation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReservationServer).ModifyReservation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reservation_ModifyReservation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReservationServer).ModifyReservation(ctx, req.(*ModifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reservation_SetCapacity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Capacity)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReservationServer).SetCapacity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reservation_SetCapacity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReservationServer).SetCapacity(ctx, req.(*Capacity))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reservation_GetOccupancy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OccupancyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReservationServer).GetOccupancy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reservation_GetOccupancy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReservationServer).GetOccupancy(ctx, req.(*OccupancyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Reservation_ServiceDesc is the grpc.ServiceDesc for Reservation service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -144,6 +319,26 @@ var Reservation_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CheckAvailability",
 			Handler:    _Reservation_CheckAvailability_Handler,
 		},
+		{
+			MethodName: "CancelReservation",
+			Handler:    _Reservation_CancelReservation_Handler,
+		},
+		{
+			MethodName: "GetReservationsByCustomer",
+			Handler:    _Reservation_GetReservationsByCustomer_Handler,
+		},
+		{
+			MethodName: "ModifyReservation",
+			Handler:    _Reservation_ModifyReservation_Handler,
+		},
+		{
+			MethodName: "SetCapacity",
+			Handler:    _Reservation_SetCapacity_Handler,
+		},
+		{
+			MethodName: "GetOccupancy",
+			Handler:    _Reservation_GetOccupancy_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "services/reservation/proto/reservation.proto",
@@ -2,27 +2,39 @@ package reservation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/dialer"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
+	rate "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate/proto"
 	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/reservation/proto"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 "github.com/rs/zerolog"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 )
 
 const name = "srv-reservation"
@@ -31,23 +43,30 @@ const name = "srv-reservation"
 type Server struct {
 	pb.UnimplementedReservationServer
 
-	uuid string
+	rateClient rate.RateClient
+	store      bookingStore
+	uuid       string
+	grpcServer *grpc.Server
+	listener   net.Listener
+	health     *health.Server
 
 	Tracer      trace.Tracer
 	Port        int
 	IpAddr      string
 	MongoClient *mongo.Client
-	Registry    *registry.Client
+	Registry    registry.Registry
 	MemcClient  *memcache.Client
 }
 
-// Run starts the server
-func (s *Server) Run() error {
+// Setup creates the server, listens on Port and registers in consul. It is
+// called before Run, so Shutdown only reads what Setup wrote.
+func (s *Server) Setup() error {
 	if s.Port == 0 {
 		return fmt.Errorf("server port must be set")
 	}
 
 	s.uuid = uuid.New().String()
+	s.store = newMongoStore(s.MongoClient, s.MemcClient)
 
 	opts := []grpc.ServerOption{
 		grpc.KeepaliveParams(keepalive.ServerParameters{
@@ -57,18 +76,30 @@ func (s *Server) Run() error {
 			PermitWithoutStream: true,
 		}),
 		grpc.UnaryInterceptor(
-			otelgrpc.UnaryServerInterceptor(),
+			otelgrpc.UnaryServerInterceptor(
+				otelgrpc.WithInterceptorFilter(filters.Not(filters.HealthCheck())),
+			),
 		),
 	}
 
 	if tlsopt := tls.GetServerOpt(); tlsopt != nil {
-		opts = append(opts, tlsopt)
+		opts = append(opts, tlsopt, grpc.ChainUnaryInterceptor(tls.UnaryServerInterceptor()))
 	}
 
 	srv := grpc.NewServer(opts...)
+	s.grpcServer = srv
+
+	// answers the consul check, serving until Shutdown
+	s.health = health.NewServer()
+	healthpb.RegisterHealthServer(srv, s.health)
 
 	pb.RegisterReservationServer(srv, s)
 
+	// init grpc clients
+	if err := s.initRateClient("srv-rate"); err != nil {
+		return err
+	}
+
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.Port))
 	if err != nil {
 		log.Fatal().Msgf("failed to listen: %v", err)
@@ -82,12 +113,46 @@ func (s *Server) Run() error {
 	}
 	log.Info().Msg("Successfully registered in consul")
 
-	return srv.Serve(lis)
+	s.listener = lis
+	return nil
+}
+
+// Run serves the server set up by Setup until Shutdown
+func (s *Server) Run() error {
+	return s.grpcServer.Serve(s.listener)
+}
+
+// Shutdown fails the health check and deregisters the service so no new
+// calls are routed here, then waits for pending calls until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+	err := s.Registry.Deregister(s.uuid)
+	shutdown.GRPC(ctx, s.grpcServer)
+	return err
+}
+
+func (s *Server) initRateClient(name string) error {
+	conn, err := s.getGprcConn(name)
+	if err != nil {
+		return fmt.Errorf("dialer error: %v", err)
+	}
+	s.rateClient = rate.NewRateClient(conn)
+	return nil
 }
 
-// Shutdown cleans up any processes
-func (s *Server) Shutdown() {
-	s.Registry.Deregister(s.uuid)
+func (s *Server) getGprcConn(name string) (*grpc.ClientConn, error) {
+	target, err := s.Registry.Target(name)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.Dial(
+		target,
+		dialer.WithTracer(s.Tracer),
+		dialer.WithBalancer(""),
+		dialer.WithEnvPolicies(),
+	)
 }
 
 // MakeReservation makes a reservation based on given information
@@ -113,131 +178,237 @@ func (s *Server) MakeReservation(ctx context.Context, req *pb.Request) (*pb.Resu
 		}
 	}
 	
+	if len(req.HotelId) == 0 || req.RoomNumber <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "a hotelId and a positive roomNumber are required")
+	}
+
 	res := new(pb.Result)
 	res.HotelId = make([]string, 0)
 
 	logger.Info().Msgf("Making hotel reservation: hotel_id=%s, in_date=%s, out_date=%s, room_number=%d, customer_name=%s", req.HotelId[0], req.InDate, req.OutDate, req.RoomNumber, req.CustomerName)
 
-	database := s.MongoClient.Database("reservation-db")
-	resCollection := database.Collection("reservation")
-	numCollection := database.Collection("number")
+	hotelId := req.HotelId[0]
+	roomNumber := int(req.RoomNumber)
 
-	inDate, _ := time.Parse(
-		time.RFC3339,
-		req.InDate+"T12:00:00+00:00")
+	nights, err := stayNights(req.InDate, req.OutDate)
+	if err != nil {
+		logger.Warn().Msgf("Invalid stay dates: hotel_id=%s, error=%v", hotelId, err)
+		res.Failure = pb.FailureReason_INVALID_DATES
+		return res, nil
+	}
 
-	outDate, _ := time.Parse(
-		time.RFC3339,
-		req.OutDate+"T12:00:00+00:00")
-	hotelId := req.HotelId[0]
+	hotelCap, err := s.store.capacity(ctx, hotelId)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		logger.Warn().Msgf("Unknown hotel: hotel_id=%s", hotelId)
+		res.Failure = pb.FailureReason_UNKNOWN_HOTEL
+		return res, nil
+	} else if err != nil {
+		logger.Error().Msgf("Failed to get hotel capacity: hotel_id=%s, error=%v", hotelId, err)
+		return nil, err
+	}
 
-	indate := inDate.String()[0:10]
-
-	memc_date_num_map := make(map[string]int)
-
-	for inDate.Before(outDate) {
-		// check reservations
-		count := 0
-		inDate = inDate.AddDate(0, 0, 1)
-		outdate := inDate.String()[0:10]
-
-		// first check memc
-		memc_key := hotelId + "_" + inDate.String()[0:10] + "_" + outdate
-		item, err := s.MemcClient.Get(memc_key)
-		if err == nil {
-			// memcached hit
-			count, _ = strconv.Atoi(string(item.Value))
-			logger.Debug().Msgf("Reservation cache hit: memc_key=%s, current_reservations=%d", memc_key, count)
-			memc_date_num_map[memc_key] = count + int(req.RoomNumber)
-
-		} else if err == memcache.ErrCacheMiss {
-			// memcached miss
-			logger.Debug().Msgf("Reservation cache miss, querying database: date_range=%s to %s", indate, outdate)
-			var reserve []reservation
-
-			filter := bson.D{{"hotelId", hotelId}, {"inDate", indate}, {"outDate", outdate}}
-			curr, err := resCollection.Find(context.TODO(), filter)
-			if err != nil {
-				logger.Error().Msgf("Failed get reservation data: ", err)
-			}
-			curr.All(context.TODO(), &reserve)
-			if err != nil {
-				logger.Panic().Msgf("Tried to find hotelId [%v] from date [%v] to date [%v], but got error", hotelId, indate, outdate, err.Error())
-			}
+	// price the stay before booking it, so the guest's own rooms do not count
+	// towards the occupancy the rates follow
+	breakdown, total := s.priceNights(ctx, hotelId, req.InDate, req.OutDate, nights, roomNumber, hotelCap, logger)
 
-			for _, r := range reserve {
-				count += r.Number
-			}
+	// book every night atomically against the per-night occupancy counters,
+	// so concurrent reservations can never push a night above capacity
+	ok, err := s.reserveNights(ctx, hotelId, roomsPerNight(nights, roomNumber), hotelCap)
+	if err != nil {
+		logger.Error().Msgf("Failed to reserve nights: hotel_id=%s, error=%v", hotelId, err)
+		return nil, err
+	}
+	if !ok {
+		logger.Debug().Msgf("Not enough rooms left: hotel_id=%s, capacity=%d, room_number=%d", hotelId, hotelCap, roomNumber)
+		res.Failure = pb.FailureReason_NO_CAPACITY
+		return res, nil
+	}
 
-			memc_date_num_map[memc_key] = count + int(req.RoomNumber)
+	reservationId := uuid.New().String()
+	if err := s.insertNights(reservationId, req.CustomerName, hotelId, nights, roomNumber); err != nil {
+		logger.Error().Msgf("Failed to insert reservation: hotel_id=%s, error=%v", hotelId, err)
+		s.releaseNights(hotelId, roomsPerNight(nights, roomNumber))
+		return nil, err
+	}
+	s.invalidateNights(hotelId, roomsPerNight(nights, roomNumber))
+	logger.Debug().Msgf("Reservation stored: reservation_id=%s, nights=%d", reservationId, len(nights))
 
-		} else {
-			logger.Panic().Msgf("Tried to get memc_key [%v], but got memmcached error = %s", memc_key, err)
-		}
-
-		// check capacity
-		// check memc capacity
-		memc_cap_key := hotelId + "_cap"
-		item, err = s.MemcClient.Get(memc_cap_key)
-		hotel_cap := 0
-		if err == nil {
-			// memcached hit
-			hotel_cap, _ = strconv.Atoi(string(item.Value))
-			logger.Trace().Msgf("memcached hit %s = %d", memc_cap_key, hotel_cap)
-		} else if err == memcache.ErrCacheMiss {
-			// memcached miss
-			var num number
-			err = numCollection.FindOne(context.TODO(), &bson.D{{"hotelId", hotelId}}).Decode(&num)
-			if err != nil {
-				logger.Panic().Msgf("Tried to find hotelId [%v], but got error %v", hotelId, err.Error())
-			}
-			hotel_cap = int(num.Number)
+	res.HotelId = append(res.HotelId, hotelId)
+	res.ReservationId = reservationId
+	res.Nights, res.TotalPrice = breakdown, total
 
-			// write to memcache
-			s.MemcClient.Set(&memcache.Item{Key: memc_cap_key, Value: []byte(strconv.Itoa(hotel_cap))})
-		} else {
-			logger.Panic().Msgf("Tried to get memc_cap_key [%v], but got memmcached error = %s", memc_cap_key, err)
+	logger.Info().Msgf("Reservation completed: reservation_id=%s, hotel_id=%s, total_price=%.2f", reservationId, hotelId, res.TotalPrice)
+
+	return res, nil
+}
+
+// priceNights returns the per-night breakdown and the total price of a stay,
+// using the cheapest rate plan the rate service prices the stay with. The
+// occupancy the rates follow is sent along, so the rate service does not call
+// back for it; if it cannot be read, the stored rates are used. The
+// reservation stands even if no price can be found, so failures only get logged.
+func (s *Server) priceNights(ctx context.Context, hotelId, inDate, outDate string, nights []night, roomNumber int, hotelCap int, logger *zerolog.Logger) ([]*pb.Night, float64) {
+	occupancy := &rate.Occupancy{}
+	dates := make([]string, 0, len(nights))
+	for _, n := range nights {
+		dates = append(dates, n.inDate)
+	}
+	if booked, err := s.bookedRooms(ctx, []string{hotelId}, dates); err != nil {
+		logger.Warn().Msgf("Failed to get occupancy, pricing with stored rates: hotel_id=%s, error=%v", hotelId, err)
+	} else {
+		occupancy.Capacity = int32(hotelCap)
+		occupancy.Booked = make(map[string]int32, len(booked[hotelId]))
+		for date, rooms := range booked[hotelId] {
+			occupancy.Booked[date] = int32(rooms)
 		}
+	}
 
-		if count+int(req.RoomNumber) > hotel_cap {
-			return res, nil
+	nightPrices := make(map[string]float64)
+	rates, err := s.rateClient.GetRates(ctx, &rate.Request{
+		HotelIds:  []string{hotelId},
+		InDate:    inDate,
+		OutDate:   outDate,
+		Occupancy: map[string]*rate.Occupancy{hotelId: occupancy},
+	})
+	if err != nil {
+		logger.Error().Err(err).Msgf("Failed to get rates: hotel_id=%s", hotelId)
+	} else {
+		var cheapest *rate.RatePlan
+		for _, ratePlan := range rates.RatePlans {
+			if ratePlan.HotelId != hotelId || ratePlan.RoomType == nil {
+				continue
+			}
+			if cheapest == nil || ratePlan.RoomType.TotalRateInclusive < cheapest.RoomType.TotalRateInclusive {
+				cheapest = ratePlan
+			}
+		}
+		if cheapest == nil {
+			logger.Warn().Msgf("No rate plan found: hotel_id=%s", hotelId)
+		} else {
+			for _, n := range cheapest.Nights {
+				nightPrices[n.Date] = n.TotalRateInclusive
+			}
 		}
-		indate = outdate
 	}
 
-	// only update reservation number cache after check succeeds
-	for key, val := range memc_date_num_map {
-		s.MemcClient.Set(&memcache.Item{Key: key, Value: []byte(strconv.Itoa(val))})
+	total := 0.0
+	breakdown := make([]*pb.Night, 0, len(nights))
+	for _, n := range nights {
+		nightPrice := nightPrices[n.inDate] * float64(roomNumber)
+		breakdown = append(breakdown, &pb.Night{
+			InDate:     n.inDate,
+			OutDate:    n.outDate,
+			RoomNumber: int32(roomNumber),
+			Price:      nightPrice,
+		})
+		total += nightPrice
+	}
+	return breakdown, total
+}
+
+// insertNights stores one reservation row per night of a booking. Rows get
+// their _id here, so a failed insert removes only the rows it added and never
+// those of the booking being modified under the same reservationId.
+func (s *Server) insertNights(reservationId, customerName, hotelId string, nights []night, roomNumber int) error {
+	rows := make([]reservation, 0, len(nights))
+	for _, n := range nights {
+		rows = append(rows, reservation{
+			Id:            primitive.NewObjectID(),
+			ReservationId: reservationId,
+			HotelId:       hotelId,
+			CustomerName:  customerName,
+			InDate:        n.inDate,
+			OutDate:       n.outDate,
+			Number:        roomNumber,
+		})
 	}
+	return s.store.insert(context.TODO(), rows)
+}
 
-	inDate, _ = time.Parse(
-		time.RFC3339,
-		req.InDate+"T12:00:00+00:00")
+// night is a single night of a stay, identified the same way as the rows in
+// the reservation collection and the per-night memcached counters.
+type night struct {
+	inDate  string
+	outDate string
+}
 
-	indate = inDate.String()[0:10]
+// memcKey returns the memcached key holding the reservation count of the night.
+func (n night) memcKey(hotelId string) string {
+	return hotelId + "_" + n.outDate + "_" + n.outDate
+}
 
-	for inDate.Before(outDate) {
-		inDate = inDate.AddDate(0, 0, 1)
-		outdate := inDate.String()[0:10]
-		_, err := resCollection.InsertOne(
-			context.TODO(),
-			reservation{
-				HotelId:      hotelId,
-				CustomerName: req.CustomerName,
-				InDate:       indate,
-				OutDate:      outdate,
-				Number:       int(req.RoomNumber),
-			},
-		)
-		if err != nil {
-			logger.Panic().Msgf("Tried to insert hotel [hotelId %v], but got error %v", hotelId, err.Error())
+// stayNights splits the stay between inDate and outDate (YYYY-MM-DD) into nights.
+func stayNights(inDate, outDate string) ([]night, error) {
+	in, err := time.Parse(time.RFC3339, inDate+"T12:00:00+00:00")
+	if err != nil {
+		return nil, fmt.Errorf("invalid inDate %q: %v", inDate, err)
+	}
+	out, err := time.Parse(time.RFC3339, outDate+"T12:00:00+00:00")
+	if err != nil {
+		return nil, fmt.Errorf("invalid outDate %q: %v", outDate, err)
+	}
+	if !in.Before(out) {
+		return nil, fmt.Errorf("inDate %s is not before outDate %s", inDate, outDate)
+	}
+
+	nights := make([]night, 0)
+	for in.Before(out) {
+		indate := in.String()[0:10]
+		in = in.AddDate(0, 0, 1)
+		outdate := in.String()[0:10]
+		nights = append(nights, night{inDate: indate, outDate: outdate})
+	}
+	return nights, nil
+}
+
+// roomsPerNight books the same number of rooms for every night.
+func roomsPerNight(nights []night, roomNumber int) map[night]int {
+	rooms := make(map[night]int)
+	for _, n := range nights {
+		rooms[n] = roomNumber
+	}
+	return rooms
+}
+
+// reserveNights books the given number of rooms for every night. If any
+// night is full, the nights booked so far are released again and false is
+// returned. Callers invalidate the cached counts once the rows are stored, so
+// CheckAvailability cannot cache a count from before the booking.
+func (s *Server) reserveNights(ctx context.Context, hotelId string, rooms map[night]int, hotelCap int) (bool, error) {
+	booked := make(map[night]int)
+	for n, number := range rooms {
+		_, mongoSpan := s.Tracer.Start(ctx, "mongo_occupancy_inc")
+		mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+		ok, err := s.store.book(ctx, hotelId, n, number, hotelCap)
+		mongoSpan.End()
+
+		if err != nil || !ok {
+			s.releaseNights(hotelId, booked)
+			return false, err
 		}
-		indate = outdate
+		booked[n] = number
 	}
+	return true, nil
+}
 
-	res.HotelId = append(res.HotelId, hotelId)
+// releaseNights gives the given number of rooms back to the occupancy counters.
+func (s *Server) releaseNights(hotelId string, rooms map[night]int) {
+	for n, number := range rooms {
+		if err := s.store.release(context.TODO(), hotelId, n, number); err != nil {
+			log.Error().Msgf("Failed to release night: hotel_id=%s, in_date=%s, error=%v", hotelId, n.inDate, err)
+		}
+	}
+	s.invalidateNights(hotelId, rooms)
+}
 
-	return res, nil
+// invalidateNights drops the cached reservation counts of the given nights so
+// that CheckAvailability reloads them.
+func (s *Server) invalidateNights(hotelId string, nights map[night]int) {
+	for n := range nights {
+		if err := s.store.invalidate(hotelId, n); err != nil {
+			log.Error().Msgf("Failed to invalidate reservation cache: hotel_id=%s, in_date=%s, error=%v", hotelId, n.inDate, err)
+		}
+	}
 }
 
 // CheckAvailability checks if given information is available
@@ -421,7 +592,7 @@ func (s *Server) CheckAvailability(ctx context.Context, req *pb.Request) (*pb.Re
 					}
 					var count int
 					for _, r := range reserve {
-						logger.Trace().Msgf("reservation check reservation number = %d", queryItem["hotelId"])
+						logger.Trace().Msgf("reservation check reservation number = %d", r.Number)
 						count += r.Number
 					}
 					// update memcached
@@ -453,12 +624,381 @@ func (s *Server) CheckAvailability(ctx context.Context, req *pb.Request) (*pb.Re
 	return res, nil
 }
 
+// CancelReservation cancels a reservation and gives its rooms back
+func (s *Server) CancelReservation(ctx context.Context, req *pb.CancelRequest) (*pb.Booking, error) {
+	// Get logger with trace context
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+	
+	// Extract trace information and add to logger
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		spanCtx := span.SpanContext()
+		if spanCtx.HasTraceID() {
+			newLogger := logger.With().Str("trace_id", spanCtx.TraceID().String()).Logger()
+			logger = &newLogger
+		}
+		if spanCtx.HasSpanID() {
+			newLogger := logger.With().Str("span_id", spanCtx.SpanID().String()).Logger()
+			logger = &newLogger
+		}
+	}
+	
+	logger.Info().Msgf("Cancelling reservation: reservation_id=%s", req.ReservationId)
+
+	rows, err := s.findReservations(ctx, bson.M{"reservationId": req.ReservationId})
+	if err != nil {
+		logger.Error().Msgf("Failed to find reservation: reservation_id=%s, error=%v", req.ReservationId, err)
+		return nil, err
+	}
+	if req.ReservationId == "" || len(rows) == 0 {
+		return nil, status.Errorf(codes.NotFound, "reservation %q not found", req.ReservationId)
+	}
+
+	// only rooms of rows this call actually deleted are given back, so that
+	// concurrent cancellations cannot release the same rooms twice
+	freed, err := s.deleteNights(ctx, rows)
+	s.releaseNights(rows[0].HotelId, freed)
+	if err != nil {
+		logger.Error().Msgf("Failed to delete reservation: reservation_id=%s, error=%v", req.ReservationId, err)
+		return nil, err
+	}
+	if len(freed) == 0 {
+		return nil, status.Errorf(codes.NotFound, "reservation %q not found", req.ReservationId)
+	}
+
+	logger.Info().Msgf("Reservation cancelled: reservation_id=%s, hotel_id=%s, nights=%d", req.ReservationId, rows[0].HotelId, len(freed))
+
+	return toBookings(rows)[0], nil
+}
+
+// GetReservationsByCustomer returns all reservations made by a customer
+func (s *Server) GetReservationsByCustomer(ctx context.Context, req *pb.CustomerRequest) (*pb.Bookings, error) {
+	// Get logger with trace context
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+	
+	// Extract trace information and add to logger
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		spanCtx := span.SpanContext()
+		if spanCtx.HasTraceID() {
+			newLogger := logger.With().Str("trace_id", spanCtx.TraceID().String()).Logger()
+			logger = &newLogger
+		}
+		if spanCtx.HasSpanID() {
+			newLogger := logger.With().Str("span_id", spanCtx.SpanID().String()).Logger()
+			logger = &newLogger
+		}
+	}
+	
+	logger.Info().Msgf("Getting reservations: customer_name=%s", req.CustomerName)
+
+	rows, err := s.findReservations(ctx, bson.M{"customerName": req.CustomerName})
+	if err != nil {
+		logger.Error().Msgf("Failed to find reservations: customer_name=%s, error=%v", req.CustomerName, err)
+		return nil, err
+	}
+
+	res := &pb.Bookings{Bookings: toBookings(rows)}
+	logger.Debug().Msgf("Get reservations completed: customer_name=%s, reservations=%d", req.CustomerName, len(res.Bookings))
+
+	return res, nil
+}
+
+// ModifyReservation changes the dates or the number of rooms of a reservation.
+// Empty dates and a zero room number keep the current values.
+func (s *Server) ModifyReservation(ctx context.Context, req *pb.ModifyRequest) (*pb.Booking, error) {
+	// Get logger with trace context
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+	
+	// Extract trace information and add to logger
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		spanCtx := span.SpanContext()
+		if spanCtx.HasTraceID() {
+			newLogger := logger.With().Str("trace_id", spanCtx.TraceID().String()).Logger()
+			logger = &newLogger
+		}
+		if spanCtx.HasSpanID() {
+			newLogger := logger.With().Str("span_id", spanCtx.SpanID().String()).Logger()
+			logger = &newLogger
+		}
+	}
+	
+	logger.Info().Msgf("Modifying reservation: reservation_id=%s, in_date=%s, out_date=%s, room_number=%d", req.ReservationId, req.InDate, req.OutDate, req.RoomNumber)
+
+	rows, err := s.findReservations(ctx, bson.M{"reservationId": req.ReservationId})
+	if err != nil {
+		logger.Error().Msgf("Failed to find reservation: reservation_id=%s, error=%v", req.ReservationId, err)
+		return nil, err
+	}
+	if req.ReservationId == "" || len(rows) == 0 {
+		return nil, status.Errorf(codes.NotFound, "reservation %q not found", req.ReservationId)
+	}
+
+	current := toBookings(rows)[0]
+	hotelId := current.HotelId
+	inDate, outDate, roomNumber := current.InDate, current.OutDate, int(current.RoomNumber)
+	if req.InDate != "" {
+		inDate = req.InDate
+	}
+	if req.OutDate != "" {
+		outDate = req.OutDate
+	}
+	if req.RoomNumber > 0 {
+		roomNumber = int(req.RoomNumber)
+	}
+
+	nights, err := stayNights(inDate, outDate)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	old := make(map[night]int)
+	for _, r := range rows {
+		old[night{inDate: r.InDate, outDate: r.OutDate}] += r.Number
+	}
+
+	// only book what the current reservation does not already hold
+	grow := make(map[night]int)
+	covered := make(map[night]int)
+	for _, n := range nights {
+		if roomNumber > old[n] {
+			grow[n] = roomNumber - old[n]
+		}
+		covered[n] = roomNumber - grow[n]
+	}
+
+	hotelCap, err := s.store.capacity(ctx, hotelId)
+	if err != nil {
+		logger.Error().Msgf("Failed to get hotel capacity: hotel_id=%s, error=%v", hotelId, err)
+		return nil, err
+	}
+	ok, err := s.reserveNights(ctx, hotelId, grow, hotelCap)
+	if err != nil {
+		logger.Error().Msgf("Failed to reserve nights: hotel_id=%s, error=%v", hotelId, err)
+		return nil, err
+	}
+	if !ok {
+		return nil, status.Errorf(codes.ResourceExhausted, "not enough rooms left in hotel %s", hotelId)
+	}
+
+	if err := s.insertNights(req.ReservationId, current.CustomerName, hotelId, nights, roomNumber); err != nil {
+		logger.Error().Msgf("Failed to insert reservation: reservation_id=%s, error=%v", req.ReservationId, err)
+		s.releaseNights(hotelId, grow)
+		return nil, err
+	}
+
+	// swap out the old rows and give back what the new stay no longer needs
+	freed, err := s.deleteNights(ctx, rows)
+	if err != nil {
+		logger.Error().Msgf("Failed to delete old reservation rows: reservation_id=%s, error=%v", req.ReservationId, err)
+	}
+	release := make(map[night]int)
+	for n, number := range freed {
+		if number > covered[n] {
+			release[n] = number - covered[n]
+		} else if number < covered[n] {
+			logger.Warn().Msgf("Reservation changed concurrently: reservation_id=%s, in_date=%s", req.ReservationId, n.inDate)
+		}
+	}
+	s.releaseNights(hotelId, release)
+	s.invalidateNights(hotelId, roomsPerNight(nights, roomNumber))
+
+	logger.Info().Msgf("Reservation modified: reservation_id=%s, hotel_id=%s, in_date=%s, out_date=%s, room_number=%d", req.ReservationId, hotelId, inDate, outDate, roomNumber)
+
+	return &pb.Booking{
+		ReservationId: req.ReservationId,
+		CustomerName:  current.CustomerName,
+		HotelId:       hotelId,
+		InDate:        inDate,
+		OutDate:       outDate,
+		RoomNumber:    int32(roomNumber),
+	}, nil
+}
+
+// SetCapacity creates or updates the number of rooms of a hotel. Lowering it
+// below the current occupancy only stops new bookings.
+func (s *Server) SetCapacity(ctx context.Context, req *pb.Capacity) (*pb.Capacity, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	if req.HotelId == "" || req.RoomNumber <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "hotelId and a positive roomNumber are required")
+	}
+
+	numCollection := s.MongoClient.Database("reservation-db").Collection("number")
+	_, err := numCollection.UpdateOne(ctx,
+		bson.M{"hotelId": req.HotelId},
+		bson.M{"$set": bson.M{"numberOfRoom": int(req.RoomNumber)}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		logger.Error().Msgf("Failed to store capacity: hotel_id=%s, error=%v", req.HotelId, err)
+		return nil, err
+	}
+
+	memcCapKey := req.HotelId + "_cap"
+	if err := s.MemcClient.Delete(memcCapKey); err != nil && err != memcache.ErrCacheMiss {
+		logger.Error().Msgf("Failed to invalidate cached capacity: hotel_id=%s, error=%v", req.HotelId, err)
+		return nil, err
+	}
+
+	logger.Info().Msgf("Capacity stored: hotel_id=%s, rooms=%d", req.HotelId, req.RoomNumber)
+	return req, nil
+}
+
+// GetOccupancy returns the capacity of each hotel and the rooms booked on
+// every night of the stay, counted from the reservation rows.
+func (s *Server) GetOccupancy(ctx context.Context, req *pb.OccupancyRequest) (*pb.OccupancyResult, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	nights, err := stayNights(req.InDate, req.OutDate)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	dates := make([]string, 0, len(nights))
+	for _, n := range nights {
+		dates = append(dates, n.inDate)
+	}
+
+	res := &pb.OccupancyResult{Hotels: make(map[string]*pb.Occupancy, len(req.HotelIds))}
+	for _, hotelId := range req.HotelIds {
+		hotelCap, err := s.store.capacity(ctx, hotelId)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		} else if err != nil {
+			logger.Error().Msgf("Failed to get capacity: hotel_id=%s, error=%v", hotelId, err)
+			return nil, err
+		}
+		res.Hotels[hotelId] = &pb.Occupancy{Capacity: int32(hotelCap), Booked: make(map[string]int32)}
+	}
+	if len(res.Hotels) == 0 {
+		return res, nil
+	}
+
+	hotelIds := make([]string, 0, len(res.Hotels))
+	for hotelId := range res.Hotels {
+		hotelIds = append(hotelIds, hotelId)
+	}
+	booked, err := s.bookedRooms(ctx, hotelIds, dates)
+	if err != nil {
+		logger.Error().Msgf("Failed to find reservations: hotels=%d, error=%v", len(hotelIds), err)
+		return nil, err
+	}
+	for hotelId, nights := range booked {
+		for date, rooms := range nights {
+			res.Hotels[hotelId].Booked[date] = int32(rooms)
+		}
+	}
+
+	logger.Debug().Msgf("Occupancy lookup completed: hotels=%d, nights=%d", len(res.Hotels), len(dates))
+	return res, nil
+}
+
+// bookedRooms returns the rooms booked per night of the given hotels
+func (s *Server) bookedRooms(ctx context.Context, hotelIds []string, dates []string) (map[string]map[string]int, error) {
+	_, mongoSpan := s.Tracer.Start(ctx, "mongo_reservation_find")
+	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	defer mongoSpan.End()
+	return s.store.bookedRooms(ctx, hotelIds, dates)
+}
+
+// findReservations returns the reservation rows matching filter.
+func (s *Server) findReservations(ctx context.Context, filter bson.M) ([]reservation, error) {
+	resCollection := s.MongoClient.Database("reservation-db").Collection("reservation")
+
+	_, mongoSpan := s.Tracer.Start(ctx, "mongo_reservation_find")
+	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	defer mongoSpan.End()
+
+	curr, err := resCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]reservation, 0)
+	if err := curr.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// deleteNights deletes the given reservation rows one by one and returns the
+// rooms per night of the rows that were actually deleted by this call.
+func (s *Server) deleteNights(ctx context.Context, rows []reservation) (map[night]int, error) {
+	resCollection := s.MongoClient.Database("reservation-db").Collection("reservation")
+
+	freed := make(map[night]int)
+	for _, r := range rows {
+		deleted, err := resCollection.DeleteOne(ctx, bson.M{"_id": r.Id})
+		if err != nil {
+			return freed, err
+		}
+		if deleted.DeletedCount == 1 {
+			freed[night{inDate: r.InDate, outDate: r.OutDate}] += r.Number
+		}
+	}
+	return freed, nil
+}
+
+// toBookings groups per-night reservation rows into bookings, ordered by
+// check-in date.
+func toBookings(rows []reservation) []*pb.Booking {
+	bookings := make([]*pb.Booking, 0)
+	byId := make(map[string]*pb.Booking)
+	for _, r := range rows {
+		b, found := byId[r.ReservationId]
+		if !found {
+			b = &pb.Booking{
+				ReservationId: r.ReservationId,
+				CustomerName:  r.CustomerName,
+				HotelId:       r.HotelId,
+				InDate:        r.InDate,
+				OutDate:       r.OutDate,
+				RoomNumber:    int32(r.Number),
+			}
+			byId[r.ReservationId] = b
+			bookings = append(bookings, b)
+		}
+		if r.InDate < b.InDate {
+			b.InDate = r.InDate
+		}
+		if r.OutDate > b.OutDate {
+			b.OutDate = r.OutDate
+		}
+	}
+
+	sort.Slice(bookings, func(i, j int) bool {
+		return bookings[i].InDate < bookings[j].InDate
+	})
+	return bookings
+}
+
 type reservation struct {
-	HotelId      string `bson:"hotelId"`
-	CustomerName string `bson:"customerName"`
-	InDate       string `bson:"inDate"`
-	OutDate      string `bson:"outDate"`
-	Number       int    `bson:"number"`
+	Id            primitive.ObjectID `bson:"_id,omitempty"`
+	ReservationId string             `bson:"reservationId"`
+	HotelId       string             `bson:"hotelId"`
+	CustomerName  string             `bson:"customerName"`
+	InDate        string             `bson:"inDate"`
+	OutDate       string             `bson:"outDate"`
+	Number        int                `bson:"number"`
 }
 
 type number struct {
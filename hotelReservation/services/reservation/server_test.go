@@ -0,0 +1,341 @@
+package reservation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	rate "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate/proto"
+	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/reservation/proto"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// memStore is an in-memory bookingStore
+type memStore struct {
+	mu        sync.Mutex
+	caps      map[string]int
+	occupancy map[string]int
+	rows      []reservation
+	// rows stored at every invalidate
+	invalidated []int
+}
+
+func newMemStore(caps map[string]int) *memStore {
+	return &memStore{caps: caps, occupancy: make(map[string]int)}
+}
+
+func (m *memStore) capacity(ctx context.Context, hotelId string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hotelCap, ok := m.caps[hotelId]
+	if !ok {
+		return 0, fmt.Errorf("find capacity of hotel %s: %w", hotelId, mongo.ErrNoDocuments)
+	}
+	return hotelCap, nil
+}
+
+func (m *memStore) book(ctx context.Context, hotelId string, n night, number int, hotelCap int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := n.memcKey(hotelId)
+	if m.occupancy[key] > hotelCap-number {
+		return false, nil
+	}
+	m.occupancy[key] += number
+	return true, nil
+}
+
+func (m *memStore) release(ctx context.Context, hotelId string, n night, number int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.occupancy[n.memcKey(hotelId)] -= number
+	return nil
+}
+
+func (m *memStore) invalidate(hotelId string, n night) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.invalidated = append(m.invalidated, len(m.rows))
+	return nil
+}
+
+func (m *memStore) insert(ctx context.Context, rows []reservation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rows = append(m.rows, rows...)
+	return nil
+}
+
+func (m *memStore) bookedRooms(ctx context.Context, hotelIds []string, dates []string) (map[string]map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	booked := make(map[string]map[string]int)
+	for _, r := range m.rows {
+		if !contains(hotelIds, r.HotelId) || !contains(dates, r.InDate) {
+			continue
+		}
+		if booked[r.HotelId] == nil {
+			booked[r.HotelId] = make(map[string]int)
+		}
+		booked[r.HotelId][r.InDate] += r.Number
+	}
+	return booked, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// booked returns the rooms of the reservation rows per night of a hotel
+func (m *memStore) booked(hotelId string) map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	booked := make(map[string]int)
+	for _, r := range m.rows {
+		if r.HotelId == hotelId {
+			booked[r.InDate] += r.Number
+		}
+	}
+	return booked
+}
+
+// noRates fails every GetRates, reservations are made without prices then
+type noRates struct {
+	rate.RateClient
+}
+
+func (noRates) GetRates(ctx context.Context, in *rate.Request, opts ...grpc.CallOption) (*rate.Result, error) {
+	return nil, errors.New("no rates")
+}
+
+// fixedRates prices every night at price and records the requests
+type fixedRates struct {
+	rate.RateClient
+	price float64
+
+	mu   sync.Mutex
+	reqs []*rate.Request
+}
+
+func (f *fixedRates) GetRates(ctx context.Context, in *rate.Request, opts ...grpc.CallOption) (*rate.Result, error) {
+	f.mu.Lock()
+	f.reqs = append(f.reqs, in)
+	f.mu.Unlock()
+
+	plan := &rate.RatePlan{HotelId: in.HotelIds[0], Code: "RACK", InDate: in.InDate, OutDate: in.OutDate, RoomType: &rate.RoomType{}}
+	nights, err := stayNights(in.InDate, in.OutDate)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nights {
+		plan.Nights = append(plan.Nights, &rate.NightRate{Date: n.inDate, TotalRateInclusive: f.price})
+		plan.RoomType.TotalRateInclusive += f.price
+	}
+	return &rate.Result{RatePlans: []*rate.RatePlan{plan}}, nil
+}
+
+func newTestServer(store bookingStore) *Server {
+	log.Logger = zerolog.Nop()
+	return &Server{
+		rateClient: noRates{},
+		store:      store,
+		Tracer:     noop.NewTracerProvider().Tracer("reservation"),
+	}
+}
+
+// reserveConcurrently runs a MakeReservation for every request at once and
+// returns the number of bookings made
+func reserveConcurrently(t *testing.T, s *Server, reqs []*pb.Request) int {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		booked int
+	)
+	for _, req := range reqs {
+		wg.Add(1)
+		go func(req *pb.Request) {
+			defer wg.Done()
+			res, err := s.MakeReservation(context.Background(), req)
+			if err != nil {
+				t.Errorf("MakeReservation: %v", err)
+				return
+			}
+			if res.Failure == pb.FailureReason_NONE {
+				mu.Lock()
+				booked++
+				mu.Unlock()
+			} else if res.Failure != pb.FailureReason_NO_CAPACITY {
+				t.Errorf("MakeReservation failed with %s", res.Failure)
+			}
+		}(req)
+	}
+	wg.Wait()
+	return booked
+}
+
+func TestMakeReservationConcurrentBookings(t *testing.T) {
+	const hotelCap, bookings = 5, 50
+
+	store := newMemStore(map[string]int{"1": hotelCap})
+	s := newTestServer(store)
+
+	reqs := make([]*pb.Request, 0, bookings)
+	for i := 0; i < bookings; i++ {
+		reqs = append(reqs, &pb.Request{
+			CustomerName: fmt.Sprintf("Cornell_%d", i),
+			HotelId:      []string{"1"},
+			InDate:       "2015-04-09",
+			OutDate:      "2015-04-12",
+			RoomNumber:   1,
+		})
+	}
+
+	if booked := reserveConcurrently(t, s, reqs); booked != hotelCap {
+		t.Errorf("got %d bookings, want %d", booked, hotelCap)
+	}
+	for _, date := range []string{"2015-04-09", "2015-04-10", "2015-04-11"} {
+		if got := store.booked("1")[date]; got != hotelCap {
+			t.Errorf("got %d rooms booked on %s, want %d", got, date, hotelCap)
+		}
+	}
+}
+
+func TestMakeReservationOverlappingStays(t *testing.T) {
+	const hotelCap = 4
+
+	store := newMemStore(map[string]int{"1": hotelCap})
+	s := newTestServer(store)
+
+	// stays overlapping on 04-10 and 04-11, so bookings fail on different
+	// nights and have to give back the nights they already booked
+	stays := [][2]string{{"2015-04-09", "2015-04-11"}, {"2015-04-10", "2015-04-12"}, {"2015-04-11", "2015-04-13"}}
+	reqs := make([]*pb.Request, 0)
+	for i := 0; i < 30; i++ {
+		stay := stays[i%len(stays)]
+		reqs = append(reqs, &pb.Request{
+			CustomerName: fmt.Sprintf("Cornell_%d", i),
+			HotelId:      []string{"1"},
+			InDate:       stay[0],
+			OutDate:      stay[1],
+			RoomNumber:   int32(1 + i%2),
+		})
+	}
+	reserveConcurrently(t, s, reqs)
+
+	booked := store.booked("1")
+	for _, n := range []night{{"2015-04-09", "2015-04-10"}, {"2015-04-10", "2015-04-11"}, {"2015-04-11", "2015-04-12"}, {"2015-04-12", "2015-04-13"}} {
+		if booked[n.inDate] > hotelCap {
+			t.Errorf("got %d rooms booked on %s, above capacity %d", booked[n.inDate], n.inDate, hotelCap)
+		}
+		if occupancy := store.occupancy[n.memcKey("1")]; occupancy != booked[n.inDate] {
+			t.Errorf("got occupancy %d on %s, want the %d rooms booked", occupancy, n.inDate, booked[n.inDate])
+		}
+	}
+}
+
+func TestMakeReservationInvalidRoomNumber(t *testing.T) {
+	store := newMemStore(map[string]int{"1": 5})
+	s := newTestServer(store)
+
+	for _, number := range []int32{0, -3} {
+		_, err := s.MakeReservation(context.Background(), &pb.Request{
+			CustomerName: "Cornell_1",
+			HotelId:      []string{"1"},
+			InDate:       "2015-04-09",
+			OutDate:      "2015-04-10",
+			RoomNumber:   number,
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("RoomNumber %d: got %v, want InvalidArgument", number, err)
+		}
+	}
+	if len(store.rows) != 0 {
+		t.Errorf("got %d reservation rows, want none", len(store.rows))
+	}
+}
+
+func TestMakeReservationInvalidatesAfterInsert(t *testing.T) {
+	store := newMemStore(map[string]int{"1": 5})
+	s := newTestServer(store)
+
+	res, err := s.MakeReservation(context.Background(), &pb.Request{
+		CustomerName: "Cornell_1",
+		HotelId:      []string{"1"},
+		InDate:       "2015-04-09",
+		OutDate:      "2015-04-11",
+		RoomNumber:   1,
+	})
+	if err != nil || res.Failure != pb.FailureReason_NONE {
+		t.Fatalf("MakeReservation: %v, %v", res, err)
+	}
+	if len(store.invalidated) != 2 {
+		t.Fatalf("got %d invalidations, want one per night", len(store.invalidated))
+	}
+	// a count cached before the rows are stored would stay stale
+	for _, rows := range store.invalidated {
+		if rows != 2 {
+			t.Errorf("invalidated with %d rows stored, want 2", rows)
+		}
+	}
+}
+
+func TestMakeReservationPricesWithoutOwnRooms(t *testing.T) {
+	store := newMemStore(map[string]int{"1": 10})
+	s := newTestServer(store)
+	rates := &fixedRates{price: 100}
+	s.rateClient = rates
+
+	reserve := func(customer string, rooms int32) *pb.Result {
+		res, err := s.MakeReservation(context.Background(), &pb.Request{
+			CustomerName: customer,
+			HotelId:      []string{"1"},
+			InDate:       "2015-04-09",
+			OutDate:      "2015-04-11",
+			RoomNumber:   rooms,
+		})
+		if err != nil {
+			t.Fatalf("MakeReservation: %v", err)
+		}
+		return res
+	}
+
+	reserve("Cornell_1", 3)
+	res := reserve("Cornell_2", 2)
+	if res.Failure != pb.FailureReason_NONE {
+		t.Fatalf("got failure %s", res.Failure)
+	}
+
+	// the second stay is priced with the 3 rooms booked before it, passed
+	// along so the rate service never calls back for the occupancy
+	occ := rates.reqs[1].Occupancy["1"]
+	if occ == nil || occ.Capacity != 10 {
+		t.Fatalf("got occupancy %v, want capacity 10", occ)
+	}
+	for _, date := range []string{"2015-04-09", "2015-04-10"} {
+		if occ.Booked[date] != 3 {
+			t.Errorf("got %d rooms booked on %s, want 3", occ.Booked[date], date)
+		}
+	}
+	if res.TotalPrice != 400 || len(res.Nights) != 2 || res.Nights[0].Price != 200 {
+		t.Errorf("got total %v and nights %v, want 400 over 2 nights", res.TotalPrice, res.Nights)
+	}
+
+	// a stay that cannot be booked comes without a price
+	res = reserve("Cornell_3", 6)
+	if res.Failure != pb.FailureReason_NO_CAPACITY || res.TotalPrice != 0 || len(res.Nights) != 0 {
+		t.Errorf("got %v, want NO_CAPACITY without a price", res)
+	}
+}
@@ -0,0 +1,190 @@
+package reservation
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// bookingStore holds what bookings are made against: the capacity of hotels,
+// the per-night occupancy counters and the reservation rows.
+type bookingStore interface {
+	// capacity returns the number of rooms of a hotel, wrapping
+	// mongo.ErrNoDocuments for unknown hotels
+	capacity(ctx context.Context, hotelId string) (int, error)
+	// book adds number rooms to the occupancy of a night unless that takes it
+	// above hotelCap, and reports whether it did
+	book(ctx context.Context, hotelId string, n night, number int, hotelCap int) (bool, error)
+	// release takes number rooms off the occupancy of a night
+	release(ctx context.Context, hotelId string, n night, number int) error
+	// invalidate drops the cached reservation count of a night
+	invalidate(hotelId string, n night) error
+	// insert stores reservation rows, removing those it added on failure
+	insert(ctx context.Context, rows []reservation) error
+	// bookedRooms returns the rooms of the reservation rows by hotel id and
+	// by the date their night starts
+	bookedRooms(ctx context.Context, hotelIds []string, dates []string) (map[string]map[string]int, error)
+}
+
+// mongoStore keeps bookings in reservation-db, with capacities and the
+// reservation counts of CheckAvailability cached in memcached
+type mongoStore struct {
+	database *mongo.Database
+	memc     *memcache.Client
+}
+
+func newMongoStore(client *mongo.Client, memc *memcache.Client) *mongoStore {
+	return &mongoStore{database: client.Database("reservation-db"), memc: memc}
+}
+
+// capacity returns the number of rooms of a hotel, from memcached if possible.
+func (m *mongoStore) capacity(ctx context.Context, hotelId string) (int, error) {
+	memcCapKey := hotelId + "_cap"
+	item, err := m.memc.Get(memcCapKey)
+	if err == nil {
+		hotelCap, _ := strconv.Atoi(string(item.Value))
+		return hotelCap, nil
+	} else if err != memcache.ErrCacheMiss {
+		return 0, fmt.Errorf("memcached get %s: %v", memcCapKey, err)
+	}
+
+	var num number
+	err = m.database.Collection("number").FindOne(ctx, bson.M{"hotelId": hotelId}).Decode(&num)
+	if err != nil {
+		return 0, fmt.Errorf("find capacity of hotel %s: %w", hotelId, err)
+	}
+
+	// write to memcache
+	m.memc.Set(&memcache.Item{Key: memcCapKey, Value: []byte(strconv.Itoa(num.Number))})
+	return num.Number, nil
+}
+
+// book uses a conditional $inc on the occupancy counter of the night, so
+// concurrent bookings can never push it above capacity
+func (m *mongoStore) book(ctx context.Context, hotelId string, n night, number int, hotelCap int) (bool, error) {
+	if err := m.ensureOccupancy(ctx, hotelId, n); err != nil {
+		return false, err
+	}
+
+	err := m.database.Collection("occupancy").FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"hotelId": hotelId,
+			"inDate":  n.inDate,
+			"outDate": n.outDate,
+			"number":  bson.M{"$lte": hotelCap - number},
+		},
+		bson.M{"$inc": bson.M{"number": number}},
+	).Err()
+	if err == mongo.ErrNoDocuments {
+		// the night is full
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("increment occupancy of hotel %s on %s: %v", hotelId, n.inDate, err)
+	}
+	return true, nil
+}
+
+func (m *mongoStore) release(ctx context.Context, hotelId string, n night, number int) error {
+	_, err := m.database.Collection("occupancy").UpdateOne(
+		ctx,
+		bson.M{"hotelId": hotelId, "inDate": n.inDate, "outDate": n.outDate},
+		bson.M{"$inc": bson.M{"number": -number}},
+	)
+	return err
+}
+
+// ensureOccupancy creates the occupancy counter of a night if it does not
+// exist yet, seeded with the rooms already booked in the reservation collection.
+func (m *mongoStore) ensureOccupancy(ctx context.Context, hotelId string, n night) error {
+	occCollection := m.database.Collection("occupancy")
+	filter := bson.M{"hotelId": hotelId, "inDate": n.inDate, "outDate": n.outDate}
+
+	err := occCollection.FindOne(ctx, filter).Err()
+	if err == nil {
+		return nil
+	} else if err != mongo.ErrNoDocuments {
+		return fmt.Errorf("find occupancy of hotel %s on %s: %v", hotelId, n.inDate, err)
+	}
+
+	var reserve []reservation
+	curr, err := m.database.Collection("reservation").Find(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("find reservations of hotel %s on %s: %v", hotelId, n.inDate, err)
+	}
+	if err := curr.All(ctx, &reserve); err != nil {
+		return fmt.Errorf("decode reservations of hotel %s on %s: %v", hotelId, n.inDate, err)
+	}
+	count := 0
+	for _, r := range reserve {
+		count += r.Number
+	}
+
+	// concurrent callers race on the unique (hotelId, inDate, outDate) index;
+	// the loser simply uses the counter the winner created
+	_, err = occCollection.UpdateOne(
+		ctx,
+		filter,
+		bson.M{"$setOnInsert": bson.M{"number": count}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("create occupancy of hotel %s on %s: %v", hotelId, n.inDate, err)
+	}
+	return nil
+}
+
+func (m *mongoStore) invalidate(hotelId string, n night) error {
+	err := m.memc.Delete(n.memcKey(hotelId))
+	if err == memcache.ErrCacheMiss {
+		return nil
+	}
+	return err
+}
+
+// insert removes the rows by the _id they were given, so a failed insert
+// never touches other rows of the same reservation
+func (m *mongoStore) insert(ctx context.Context, rows []reservation) error {
+	docs := make([]interface{}, 0, len(rows))
+	ids := make([]primitive.ObjectID, 0, len(rows))
+	for _, r := range rows {
+		docs = append(docs, r)
+		ids = append(ids, r.Id)
+	}
+	resCollection := m.database.Collection("reservation")
+	_, err := resCollection.InsertMany(ctx, docs)
+	if err != nil {
+		// drop whatever part of the booking made it into the collection
+		resCollection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	}
+	return err
+}
+
+func (m *mongoStore) bookedRooms(ctx context.Context, hotelIds []string, dates []string) (map[string]map[string]int, error) {
+	curr, err := m.database.Collection("reservation").Find(ctx, bson.M{
+		"hotelId": bson.M{"$in": hotelIds},
+		"inDate":  bson.M{"$in": dates},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find reservations of %d hotels: %v", len(hotelIds), err)
+	}
+	var rows []reservation
+	if err := curr.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("decode reservations of %d hotels: %v", len(hotelIds), err)
+	}
+
+	booked := make(map[string]map[string]int, len(hotelIds))
+	for _, r := range rows {
+		if booked[r.HotelId] == nil {
+			booked[r.HotelId] = make(map[string]int)
+		}
+		booked[r.HotelId][r.InDate] += r.Number
+	}
+	return booked, nil
+}
@@ -19,7 +19,11 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Review_GetReviews_FullMethodName = "/review.Review/GetReviews"
+	Review_GetReviews_FullMethodName       = "/review.Review/GetReviews"
+	Review_PostReview_FullMethodName       = "/review.Review/PostReview"
+	Review_GetReview_FullMethodName        = "/review.Review/GetReview"
+	Review_DeleteReview_FullMethodName     = "/review.Review/DeleteReview"
+	Review_GetRatingSummary_FullMethodName = "/review.Review/GetRatingSummary"
 )
 
 // ReviewClient is the client API for Review service.
@@ -27,6 +31,14 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ReviewClient interface {
 	GetReviews(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
+	// PostReview stores a new review and returns it with its id and date
+	PostReview(ctx context.Context, in *ReviewComm, opts ...grpc.CallOption) (*ReviewComm, error)
+	// GetReview returns a single review by its id
+	GetReview(ctx context.Context, in *ReviewRequest, opts ...grpc.CallOption) (*ReviewComm, error)
+	// DeleteReview removes a review and returns it
+	DeleteReview(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*ReviewComm, error)
+	// GetRatingSummary aggregates the review ratings of each hotel
+	GetRatingSummary(ctx context.Context, in *RatingSummaryRequest, opts ...grpc.CallOption) (*RatingSummaryResult, error)
 }
 
 type reviewClient struct {
@@ -46,11 +58,55 @@ func (c *reviewClient) GetReviews(ctx context.Context, in *Request, opts ...grpc
 	return out, nil
 }
 
+func (c *reviewClient) PostReview(ctx context.Context, in *ReviewComm, opts ...grpc.CallOption) (*ReviewComm, error) {
+	out := new(ReviewComm)
+	err := c.cc.Invoke(ctx, Review_PostReview_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reviewClient) GetReview(ctx context.Context, in *ReviewRequest, opts ...grpc.CallOption) (*ReviewComm, error) {
+	out := new(ReviewComm)
+	err := c.cc.Invoke(ctx, Review_GetReview_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reviewClient) DeleteReview(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*ReviewComm, error) {
+	out := new(ReviewComm)
+	err := c.cc.Invoke(ctx, Review_DeleteReview_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reviewClient) GetRatingSummary(ctx context.Context, in *RatingSummaryRequest, opts ...grpc.CallOption) (*RatingSummaryResult, error) {
+	out := new(RatingSummaryResult)
+	err := c.cc.Invoke(ctx, Review_GetRatingSummary_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ReviewServer is the server API for Review service.
 // All implementations must embed UnimplementedReviewServer
 // for forward compatibility
 type ReviewServer interface {
 	GetReviews(context.Context, *Request) (*Result, error)
+	// PostReview stores a new review and returns it with its id and date
+	PostReview(context.Context, *ReviewComm) (*ReviewComm, error)
+	// GetReview returns a single review by its id
+	GetReview(context.Context, *ReviewRequest) (*ReviewComm, error)
+	// DeleteReview removes a review and returns it
+	DeleteReview(context.Context, *DeleteRequest) (*ReviewComm, error)
+	// GetRatingSummary aggregates the review ratings of each hotel
+	GetRatingSummary(context.Context, *RatingSummaryRequest) (*RatingSummaryResult, error)
 	mustEmbedUnimplementedReviewServer()
 }
 
@@ -61,6 +117,18 @@ type UnimplementedReviewServer struct {
 func (UnimplementedReviewServer) GetReviews(context.Context, *Request) (*Result, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetReviews not implemented")
 }
+func (UnimplementedReviewServer) PostReview(context.Context, *ReviewComm) (*ReviewComm, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PostReview not implemented")
+}
+func (UnimplementedReviewServer) GetReview(context.Context, *ReviewRequest) (*ReviewComm, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReview not implemented")
+}
+func (UnimplementedReviewServer) DeleteReview(context.Context, *DeleteRequest) (*ReviewComm, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteReview not implemented")
+}
+func (UnimplementedReviewServer) GetRatingSummary(context.Context, *RatingSummaryRequest) (*RatingSummaryResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRatingSummary not implemented")
+}
 func (UnimplementedReviewServer) mustEmbedUnimplementedReviewServer() {}
 
 // UnsafeReviewServer may be embedded to opt out of forward compatibility for this service.
@@ -92,6 +160,78 @@ func _Review_GetReviews_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Review_PostReview_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReviewComm)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReviewServer).PostReview(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Review_PostReview_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReviewServer).PostReview(ctx, req.(*ReviewComm))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Review_GetReview_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReviewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReviewServer).GetReview(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Review_GetReview_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReviewServer).GetReview(ctx, req.(*ReviewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Review_DeleteReview_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReviewServer).DeleteReview(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Review_DeleteReview_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReviewServer).DeleteReview(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Review_GetRatingSummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RatingSummaryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReviewServer).GetRatingSummary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Review_GetRatingSummary_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReviewServer).GetRatingSummary(ctx, req.(*RatingSummaryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Review_ServiceDesc is the grpc.ServiceDesc for Review service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -103,6 +243,22 @@ var Review_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetReviews",
 			Handler:    _Review_GetReviews_Handler,
 		},
+		{
+			MethodName: "PostReview",
+			Handler:    _Review_PostReview_Handler,
+		},
+		{
+			MethodName: "GetReview",
+			Handler:    _Review_GetReview_Handler,
+		},
+		{
+			MethodName: "DeleteReview",
+			Handler:    _Review_DeleteReview_Handler,
+		},
+		{
+			MethodName: "GetRatingSummary",
+			Handler:    _Review_GetRatingSummary_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "services/review/proto/review.proto",
@@ -1,8 +1,10 @@
 package review
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -10,23 +12,30 @@ import (
 	// "io/ioutil"
 	"net"
 	// "os"
-	// "sort"
 	"time"
 	//"sync"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/dialer"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
+	image "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/image/proto"
 	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/review/proto"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 
 	// "strings"
 
@@ -35,6 +44,14 @@ import (
 
 const name = "srv-review"
 
+const (
+	// ratingBuckets is the number of histogram buckets of a rating summary
+	ratingBuckets = 5
+	// summaryTTL bounds how long a cached summary can outlive a write that
+	// raced with its computation, in seconds
+	summaryTTL = 300
+)
+
 // Server implements the rate service
 type Server struct {
 	pb.UnimplementedReviewServer
@@ -43,17 +60,26 @@ type Server struct {
 	Port        int
 	IpAddr      string
 	MongoClient *mongo.Client
-	Registry    *registry.Client
+	Registry    registry.Registry
 	MemcClient  *memcache.Client
 	uuid        string
+	grpcServer  *grpc.Server
+	listener    net.Listener
+	health      *health.Server
+	imageClient image.ImageClient
 }
 
-// Run starts the server
-func (s *Server) Run() error {
+// Setup creates the server, listens on Port and registers in consul. It is
+// called before Run, so Shutdown only reads what Setup wrote.
+func (s *Server) Setup() error {
 	if s.Port == 0 {
 		return fmt.Errorf("server port must be set")
 	}
 
+	if err := s.initImageClient("srv-image"); err != nil {
+		return err
+	}
+
 	s.uuid = uuid.New().String()
 
 	opts := []grpc.ServerOption{
@@ -64,15 +90,22 @@ func (s *Server) Run() error {
 			PermitWithoutStream: true,
 		}),
 		grpc.UnaryInterceptor(
-			otelgrpc.UnaryServerInterceptor(),
+			otelgrpc.UnaryServerInterceptor(
+				otelgrpc.WithInterceptorFilter(filters.Not(filters.HealthCheck())),
+			),
 		),
 	}
 
 	if tlsopt := tls.GetServerOpt(); tlsopt != nil {
-		opts = append(opts, tlsopt)
+		opts = append(opts, tlsopt, grpc.ChainUnaryInterceptor(tls.UnaryServerInterceptor()))
 	}
 
 	srv := grpc.NewServer(opts...)
+	s.grpcServer = srv
+
+	// answers the consul check, serving until Shutdown
+	s.health = health.NewServer()
+	healthpb.RegisterHealthServer(srv, s.health)
 
 	pb.RegisterReviewServer(srv, s)
 
@@ -87,12 +120,46 @@ func (s *Server) Run() error {
 	}
 	log.Info().Msg("Successfully registered in consul")
 
-	return srv.Serve(lis)
+	s.listener = lis
+	return nil
+}
+
+// Run serves the server set up by Setup until Shutdown
+func (s *Server) Run() error {
+	return s.grpcServer.Serve(s.listener)
+}
+
+// Shutdown fails the health check and deregisters the service so no new
+// calls are routed here, then waits for pending calls until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+	err := s.Registry.Deregister(s.uuid)
+	shutdown.GRPC(ctx, s.grpcServer)
+	return err
 }
 
-// Shutdown cleans up any processes
-func (s *Server) Shutdown() {
-	s.Registry.Deregister(s.uuid)
+func (s *Server) initImageClient(name string) error {
+	conn, err := s.getGprcConn(name)
+	if err != nil {
+		return fmt.Errorf("dialer error: %v", err)
+	}
+	s.imageClient = image.NewImageClient(conn)
+	return nil
+}
+
+func (s *Server) getGprcConn(name string) (*grpc.ClientConn, error) {
+	target, err := s.Registry.Target(name)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.Dial(
+		target,
+		dialer.WithTracer(s.Tracer),
+		dialer.WithBalancer(""),
+		dialer.WithEnvPolicies(),
+	)
 }
 
 type ReviewHelper struct {
@@ -102,6 +169,7 @@ type ReviewHelper struct {
 	Rating      float32   `bson:"rating"`
 	Description string    `bson:"description"`
 	Image       *pb.Image `bson:"images"`
+	CreatedAt   int64     `bson:"createdAt,omitempty"`
 }
 
 type ImageHelper struct {
@@ -147,6 +215,7 @@ func (s *Server) GetReviews(ctx context.Context, req *pb.Request) (*pb.Result, e
 	} else {
 		if err == memcache.ErrCacheMiss {
 			logger.Debug().Msgf("Review cache miss, fetching from database: hotel_id=%s", hotelId)
+			version, versionErr := s.reviewsVersion(hotelId)
 			
 			_, mongoSpan := s.Tracer.Start(ctx, "mongo_review")
 			mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
@@ -169,24 +238,17 @@ func (s *Server) GetReviews(ctx context.Context, req *pb.Request) (*pb.Result, e
 			}
 
 			for _, reviewHelper := range reviewHelpers {
-				revComm := pb.ReviewComm{
-					ReviewId:    reviewHelper.ReviewId,
-					Name:        reviewHelper.Name,
-					Rating:      reviewHelper.Rating,
-					Description: reviewHelper.Description,
-					Images:      reviewHelper.Image}
-				reviews = append(reviews, &revComm)
+				reviews = append(reviews, reviewHelper.toReviewComm())
 			}
 
 			reviewJson, err := json.Marshal(reviews)
 			if err != nil {
 				logger.Error().Msgf("Failed to marshal reviews: hotel_id=%s, error=%v", hotelId, err)
 			}
-			memcStr := string(reviewJson)
-
-			s.MemcClient.Set(&memcache.Item{Key: hotelId, Value: []byte(memcStr)})
-			
-			logger.Debug().Msgf("Review cache populated: hotel_id=%s, reviews_count=%d", hotelId, len(reviews))
+			if versionErr == nil {
+				s.fillCache(hotelId, version, reviewJson)
+				logger.Debug().Msgf("Review cache populated: hotel_id=%s, reviews_count=%d", hotelId, len(reviews))
+			}
 		} else {
 			reviewsStr := string(item.Value)
 			logger.Debug().Msgf("Review cache hit: hotel_id=%s, size=%d", hotelId, len(reviewsStr))
@@ -198,7 +260,455 @@ func (s *Server) GetReviews(ctx context.Context, req *pb.Request) (*pb.Result, e
 
 	//reviewsEmpty := make([]*pb.ReviewComm, 0)
 
-	res.Reviews = reviews
-	logger.Info().Msgf("Returning reviews: hotel_id=%s, reviews_count=%d", hotelId, len(reviews))
+	page, nextCursor, err := paginate(reviews, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the returned page is looked up in the image service
+	if err := s.attachImages(ctx, page); err != nil {
+		logger.Warn().Msgf("Failed to get review images: hotel_id=%s, error=%v", hotelId, err)
+	}
+
+	res.Reviews = page
+	res.NextCursor = nextCursor
+	logger.Info().Msgf("Returning reviews: hotel_id=%s, reviews_count=%d, total=%d", hotelId, len(page), len(reviews))
+	return res, nil
+}
+
+// attachImages sets the image of every review that has one in the image
+// service, preferring its default image
+func (s *Server) attachImages(ctx context.Context, reviews []*pb.ReviewComm) error {
+	if len(reviews) == 0 {
+		return nil
+	}
+	ids := make([]string, len(reviews))
+	for i, r := range reviews {
+		ids[i] = r.ReviewId
+	}
+
+	list, err := s.imageClient.List(ctx, &image.ListRequest{Owner: image.Owner_REVIEW, OwnerIds: ids})
+	if err != nil {
+		return err
+	}
+	for _, r := range reviews {
+		// images are listed default first
+		if infos := list.Images[r.ReviewId]; infos != nil && len(infos.Images) > 0 {
+			r.Images = &pb.Image{Url: infos.Images[0].Url, Default: infos.Images[0].Default}
+		}
+	}
+	return nil
+}
+
+func (h *ReviewHelper) toReviewComm() *pb.ReviewComm {
+	return &pb.ReviewComm{
+		ReviewId:    h.ReviewId,
+		HotelId:     h.HotelId,
+		Name:        h.Name,
+		Rating:      h.Rating,
+		Description: h.Description,
+		Images:      h.Image,
+		CreatedAt:   h.CreatedAt,
+	}
+}
+
+// PostReview stores a new review and adds it to the cached reviews of the hotel
+func (s *Server) PostReview(ctx context.Context, req *pb.ReviewComm) (*pb.ReviewComm, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	if req.HotelId == "" || req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "hotelId and name are required")
+	}
+	if req.Rating < 0 || req.Rating > 5 {
+		return nil, status.Errorf(codes.InvalidArgument, "rating %v out of range [0, 5]", req.Rating)
+	}
+
+	review := &ReviewHelper{
+		ReviewId:    uuid.New().String(),
+		HotelId:     req.HotelId,
+		Name:        req.Name,
+		Rating:      req.Rating,
+		Description: req.Description,
+		Image:       req.Images,
+		CreatedAt:   time.Now().Unix(),
+	}
+
+	_, mongoSpan := s.Tracer.Start(ctx, "mongo_review_insert")
+	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	_, err := s.MongoClient.Database("review-db").Collection("reviews").InsertOne(ctx, review)
+	mongoSpan.End()
+	if err != nil {
+		logger.Error().Msgf("Failed to store review: hotel_id=%s, error=%v", req.HotelId, err)
+		return nil, err
+	}
+
+	comm := review.toReviewComm()
+	s.updateCache(ctx, review.HotelId, func(reviews []*pb.ReviewComm) []*pb.ReviewComm {
+		return append(reviews, comm)
+	})
+	s.invalidateSummary(review.HotelId)
+
+	logger.Info().Msgf("Review posted: hotel_id=%s, review_id=%s", review.HotelId, review.ReviewId)
+	return comm, nil
+}
+
+// GetReview reads a single review from mongodb
+func (s *Server) GetReview(ctx context.Context, req *pb.ReviewRequest) (*pb.ReviewComm, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	if req.ReviewId == "" {
+		return nil, status.Error(codes.InvalidArgument, "reviewId is required")
+	}
+
+	var review ReviewHelper
+	_, mongoSpan := s.Tracer.Start(ctx, "mongo_review_get")
+	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	err := s.MongoClient.Database("review-db").Collection("reviews").
+		FindOne(ctx, bson.M{"reviewId": req.ReviewId}).Decode(&review)
+	mongoSpan.End()
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.NotFound, "review %s not found", req.ReviewId)
+	} else if err != nil {
+		logger.Error().Msgf("Failed to get review: review_id=%s, error=%v", req.ReviewId, err)
+		return nil, err
+	}
+	return review.toReviewComm(), nil
+}
+
+// DeleteReview removes a review and drops it from the cached reviews of the
+// hotel. Only reviews of the requested author are deleted.
+func (s *Server) DeleteReview(ctx context.Context, req *pb.DeleteRequest) (*pb.ReviewComm, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	if req.ReviewId == "" || req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "reviewId and name are required")
+	}
+
+	filter := bson.M{"reviewId": req.ReviewId, "name": req.Name}
+
+	var review ReviewHelper
+	c := s.MongoClient.Database("review-db").Collection("reviews")
+	_, mongoSpan := s.Tracer.Start(ctx, "mongo_review_delete")
+	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	err := c.FindOneAndDelete(ctx, filter).Decode(&review)
+	mongoSpan.End()
+	if err == mongo.ErrNoDocuments {
+		if n, err := c.CountDocuments(ctx, bson.M{"reviewId": req.ReviewId}); err == nil && n > 0 {
+			return nil, status.Errorf(codes.PermissionDenied, "review %s was written by another user", req.ReviewId)
+		}
+		return nil, status.Errorf(codes.NotFound, "review %s not found", req.ReviewId)
+	} else if err != nil {
+		logger.Error().Msgf("Failed to delete review: review_id=%s, error=%v", req.ReviewId, err)
+		return nil, err
+	}
+
+	s.updateCache(ctx, review.HotelId, func(reviews []*pb.ReviewComm) []*pb.ReviewComm {
+		kept := reviews[:0]
+		for _, r := range reviews {
+			if r.ReviewId != review.ReviewId {
+				kept = append(kept, r)
+			}
+		}
+		return kept
+	})
+	s.invalidateSummary(review.HotelId)
+
+	logger.Info().Msgf("Review deleted: hotel_id=%s, review_id=%s", review.HotelId, review.ReviewId)
+	return review.toReviewComm(), nil
+}
+
+// reviewsVersionKey holds a token replaced on every change of the reviews of
+// a hotel, see fillCache
+func reviewsVersionKey(hotelId string) string {
+	return "reviews_version_" + hotelId
+}
+
+// reviewsVersion returns the current token of the reviews of a hotel, empty
+// if none was set yet
+func (s *Server) reviewsVersion(hotelId string) (string, error) {
+	item, err := s.MemcClient.Get(reviewsVersionKey(hotelId))
+	if err == memcache.ErrCacheMiss {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return string(item.Value), nil
+}
+
+// fillCache caches the reviews of a hotel loaded from mongo, unless they
+// changed since version was read before loading them. Such a change may have
+// found nothing to update in updateCache, so caching the loaded reviews would
+// hide it until the entry is evicted.
+func (s *Server) fillCache(hotelId, version string, value []byte) {
+	if err := s.MemcClient.Add(&memcache.Item{Key: hotelId, Value: value}); err != nil {
+		// filled by another read meanwhile, or memcached is unavailable
+		return
+	}
+	if current, err := s.reviewsVersion(hotelId); err != nil || current != version {
+		if err := s.MemcClient.Delete(hotelId); err != nil && err != memcache.ErrCacheMiss {
+			log.Error().Msgf("Failed to drop stale cached reviews: hotel_id=%s, error=%v", hotelId, err)
+		}
+	}
+}
+
+// updateCache applies change to the cached reviews of a hotel using
+// compare-and-swap. Nothing is cached on a miss; if the entry changed
+// concurrently or cannot be updated it is deleted, so the next read reloads it.
+// The version of the reviews is replaced first, so reads loading them from
+// mongo meanwhile do not cache them without change.
+func (s *Server) updateCache(ctx context.Context, hotelId string, change func([]*pb.ReviewComm) []*pb.ReviewComm) {
+	_, memSpan := s.Tracer.Start(ctx, "memcached_update_review")
+	memSpan.SetAttributes(attribute.String("span.kind", "client"))
+	defer memSpan.End()
+
+	err := s.MemcClient.Set(&memcache.Item{Key: reviewsVersionKey(hotelId), Value: []byte(uuid.New().String())})
+	if err != nil {
+		log.Error().Msgf("Failed to update reviews version: hotel_id=%s, error=%v", hotelId, err)
+	}
+
+	item, err := s.MemcClient.Get(hotelId)
+	if err == memcache.ErrCacheMiss {
+		memSpan.SetAttributes(attribute.String("cache.action", "none"))
+		return
+	}
+	if err == nil {
+		var reviews []*pb.ReviewComm
+		if err = json.Unmarshal(item.Value, &reviews); err == nil {
+			if item.Value, err = json.Marshal(change(reviews)); err == nil {
+				if err = s.MemcClient.CompareAndSwap(item); err == nil {
+					memSpan.SetAttributes(attribute.String("cache.action", "update"))
+					return
+				}
+			}
+		}
+	}
+
+	memSpan.SetAttributes(attribute.String("cache.action", "invalidate"))
+	if err := s.MemcClient.Delete(hotelId); err != nil && err != memcache.ErrCacheMiss {
+		log.Error().Msgf("Failed to invalidate cached reviews: hotel_id=%s, error=%v", hotelId, err)
+	}
+}
+
+// GetRatingSummary returns count, mean and histogram of the ratings of each
+// requested hotel. Summaries are computed by mongo and cached per hotel until
+// the next review of that hotel is posted or deleted.
+func (s *Server) GetRatingSummary(ctx context.Context, req *pb.RatingSummaryRequest) (*pb.RatingSummaryResult, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	res := &pb.RatingSummaryResult{Summaries: make(map[string]*pb.RatingSummary)}
+
+	// every hotel at once is only asked for in bulk, so skip the cache
+	if len(req.HotelIds) == 0 {
+		summaries, err := s.computeSummaries(ctx, nil)
+		if err != nil {
+			logger.Error().Msgf("Failed to compute rating summaries: error=%v", err)
+			return nil, err
+		}
+		res.Summaries = summaries
+		logger.Info().Msgf("Rating summaries computed: hotels=%d", len(summaries))
+		return res, nil
+	}
+
+	keys := make([]string, 0, len(req.HotelIds))
+	for _, id := range req.HotelIds {
+		keys = append(keys, summaryKey(id))
+	}
+
+	_, memSpan := s.Tracer.Start(ctx, "memcached_get_rating_summary")
+	memSpan.SetAttributes(attribute.String("span.kind", "client"))
+	items, err := s.MemcClient.GetMulti(keys)
+	memSpan.End()
+	if err != nil {
+		logger.Warn().Msgf("Memcached error while getting rating summaries: error=%v", err)
+		items = nil
+	}
+
+	var missing []string
+	for _, id := range req.HotelIds {
+		if item, ok := items[summaryKey(id)]; ok {
+			summary := new(pb.RatingSummary)
+			if err := json.Unmarshal(item.Value, summary); err == nil {
+				if summary.Count > 0 {
+					res.Summaries[id] = summary
+				}
+				continue
+			}
+		}
+		missing = append(missing, id)
+	}
+
+	if len(missing) > 0 {
+		logger.Debug().Msgf("Rating summary cache miss, computing from database: hotels=%d", len(missing))
+		summaries, err := s.computeSummaries(ctx, missing)
+		if err != nil {
+			logger.Error().Msgf("Failed to compute rating summaries: error=%v", err)
+			return nil, err
+		}
+		for _, id := range missing {
+			summary, ok := summaries[id]
+			if ok {
+				res.Summaries[id] = summary
+			} else {
+				// cache hotels without reviews too, so they are not recomputed
+				summary = &pb.RatingSummary{HotelId: id, Histogram: make([]int32, ratingBuckets)}
+			}
+			value, _ := json.Marshal(summary)
+			s.MemcClient.Set(&memcache.Item{Key: summaryKey(id), Value: value, Expiration: summaryTTL})
+		}
+	}
+
+	logger.Info().Msgf("Returning rating summaries: requested=%d, found=%d, computed=%d", len(req.HotelIds), len(res.Summaries), len(missing))
 	return res, nil
 }
+
+type ratingGroup struct {
+	Id struct {
+		HotelId string `bson:"hotelId"`
+		Bucket  int    `bson:"bucket"`
+	} `bson:"_id"`
+	Count int32   `bson:"count"`
+	Sum   float64 `bson:"sum"`
+}
+
+// computeSummaries aggregates the reviews of the given hotels, or of every
+// hotel if hotelIds is nil, grouped by hotel and histogram bucket
+func (s *Server) computeSummaries(ctx context.Context, hotelIds []string) (map[string]*pb.RatingSummary, error) {
+	match := bson.M{}
+	if hotelIds != nil {
+		match["hotelId"] = bson.M{"$in": hotelIds}
+	}
+	bucket := bson.M{"$toInt": bson.M{"$max": bson.A{0, bson.M{"$min": bson.A{ratingBuckets - 1, bson.M{"$floor": "$rating"}}}}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"hotelId": "$hotelId", "bucket": bucket},
+			"count": bson.M{"$sum": 1},
+			"sum":   bson.M{"$sum": "$rating"},
+		}}},
+	}
+
+	_, mongoSpan := s.Tracer.Start(ctx, "mongo_rating_summary")
+	mongoSpan.SetAttributes(attribute.String("span.kind", "client"))
+	defer mongoSpan.End()
+
+	curr, err := s.MongoClient.Database("review-db").Collection("reviews").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var groups []ratingGroup
+	if err := curr.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[string]*pb.RatingSummary)
+	sums := make(map[string]float64)
+	for _, g := range groups {
+		summary, ok := summaries[g.Id.HotelId]
+		if !ok {
+			summary = &pb.RatingSummary{HotelId: g.Id.HotelId, Histogram: make([]int32, ratingBuckets)}
+			summaries[g.Id.HotelId] = summary
+		}
+		summary.Count += g.Count
+		summary.Histogram[g.Id.Bucket] += g.Count
+		sums[g.Id.HotelId] += g.Sum
+	}
+	for id, summary := range summaries {
+		summary.Mean = sums[id] / float64(summary.Count)
+	}
+	return summaries, nil
+}
+
+func summaryKey(hotelId string) string {
+	return "summary_" + hotelId
+}
+
+func (s *Server) invalidateSummary(hotelId string) {
+	if err := s.MemcClient.Delete(summaryKey(hotelId)); err != nil && err != memcache.ErrCacheMiss {
+		log.Error().Msgf("Failed to invalidate rating summary: hotel_id=%s, error=%v", hotelId, err)
+	}
+}
+
+type cursor struct {
+	Key      float64 `json:"k"`
+	ReviewId string  `json:"id"`
+}
+
+func sortKey(r *pb.ReviewComm, by pb.SortBy) float64 {
+	switch by {
+	case pb.SortBy_RATING:
+		return float64(r.Rating)
+	case pb.SortBy_DATE:
+		return float64(r.CreatedAt)
+	}
+	return 0
+}
+
+// paginate sorts reviews as requested and returns the page following
+// req.Cursor. Ties are broken by review id, so every review has a fixed
+// position and pages stay consistent while reviews are added or removed.
+func paginate(reviews []*pb.ReviewComm, req *pb.Request) ([]*pb.ReviewComm, string, error) {
+	if req.PageSize <= 0 && req.Cursor == "" && req.SortBy == pb.SortBy_NONE {
+		return reviews, "", nil
+	}
+
+	// before reports whether (key, id) comes before c in the requested order
+	before := func(key float64, id string, c cursor) bool {
+		if key != c.Key {
+			return (key < c.Key) == req.Ascending
+		}
+		return id < c.ReviewId
+	}
+
+	sorted := make([]*pb.ReviewComm, len(reviews))
+	copy(sorted, reviews)
+	sort.Slice(sorted, func(i, j int) bool {
+		return before(sortKey(sorted[i], req.SortBy), sorted[i].ReviewId,
+			cursor{sortKey(sorted[j], req.SortBy), sorted[j].ReviewId})
+	})
+
+	start := 0
+	if req.Cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(req.Cursor)
+		var c cursor
+		if err == nil {
+			err = json.Unmarshal(raw, &c)
+		}
+		if err != nil {
+			return nil, "", status.Error(codes.InvalidArgument, "malformed cursor")
+		}
+		start = sort.Search(len(sorted), func(i int) bool {
+			r := sorted[i]
+			return !before(sortKey(r, req.SortBy), r.ReviewId, c) && !(sortKey(r, req.SortBy) == c.Key && r.ReviewId == c.ReviewId)
+		})
+	}
+
+	end := len(sorted)
+	if req.PageSize > 0 && start+int(req.PageSize) < end {
+		end = start + int(req.PageSize)
+	}
+	page := sorted[start:end]
+
+	nextCursor := ""
+	if end < len(sorted) && len(page) > 0 {
+		last := page[len(page)-1]
+		raw, _ := json.Marshal(cursor{sortKey(last, req.SortBy), last.ReviewId})
+		nextCursor = base64.RawURLEncoding.EncodeToString(raw)
+	}
+	return page, nextCursor, nil
+}
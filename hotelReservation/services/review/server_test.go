@@ -0,0 +1,169 @@
+package review
+
+import (
+	"encoding/base64"
+	"testing"
+
+	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/review/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testReviews() []*pb.ReviewComm {
+	return []*pb.ReviewComm{
+		{ReviewId: "r1", Rating: 4, CreatedAt: 100},
+		{ReviewId: "r2", Rating: 5, CreatedAt: 300},
+		{ReviewId: "r3", Rating: 4, CreatedAt: 200},
+		{ReviewId: "r4", Rating: 2, CreatedAt: 400},
+		{ReviewId: "r5", Rating: 4, CreatedAt: 500},
+	}
+}
+
+func ids(reviews []*pb.ReviewComm) []string {
+	out := make([]string, len(reviews))
+	for i, r := range reviews {
+		out[i] = r.ReviewId
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPaginateRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *pb.Request
+		want []string
+	}{
+		{
+			name: "rating descending, ties by id",
+			req:  &pb.Request{SortBy: pb.SortBy_RATING, PageSize: 2},
+			want: []string{"r2", "r1", "r3", "r5", "r4"},
+		},
+		{
+			name: "rating ascending",
+			req:  &pb.Request{SortBy: pb.SortBy_RATING, Ascending: true, PageSize: 2},
+			want: []string{"r4", "r1", "r3", "r5", "r2"},
+		},
+		{
+			name: "date descending",
+			req:  &pb.Request{SortBy: pb.SortBy_DATE, PageSize: 3},
+			want: []string{"r5", "r4", "r2", "r3", "r1"},
+		},
+		{
+			name: "page size above the number of reviews",
+			req:  &pb.Request{SortBy: pb.SortBy_DATE, Ascending: true, PageSize: 10},
+			want: []string{"r1", "r3", "r2", "r4", "r5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			pages := 0
+			for {
+				page, next, err := paginate(testReviews(), tt.req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if int32(len(page)) > tt.req.PageSize {
+					t.Fatalf("got a page of %d, want at most %d", len(page), tt.req.PageSize)
+				}
+				got = append(got, ids(page)...)
+				pages++
+				if next == "" {
+					break
+				}
+				if pages > len(tt.want) {
+					t.Fatalf("no last page after %d pages", pages)
+				}
+				tt.req.Cursor = next
+			}
+			if !equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginateLastPage(t *testing.T) {
+	req := &pb.Request{SortBy: pb.SortBy_RATING, PageSize: 2}
+	page, next, err := paginate(testReviews(), req)
+	if err != nil || next == "" {
+		t.Fatalf("first page: %v, %q, %v", ids(page), next, err)
+	}
+
+	// a page ending exactly at the last review has no cursor
+	req.PageSize = 3
+	req.Cursor = next
+	page, next, err = paginate(testReviews(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"r3", "r5", "r4"}; !equal(ids(page), want) {
+		t.Errorf("got %v, want %v", ids(page), want)
+	}
+	if next != "" {
+		t.Errorf("got cursor %q after the last review", next)
+	}
+}
+
+func TestPaginateCursorOfRemovedReview(t *testing.T) {
+	req := &pb.Request{SortBy: pb.SortBy_RATING, PageSize: 2}
+	_, next, err := paginate(testReviews(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// r1 ended the first page and is deleted before the next one is read
+	var left []*pb.ReviewComm
+	for _, r := range testReviews() {
+		if r.ReviewId != "r1" {
+			left = append(left, r)
+		}
+	}
+	req.Cursor = next
+	page, _, err := paginate(left, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"r3", "r5"}; !equal(ids(page), want) {
+		t.Errorf("got %v, want %v", ids(page), want)
+	}
+}
+
+func TestPaginateMalformedCursor(t *testing.T) {
+	_, next, err := paginate(testReviews(), &pb.Request{SortBy: pb.SortBy_RATING, PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!not-base64!!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"k":4,"id":"r1"}`))},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("r1"))},
+		{"wrong types", base64.RawURLEncoding.EncodeToString([]byte(`{"k":"4","id":1}`))},
+		{"truncated", next[:len(next)-3]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := paginate(testReviews(), &pb.Request{SortBy: pb.SortBy_RATING, PageSize: 2, Cursor: tt.cursor})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("got %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
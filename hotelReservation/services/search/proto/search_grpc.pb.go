@@ -20,6 +20,7 @@ const _ = grpc.SupportPackageIsVersion7
 
 const (
 	Search_Nearby_FullMethodName = "/search.Search/Nearby"
+	Search_City_FullMethodName   = "/search.Search/City"
 )
 
 // SearchClient is the client API for Search service.
@@ -27,6 +28,7 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type SearchClient interface {
 	Nearby(ctx context.Context, in *NearbyRequest, opts ...grpc.CallOption) (*SearchResult, error)
+	City(ctx context.Context, in *CityRequest, opts ...grpc.CallOption) (*SearchResult, error)
 }
 
 type searchClient struct {
@@ -46,11 +48,21 @@ func (c *searchClient) Nearby(ctx context.Context, in *NearbyRequest, opts ...gr
 	return out, nil
 }
 
+func (c *searchClient) City(ctx context.Context, in *CityRequest, opts ...grpc.CallOption) (*SearchResult, error) {
+	out := new(SearchResult)
+	err := c.cc.Invoke(ctx, Search_City_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SearchServer is the server API for Search service.
 // All implementations must embed UnimplementedSearchServer
 // for forward compatibility
 type SearchServer interface {
 	Nearby(context.Context, *NearbyRequest) (*SearchResult, error)
+	City(context.Context, *CityRequest) (*SearchResult, error)
 	mustEmbedUnimplementedSearchServer()
 }
 
@@ -61,6 +73,9 @@ type UnimplementedSearchServer struct {
 func (UnimplementedSearchServer) Nearby(context.Context, *NearbyRequest) (*SearchResult, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Nearby not implemented")
 }
+func (UnimplementedSearchServer) City(context.Context, *CityRequest) (*SearchResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method City not implemented")
+}
 func (UnimplementedSearchServer) mustEmbedUnimplementedSearchServer() {}
 
 // UnsafeSearchServer may be embedded to opt out of forward compatibility for this service.
@@ -92,6 +107,24 @@ func _Search_Nearby_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Search_City_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).City(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Search_City_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).City(ctx, req.(*CityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Search_ServiceDesc is the grpc.ServiceDesc for Search service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -103,6 +136,10 @@ var Search_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Nearby",
 			Handler:    _Search_Nearby_Handler,
 		},
+		{
+			MethodName: "City",
+			Handler:    _Search_City_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "services/search/proto/search.proto",
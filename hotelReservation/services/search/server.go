@@ -8,17 +8,21 @@ import (
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/dialer"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
 	geo "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/geo/proto"
+	profile "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/profile/proto"
 	rate "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/rate/proto"
 	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/search/proto"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 	"github.com/google/uuid"
-	_ "github.com/mbobakov/grpc-consul-resolver"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"go.opentelemetry.io/otel/trace"
 	context "golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 )
 
@@ -28,20 +32,23 @@ const name = "srv-search"
 type Server struct {
 	pb.UnimplementedSearchServer
 
-	geoClient  geo.GeoClient
-	rateClient rate.RateClient
-	uuid       string
+	geoClient     geo.GeoClient
+	rateClient    rate.RateClient
+	profileClient profile.ProfileClient
+	uuid          string
+	grpcServer    *grpc.Server
+	listener      net.Listener
+	health        *health.Server
 
-	Tracer     trace.Tracer
-	Port       int
-	IpAddr     string
-	ConsulAddr string
-	KnativeDns string
-	Registry   *registry.Client
+	Tracer   trace.Tracer
+	Port     int
+	IpAddr   string
+	Registry registry.Registry
 }
 
-// Run starts the server
-func (s *Server) Run() error {
+// Setup creates the server, listens on Port and registers in consul. It is
+// called before Run, so Shutdown only reads what Setup wrote.
+func (s *Server) Setup() error {
 	if s.Port == 0 {
 		return fmt.Errorf("server port must be set")
 	}
@@ -56,15 +63,23 @@ func (s *Server) Run() error {
 			PermitWithoutStream: true,
 		}),
 		grpc.UnaryInterceptor(
-			otelgrpc.UnaryServerInterceptor(),
+			otelgrpc.UnaryServerInterceptor(
+				otelgrpc.WithInterceptorFilter(filters.Not(filters.HealthCheck())),
+			),
 		),
 	}
 
 	if tlsopt := tls.GetServerOpt(); tlsopt != nil {
-		opts = append(opts, tlsopt)
+		opts = append(opts, tlsopt, grpc.ChainUnaryInterceptor(tls.UnaryServerInterceptor()))
 	}
 
 	srv := grpc.NewServer(opts...)
+	s.grpcServer = srv
+
+	// answers the consul check, serving until Shutdown
+	s.health = health.NewServer()
+	healthpb.RegisterHealthServer(srv, s.health)
+
 	pb.RegisterSearchServer(srv, s)
 
 	// init grpc clients
@@ -74,6 +89,9 @@ func (s *Server) Run() error {
 	if err := s.initRateClient("srv-rate"); err != nil {
 		return err
 	}
+	if err := s.initProfileClient("srv-profile"); err != nil {
+		return err
+	}
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.Port))
 	if err != nil {
@@ -86,12 +104,24 @@ func (s *Server) Run() error {
 	}
 	log.Info().Msg("Successfully registered in consul")
 
-	return srv.Serve(lis)
+	s.listener = lis
+	return nil
 }
 
-// Shutdown cleans up any processes
-func (s *Server) Shutdown() {
-	s.Registry.Deregister(s.uuid)
+// Run serves the server set up by Setup until Shutdown
+func (s *Server) Run() error {
+	return s.grpcServer.Serve(s.listener)
+}
+
+// Shutdown fails the health check and deregisters the service so no new
+// calls are routed here, then waits for pending calls until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+	err := s.Registry.Deregister(s.uuid)
+	shutdown.GRPC(ctx, s.grpcServer)
+	return err
 }
 
 func (s *Server) initGeoClient(name string) error {
@@ -112,18 +142,26 @@ func (s *Server) initRateClient(name string) error {
 	return nil
 }
 
+func (s *Server) initProfileClient(name string) error {
+	conn, err := s.getGprcConn(name)
+	if err != nil {
+		return fmt.Errorf("dialer error: %v", err)
+	}
+	s.profileClient = profile.NewProfileClient(conn)
+	return nil
+}
+
 func (s *Server) getGprcConn(name string) (*grpc.ClientConn, error) {
-	if s.KnativeDns != "" {
-		return dialer.Dial(
-			fmt.Sprintf("consul://%s/%s.%s", s.ConsulAddr, name, s.KnativeDns),
-			dialer.WithTracer(s.Tracer))
-	} else {
-		return dialer.Dial(
-			fmt.Sprintf("consul://%s/%s", s.ConsulAddr, name),
-			dialer.WithTracer(s.Tracer),
-			dialer.WithBalancer(s.Registry.Client),
-		)
+	target, err := s.Registry.Target(name)
+	if err != nil {
+		return nil, err
 	}
+	return dialer.Dial(
+		target,
+		dialer.WithTracer(s.Tracer),
+		dialer.WithBalancer(""),
+		dialer.WithEnvPolicies(),
+	)
 }
 
 // Nearby returns ids of nearby hotels ordered by ranking algo
@@ -156,8 +194,12 @@ func (s *Server) Nearby(ctx context.Context, req *pb.NearbyRequest) (*pb.SearchR
 	logger.Debug().Msg("Querying geo service for nearby hotels")
 
 	nearby, err := s.geoClient.Nearby(ctx, &geo.Request{
-		Lat: req.Lat,
-		Lon: req.Lon,
+		Lat:             req.Lat,
+		Lon:             req.Lon,
+		Radius:          req.Radius,
+		Limit:           req.Limit,
+		ExcludeHotelIds: req.ExcludeHotelIds,
+		MinRating:       req.MinRating,
 	})
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to get nearby hotels from geo service")
@@ -176,6 +218,7 @@ func (s *Server) Nearby(ctx context.Context, req *pb.NearbyRequest) (*pb.SearchR
 		HotelIds: nearby.HotelIds,
 		InDate:   req.InDate,
 		OutDate:  req.OutDate,
+		Currency: req.Currency,
 	})
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to get rates from rate service")
@@ -193,8 +236,99 @@ func (s *Server) Nearby(ctx context.Context, req *pb.NearbyRequest) (*pb.SearchR
 		logger.Trace().Msgf("Adding hotel to search results: hotel_id=%s, rate_code=%s", ratePlan.HotelId, ratePlan.Code)
 		res.HotelIds = append(res.HotelIds, ratePlan.HotelId)
 	}
+	res.Prices = cheapestPrices(rates.RatePlans)
 	
 	logger.Info().Msgf("Search nearby completed: results_count=%d", len(res.HotelIds))
 	
 	return res, nil
 }
+
+// City returns ids of hotels in a city, or matching a postal code or keyword
+func (s *Server) City(ctx context.Context, req *pb.CityRequest) (*pb.SearchResult, error) {
+	// Get logger with trace context
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		// If no logger in context, use global logger
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+	
+	// Extract trace information and add to logger
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		spanCtx := span.SpanContext()
+		if spanCtx.HasTraceID() {
+			newLogger := logger.With().Str("trace_id", spanCtx.TraceID().String()).Logger()
+			logger = &newLogger
+		}
+		if spanCtx.HasSpanID() {
+			newLogger := logger.With().Str("span_id", spanCtx.SpanID().String()).Logger()
+			logger = &newLogger
+		}
+	}
+	
+	logger.Info().Msgf("Searching hotels by city: city=%s, postal_code=%s, keyword=%s, in_date=%s, out_date=%s", req.City, req.PostalCode, req.Keyword, req.InDate, req.OutDate)
+
+	// find hotels in the city
+	logger.Debug().Msg("Querying profile service for hotels in city")
+
+	hotels, err := s.profileClient.GetHotelsByCity(ctx, &profile.CityRequest{
+		City:       req.City,
+		PostalCode: req.PostalCode,
+		Keyword:    req.Keyword,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to get hotels in city from profile service")
+		return nil, err
+	}
+
+	logger.Debug().Msgf("Retrieved hotels in city from profile service: hotels_count=%d", len(hotels.HotelIds))
+
+	res := new(pb.SearchResult)
+	if len(hotels.HotelIds) == 0 {
+		return res, nil
+	}
+
+	// find rates for hotels
+	logger.Debug().Msg("Querying rate service for hotel rates")
+	rates, err := s.rateClient.GetRates(ctx, &rate.Request{
+		HotelIds: hotels.HotelIds,
+		InDate:   req.InDate,
+		OutDate:  req.OutDate,
+		Currency: req.Currency,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to get rates from rate service")
+		return nil, err
+	}
+
+	// build the response
+	for _, ratePlan := range rates.RatePlans {
+		logger.Trace().Msgf("Adding hotel to search results: hotel_id=%s, rate_code=%s", ratePlan.HotelId, ratePlan.Code)
+		res.HotelIds = append(res.HotelIds, ratePlan.HotelId)
+	}
+	res.Prices = cheapestPrices(rates.RatePlans)
+
+	logger.Info().Msgf("Search city completed: results_count=%d", len(res.HotelIds))
+
+	return res, nil
+}
+
+// cheapestPrices returns the lowest price of each hotel among its rate plans
+func cheapestPrices(plans []*rate.RatePlan) map[string]*pb.Price {
+	prices := make(map[string]*pb.Price)
+	for _, p := range plans {
+		if p.RoomType == nil {
+			continue
+		}
+		if cur, ok := prices[p.HotelId]; ok && cur.TotalRateInclusive <= p.RoomType.TotalRateInclusive {
+			continue
+		}
+		prices[p.HotelId] = &pb.Price{
+			TotalRate:          p.RoomType.TotalRate,
+			TotalRateInclusive: p.RoomType.TotalRateInclusive,
+			Currency:           p.RoomType.Currency,
+		}
+	}
+	return prices
+}
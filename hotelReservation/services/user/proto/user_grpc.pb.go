@@ -19,7 +19,9 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	User_CheckUser_FullMethodName = "/user.User/CheckUser"
+	User_CheckUser_FullMethodName    = "/user.User/CheckUser"
+	User_RegisterUser_FullMethodName = "/user.User/RegisterUser"
+	User_Login_FullMethodName        = "/user.User/Login"
 )
 
 // UserClient is the client API for User service.
@@ -28,6 +30,10 @@ const (
 type UserClient interface {
 	// CheckUser returns whether the username and password are correct
 	CheckUser(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
+	// RegisterUser creates a new user
+	RegisterUser(ctx context.Context, in *Request, opts ...grpc.CallOption) (*RegisterResult, error)
+	// Login checks the credentials and issues a signed session token
+	Login(ctx context.Context, in *Request, opts ...grpc.CallOption) (*LoginResult, error)
 }
 
 type userClient struct {
@@ -47,12 +53,34 @@ func (c *userClient) CheckUser(ctx context.Context, in *Request, opts ...grpc.Ca
 	return out, nil
 }
 
+func (c *userClient) RegisterUser(ctx context.Context, in *Request, opts ...grpc.CallOption) (*RegisterResult, error) {
+	out := new(RegisterResult)
+	err := c.cc.Invoke(ctx, User_RegisterUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) Login(ctx context.Context, in *Request, opts ...grpc.CallOption) (*LoginResult, error) {
+	out := new(LoginResult)
+	err := c.cc.Invoke(ctx, User_Login_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // UserServer is the server API for User service.
 // All implementations must embed UnimplementedUserServer
 // for forward compatibility
 type UserServer interface {
 	// CheckUser returns whether the username and password are correct
 	CheckUser(context.Context, *Request) (*Result, error)
+	// RegisterUser creates a new user
+	RegisterUser(context.Context, *Request) (*RegisterResult, error)
+	// Login checks the credentials and issues a signed session token
+	Login(context.Context, *Request) (*LoginResult, error)
 	mustEmbedUnimplementedUserServer()
 }
 
@@ -63,6 +91,12 @@ type UnimplementedUserServer struct {
 func (UnimplementedUserServer) CheckUser(context.Context, *Request) (*Result, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CheckUser not implemented")
 }
+func (UnimplementedUserServer) RegisterUser(context.Context, *Request) (*RegisterResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterUser not implemented")
+}
+func (UnimplementedUserServer) Login(context.Context, *Request) (*LoginResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
 func (UnimplementedUserServer) mustEmbedUnimplementedUserServer() {}
 
 // UnsafeUserServer may be embedded to opt out of forward compatibility for this service.
@@ -94,6 +128,42 @@ func _User_CheckUser_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _User_RegisterUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).RegisterUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_RegisterUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).RegisterUser(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_Login_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).Login(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // User_ServiceDesc is the grpc.ServiceDesc for User service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -105,6 +175,14 @@ var User_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CheckUser",
 			Handler:    _User_CheckUser_Handler,
 		},
+		{
+			MethodName: "RegisterUser",
+			Handler:    _User_RegisterUser_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _User_Login_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "services/user/proto/user.proto",
@@ -1,13 +1,20 @@
 package user
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"fmt"
 	"net"
+	"runtime"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/registry"
 	pb "github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/services/user/proto"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/session"
+	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/shutdown"
 	"github.com/delimitrou/DeathStarBench/tree/master/hotelReservation/tls"
 "github.com/rs/zerolog"
 	"github.com/google/uuid"
@@ -15,10 +22,16 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 )
 
 const name = "srv-user"
@@ -27,18 +40,32 @@ const name = "srv-user"
 type Server struct {
 	pb.UnimplementedUserServer
 
-	users map[string]string
-	uuid  string
+	// usersMu guards users (username -> bcrypt hash) and verified. users is
+	// loaded at startup and filled from mongodb with users registered on
+	// other replicas, see lookupUser
+	usersMu sync.RWMutex
+	users   map[string]string
+	// verified remembers the last password that matched per user for
+	// verifiedTTL, so repeated logins of the benchmark skip bcrypt
+	verified   map[string]verifiedPassword
+	cacheKey   []byte
+	uuid       string
+	grpcServer *grpc.Server
+	listener   net.Listener
+	health     *health.Server
 
-	Tracer      trace.Tracer
-	Registry    *registry.Client
-	Port        int
-	IpAddr      string
-	MongoClient *mongo.Client
+	Tracer        trace.Tracer
+	Registry      registry.Registry
+	Port          int
+	IpAddr        string
+	MongoClient   *mongo.Client
+	SessionSecret []byte
+	SessionTTL    time.Duration
 }
 
-// Run starts the server
-func (s *Server) Run() error {
+// Setup creates the server, listens on Port and registers in consul. It is
+// called before Run, so Shutdown only reads what Setup wrote.
+func (s *Server) Setup() error {
 	if s.Port == 0 {
 		return fmt.Errorf("server port must be set")
 	}
@@ -46,6 +73,17 @@ func (s *Server) Run() error {
 	if s.users == nil {
 		s.users = loadUsers(s.MongoClient)
 	}
+	s.verified = make(map[string]verifiedPassword)
+	s.cacheKey = make([]byte, 32)
+	if _, err := rand.Read(s.cacheKey); err != nil {
+		return fmt.Errorf("failed to generate cache key: %v", err)
+	}
+	if len(s.SessionSecret) == 0 {
+		return fmt.Errorf("session secret must be set, see SESSION_SECRET")
+	}
+	if s.SessionTTL == 0 {
+		s.SessionTTL = session.DefaultTTL
+	}
 
 	s.uuid = uuid.New().String()
 
@@ -57,15 +95,22 @@ func (s *Server) Run() error {
 			PermitWithoutStream: true,
 		}),
 		grpc.UnaryInterceptor(
-			otelgrpc.UnaryServerInterceptor(),
+			otelgrpc.UnaryServerInterceptor(
+				otelgrpc.WithInterceptorFilter(filters.Not(filters.HealthCheck())),
+			),
 		),
 	}
 
 	if tlsopt := tls.GetServerOpt(); tlsopt != nil {
-		opts = append(opts, tlsopt)
+		opts = append(opts, tlsopt, grpc.ChainUnaryInterceptor(tls.UnaryServerInterceptor()))
 	}
 
 	srv := grpc.NewServer(opts...)
+	s.grpcServer = srv
+
+	// answers the consul check, serving until Shutdown
+	s.health = health.NewServer()
+	healthpb.RegisterHealthServer(srv, s.health)
 
 	pb.RegisterUserServer(srv, s)
 
@@ -80,12 +125,24 @@ func (s *Server) Run() error {
 	}
 	log.Info().Msg("Successfully registered in consul")
 
-	return srv.Serve(lis)
+	s.listener = lis
+	return nil
 }
 
-// Shutdown cleans up any processes
-func (s *Server) Shutdown() {
-	s.Registry.Deregister(s.uuid)
+// Run serves the server set up by Setup until Shutdown
+func (s *Server) Run() error {
+	return s.grpcServer.Serve(s.listener)
+}
+
+// Shutdown fails the health check and deregisters the service so no new
+// calls are routed here, then waits for pending calls until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+	err := s.Registry.Deregister(s.uuid)
+	shutdown.GRPC(ctx, s.grpcServer)
+	return err
 }
 
 // CheckUser returns whether the username and password are correct.
@@ -115,33 +172,222 @@ func (s *Server) CheckUser(ctx context.Context, req *pb.Request) (*pb.Result, er
 
 	logger.Info().Msgf("Checking user credentials: username=%s", req.Username)
 
-	sum := sha256.Sum256([]byte(req.Password))
-	pass := fmt.Sprintf("%x", sum)
-
-	res.Correct = false
-	if true_pass, found := s.users[req.Username]; found {
-		res.Correct = pass == true_pass
-	}
+	res.Correct = s.checkPassword(ctx, req.Username, req.Password)
 
 	logger.Info().Msgf("User authentication completed: username=%s, authenticated=%v", req.Username, res.Correct)
 
 	return res, nil
 }
 
+// RegisterUser creates a new user with a salted password hash
+func (s *Server) RegisterUser(ctx context.Context, req *pb.Request) (*pb.RegisterResult, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	if req.Username == "" || req.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "username and password are required")
+	}
+
+	if _, exists := s.lookupUser(ctx, req.Username); exists {
+		return nil, status.Errorf(codes.AlreadyExists, "user %s already exists", req.Username)
+	}
+
+	hash, err := hashPassword(legacyDigest(req.Password))
+	if err != nil {
+		return nil, err
+	}
+
+	collection := s.MongoClient.Database("user-db").Collection("user")
+	_, err = collection.InsertOne(ctx, User{Username: req.Username, Password: hash})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, status.Errorf(codes.AlreadyExists, "user %s already exists", req.Username)
+	} else if err != nil {
+		logger.Error().Msgf("Failed to store user: username=%s, error=%v", req.Username, err)
+		return nil, err
+	}
+
+	s.usersMu.Lock()
+	s.users[req.Username] = hash
+	s.usersMu.Unlock()
+
+	logger.Info().Msgf("User registered: username=%s", req.Username)
+	return &pb.RegisterResult{Username: req.Username}, nil
+}
+
+// Login checks the credentials and returns a signed session token
+func (s *Server) Login(ctx context.Context, req *pb.Request) (*pb.LoginResult, error) {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		globalLogger := log.Logger
+		logger = &globalLogger
+	}
+
+	if !s.checkPassword(ctx, req.Username, req.Password) {
+		logger.Info().Msgf("Login rejected: username=%s", req.Username)
+		return nil, status.Error(codes.Unauthenticated, "wrong username or password")
+	}
+
+	token, expiresAt, err := session.Sign(s.SessionSecret, req.Username, s.SessionTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info().Msgf("Login succeeded: username=%s", req.Username)
+	return &pb.LoginResult{Token: token, ExpiresAt: expiresAt.Unix()}, nil
+}
+
+// lookupUser returns the stored hash of a user. Users missing in memory are
+// looked up in mongodb, as they may have registered with another replica.
+func (s *Server) lookupUser(ctx context.Context, username string) (string, bool) {
+	s.usersMu.RLock()
+	hash, found := s.users[username]
+	s.usersMu.RUnlock()
+	if found {
+		return hash, true
+	}
+
+	var user User
+	collection := s.MongoClient.Database("user-db").Collection("user")
+	err := collection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Error().Msgf("Failed to find user: username=%s, error=%v", username, err)
+		}
+		return "", false
+	}
+
+	s.usersMu.Lock()
+	s.users[username] = user.Password
+	s.usersMu.Unlock()
+	return user.Password, true
+}
+
+// verifiedTTL bounds how long a matched password is accepted without bcrypt
+const verifiedTTL = time.Minute
+
+// verifiedPassword is a password that matched hash, keyed with cacheKey
+type verifiedPassword struct {
+	mac     []byte
+	hash    string
+	expires time.Time
+}
+
+// checkPassword compares password against the stored hash of the user. A
+// password that matched recently is accepted again without bcrypt, as long
+// as the stored hash did not change since.
+func (s *Server) checkPassword(ctx context.Context, username, password string) bool {
+	mac := hmac.New(sha256.New, s.cacheKey)
+	mac.Write([]byte(password))
+	cached := mac.Sum(nil)
+
+	hash, found := s.lookupUser(ctx, username)
+	if !found {
+		return false
+	}
+	s.usersMu.RLock()
+	last, ok := s.verified[username]
+	s.usersMu.RUnlock()
+	if ok && last.hash == hash && time.Now().Before(last.expires) && hmac.Equal(last.mac, cached) {
+		return true
+	}
+
+	digest := legacyDigest(password)
+	if !isBcrypt(hash) {
+		// record that failed to migrate at startup
+		if !hmac.Equal([]byte(hash), []byte(digest)) {
+			return false
+		}
+	} else if bcrypt.CompareHashAndPassword([]byte(hash), []byte(digest)) != nil {
+		return false
+	}
+
+	s.usersMu.Lock()
+	s.verified[username] = verifiedPassword{mac: cached, hash: hash, expires: time.Now().Add(verifiedTTL)}
+	s.usersMu.Unlock()
+	return true
+}
+
+// legacyDigest is the unsalted hex SHA-256 the user records used to hold.
+// New hashes are bcrypt over this digest, so old records can be migrated
+// without knowing the passwords.
+func legacyDigest(password string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(password)))
+}
+
+func hashPassword(digest string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(digest), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2")
+}
+
+// migrateUsers replaces legacy SHA-256 records with bcrypt hashes, in memory
+// and in mongodb. Users are updated only if their record did not change
+// meanwhile, so concurrent replicas migrating at once are harmless.
+func migrateUsers(collection *mongo.Collection, users []User) {
+	var wg sync.WaitGroup
+	todo := make(chan *User)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for user := range todo {
+				hash, err := hashPassword(user.Password)
+				if err != nil {
+					log.Error().Msgf("Failed to hash password of %s: %v", user.Username, err)
+					continue
+				}
+				_, err = collection.UpdateOne(context.TODO(),
+					bson.M{"username": user.Username, "password": user.Password},
+					bson.M{"$set": bson.M{"password": hash}})
+				if err != nil {
+					log.Error().Msgf("Failed to migrate user %s: %v", user.Username, err)
+					continue
+				}
+				user.Password = hash
+			}
+		}()
+	}
+
+	migrated := 0
+	for i := range users {
+		if !isBcrypt(users[i].Password) {
+			todo <- &users[i]
+			migrated++
+		}
+	}
+	close(todo)
+	wg.Wait()
+
+	if migrated > 0 {
+		log.Info().Msgf("Migrated %d legacy password hashes", migrated)
+	}
+}
+
 // loadUsers loads hotel users from mongodb.
 func loadUsers(client *mongo.Client) map[string]string {
 	collection := client.Database("user-db").Collection("user")
 	curr, err := collection.Find(context.TODO(), bson.D{})
 	if err != nil {
-		log.Error().Msgf("Failed get users data: ", err)
+		log.Error().Msgf("Failed get users data: %v", err)
 	}
 
 	var users []User
 	curr.All(context.TODO(), &users)
 	if err != nil {
-		log.Error().Msgf("Failed get users data: ", err)
+		log.Error().Msgf("Failed get users data: %v", err)
 	}
 
+	migrateUsers(collection, users)
+
 	res := make(map[string]string)
 	for _, user := range users {
 		res[user.Username] = user.Password
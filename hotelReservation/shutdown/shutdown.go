@@ -0,0 +1,131 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+)
+
+// DefaultTimeout bounds how long a service drains before it exits. Together
+// with FlushTimeout it stays below the 10s docker waits before killing a
+// container; set SHUTDOWN_TIMEOUT (e.g. "20s") to change it, keeping it plus
+// FlushTimeout below the grace period of the orchestrator.
+const DefaultTimeout = 6 * time.Second
+
+// FlushTimeout is the time telemetry gets to flush after draining, on top of
+// Timeout
+const FlushTimeout = 2 * time.Second
+
+// Hook releases one resource of a service, giving up once ctx is done
+type Hook func(ctx context.Context) error
+
+// Timeout returns the time hooks get to finish
+func Timeout() time.Duration {
+	if val, ok := os.LookupEnv("SHUTDOWN_TIMEOUT"); ok {
+		if d, err := time.ParseDuration(val); err == nil && d > 0 {
+			return d
+		}
+		log.Warn().Msgf("Ignoring invalid SHUTDOWN_TIMEOUT %q", val)
+	}
+	return DefaultTimeout
+}
+
+// Run calls serve and blocks until it returns or the process gets SIGINT or
+// SIGTERM. Either way the hooks then run in order, sharing one Timeout
+// except for those wrapped in Flush. After
+// a signal Run waits for serve to return and reports its error, if any
+// besides being stopped.
+func Run(serve func() error, hooks ...Hook) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- serve()
+	}()
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+
+	select {
+	case err := <-errc:
+		// serving failed by itself, still release what was set up
+		ctx, cancel := context.WithTimeout(context.Background(), Timeout())
+		defer cancel()
+		runHooks(ctx, hooks)
+		return err
+	case sig := <-sigc:
+		log.Info().Msgf("Received %v, shutting down within %v", sig, Timeout())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout())
+	defer cancel()
+	runHooks(ctx, hooks)
+
+	select {
+	case err := <-errc:
+		return stopped(err)
+	case <-ctx.Done():
+	}
+	// a Flush hook may have run past ctx after serve returned
+	select {
+	case err := <-errc:
+		return stopped(err)
+	default:
+		return fmt.Errorf("shutdown timed out after %v", Timeout())
+	}
+}
+
+// stopped reports the error serve returned with, if any besides being stopped
+func stopped(err error) error {
+	if err == nil || errors.Is(err, http.ErrServerClosed) || errors.Is(err, grpc.ErrServerStopped) {
+		log.Info().Msg("Shutdown complete")
+		return nil
+	}
+	return err
+}
+
+func runHooks(ctx context.Context, hooks []Hook) {
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			log.Warn().Msgf("Shutdown step failed: %v", err)
+		}
+	}
+}
+
+// Flush gives hook FlushTimeout of its own instead of what the hooks before
+// it left of the shared Timeout, so a slow drain cannot lose the telemetry
+// recorded during it
+func Flush(hook Hook) Hook {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), FlushTimeout)
+		defer cancel()
+		return hook(ctx)
+	}
+}
+
+// GRPC stops srv once its pending calls are done, closing the remaining
+// connections when ctx is done first. A nil srv is ignored, so it can be
+// called before the server is up.
+func GRPC(ctx context.Context, srv *grpc.Server) {
+	if srv == nil {
+		return
+	}
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Warn().Msg("Pending gRPC calls did not finish in time, closing connections")
+		srv.Stop()
+	}
+}
@@ -0,0 +1,116 @@
+package tls
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// authz checks the identities of peers, set by Init
+var authz = newAuthorizer("", "")
+
+// authorizer checks the SPIFFE IDs of peers, as in
+// spiffe://hotel.dsb/frontend, taken from the URI SAN of their certificate
+type authorizer struct {
+	// trustDomain is the domain every peer ID must be in, any certificate
+	// of the CA is accepted when empty
+	trustDomain string
+	// allowed lists the IDs of the clients allowed to call, any client in
+	// the trust domain when empty
+	allowed map[string]bool
+}
+
+// newAuthorizer returns an authorizer of trustDomain allowing the comma
+// separated IDs in allowed
+func newAuthorizer(trustDomain string, allowed string) *authorizer {
+	a := &authorizer{trustDomain: trustDomain}
+	for _, id := range strings.Split(allowed, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			if a.allowed == nil {
+				a.allowed = make(map[string]bool)
+			}
+			a.allowed[id] = true
+		}
+	}
+	return a
+}
+
+// spiffeID returns the SPIFFE ID of cert, nil if it has none
+func spiffeID(cert *x509.Certificate) *url.URL {
+	for _, u := range cert.URIs {
+		if u.Scheme == "spiffe" {
+			return u
+		}
+	}
+	return nil
+}
+
+// checkTrustDomain checks that cert has an ID in the trust domain, if any
+func (a *authorizer) checkTrustDomain(cert *x509.Certificate) (*url.URL, error) {
+	id := spiffeID(cert)
+	if a.trustDomain == "" {
+		return id, nil
+	}
+	if id == nil {
+		return nil, fmt.Errorf("certificate of %s has no SPIFFE ID", cert.Subject.CommonName)
+	}
+	if id.Host != a.trustDomain {
+		return nil, fmt.Errorf("%s is not in trust domain %s", id, a.trustDomain)
+	}
+	return id, nil
+}
+
+// checkServer checks the identity of a server the service connects to
+func (a *authorizer) checkServer(cert *x509.Certificate) error {
+	_, err := a.checkTrustDomain(cert)
+	return err
+}
+
+// checkClient checks the identity of a client calling the service
+func (a *authorizer) checkClient(cert *x509.Certificate) error {
+	id, err := a.checkTrustDomain(cert)
+	if err != nil || a.allowed == nil {
+		return err
+	}
+	if id == nil || !a.allowed[id.String()] {
+		return fmt.Errorf("%v is not allowed", id)
+	}
+	return nil
+}
+
+// UnaryServerInterceptor refuses calls from clients without a certificate
+// of the CA with UNAUTHENTICATED, and calls from clients whose SPIFFE ID is
+// not allowed with PERMISSION_DENIED. Health checks are let through, as the
+// consul agent calls them without a certificate. Calls pass through when TLS
+// is disabled.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !enabled || strings.HasPrefix(info.FullMethod, "/grpc.health.v1.Health/") {
+			return handler(ctx, req)
+		}
+
+		p, ok := peer.FromContext(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "no peer")
+		}
+		ti, ok := p.AuthInfo.(credentials.TLSInfo)
+		if !ok || len(ti.State.VerifiedChains) == 0 {
+			log.Warn().Msgf("Refused call to %s from %s without client certificate", info.FullMethod, p.Addr)
+			return nil, status.Error(codes.Unauthenticated, "client certificate required")
+		}
+		if err := authz.checkClient(ti.State.VerifiedChains[0][0]); err != nil {
+			log.Warn().Msgf("Refused call to %s from %s: %v", info.FullMethod, p.Addr, err)
+			return nil, status.Errorf(codes.PermissionDenied, "%v", err)
+		}
+		return handler(ctx, req)
+	}
+}
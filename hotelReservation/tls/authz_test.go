@@ -0,0 +1,108 @@
+package tls
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+)
+
+// cert returns a certificate with the given URI SANs
+func cert(uris ...string) *x509.Certificate {
+	c := &x509.Certificate{Subject: pkix.Name{CommonName: "hotel"}}
+	for _, s := range uris {
+		u, err := url.Parse(s)
+		if err != nil {
+			panic(err)
+		}
+		c.URIs = append(c.URIs, u)
+	}
+	return c
+}
+
+func TestNewAuthorizer(t *testing.T) {
+	a := newAuthorizer("hotel.dsb", " spiffe://hotel.dsb/frontend,, spiffe://hotel.dsb/search ,")
+	if len(a.allowed) != 2 || !a.allowed["spiffe://hotel.dsb/frontend"] || !a.allowed["spiffe://hotel.dsb/search"] {
+		t.Errorf("got allowed %v", a.allowed)
+	}
+	for _, allowed := range []string{"", " , ,"} {
+		if a := newAuthorizer("hotel.dsb", allowed); a.allowed != nil {
+			t.Errorf("%q: got allowed %v, want any client", allowed, a.allowed)
+		}
+	}
+}
+
+func TestCheckTrustDomain(t *testing.T) {
+	tests := []struct {
+		name        string
+		trustDomain string
+		cert        *x509.Certificate
+		want        string
+		wantErr     bool
+	}{
+		{name: "in the trust domain", trustDomain: "hotel.dsb", cert: cert("spiffe://hotel.dsb/geo"), want: "spiffe://hotel.dsb/geo"},
+		{
+			name:        "first SPIFFE ID among other URIs",
+			trustDomain: "hotel.dsb",
+			cert:        cert("https://hotel.dsb/geo", "spiffe://hotel.dsb/geo", "spiffe://other.dsb/geo"),
+			want:        "spiffe://hotel.dsb/geo",
+		},
+		{name: "missing SPIFFE ID", trustDomain: "hotel.dsb", cert: cert(), wantErr: true},
+		{name: "only other URIs", trustDomain: "hotel.dsb", cert: cert("https://hotel.dsb/geo"), wantErr: true},
+		{name: "wrong trust domain", trustDomain: "hotel.dsb", cert: cert("spiffe://other.dsb/geo"), wantErr: true},
+		{name: "subdomain of the trust domain", trustDomain: "hotel.dsb", cert: cert("spiffe://evil.hotel.dsb/geo"), wantErr: true},
+		{name: "no trust domain", trustDomain: "", cert: cert("spiffe://other.dsb/geo"), want: "spiffe://other.dsb/geo"},
+		{name: "no trust domain nor SPIFFE ID", trustDomain: "", cert: cert()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := newAuthorizer(tt.trustDomain, "").checkTrustDomain(tt.cert)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := ""
+			if id != nil {
+				got = id.String()
+			}
+			if got != tt.want {
+				t.Errorf("got ID %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckClient(t *testing.T) {
+	const allowed = "spiffe://hotel.dsb/frontend,spiffe://hotel.dsb/search"
+
+	tests := []struct {
+		name        string
+		trustDomain string
+		allowed     string
+		cert        *x509.Certificate
+		wantErr     bool
+	}{
+		{name: "on the allow list", trustDomain: "hotel.dsb", allowed: allowed, cert: cert("spiffe://hotel.dsb/frontend")},
+		{name: "not on the allow list", trustDomain: "hotel.dsb", allowed: allowed, cert: cert("spiffe://hotel.dsb/rate"), wantErr: true},
+		{name: "allowed path in the wrong trust domain", trustDomain: "hotel.dsb", allowed: allowed, cert: cert("spiffe://other.dsb/frontend"), wantErr: true},
+		{name: "missing SPIFFE ID", trustDomain: "hotel.dsb", allowed: allowed, cert: cert(), wantErr: true},
+		{name: "empty allow list, in the trust domain", trustDomain: "hotel.dsb", cert: cert("spiffe://hotel.dsb/rate")},
+		{name: "empty allow list, wrong trust domain", trustDomain: "hotel.dsb", cert: cert("spiffe://other.dsb/rate"), wantErr: true},
+		{name: "empty allow list, missing SPIFFE ID", trustDomain: "hotel.dsb", cert: cert(), wantErr: true},
+		{name: "allow list without trust domain", allowed: allowed, cert: cert("spiffe://hotel.dsb/search")},
+		{name: "allow list without trust domain nor SPIFFE ID", allowed: allowed, cert: cert(), wantErr: true},
+		{name: "neither trust domain nor allow list", cert: cert()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newAuthorizer(tt.trustDomain, tt.allowed).checkClient(tt.cert)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got err %v, want err %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const defaultReloadInterval = 30 * time.Second
+
+// certStore holds the CA and the certificate of the service, reloaded when
+// their files change so certificates can be rotated without a restart.
+// Connections pick up new certificates on their next handshake.
+type certStore struct {
+	caFile, certFile, keyFile string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime time.Time
+}
+
+// load reads the files of the store, keeping the current certificates if
+// any of them is invalid
+func (s *certStore) load() error {
+	modTime, err := s.lastModified()
+	if err != nil {
+		return err
+	}
+
+	b, err := os.ReadFile(s.caFile)
+	if err != nil {
+		return fmt.Errorf("tls: failed to read credentials: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return fmt.Errorf("tls: no CA certificate in %s", s.caFile)
+	}
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return fmt.Errorf("tls: failed to load key pair: %v", err)
+	}
+
+	s.mu.Lock()
+	s.cert, s.pool, s.modTime = &cert, pool, modTime
+	s.mu.Unlock()
+	return nil
+}
+
+// lastModified returns the latest modification time of the files
+func (s *certStore) lastModified() (time.Time, error) {
+	var last time.Time
+	for _, f := range []string{s.caFile, s.certFile, s.keyFile} {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return last, fmt.Errorf("tls: %v", err)
+		}
+		if fi.ModTime().After(last) {
+			last = fi.ModTime()
+		}
+	}
+	return last, nil
+}
+
+// watch reloads the certificates every interval in which their files
+// changed. Files being replaced one at a time may not match for a moment,
+// so failed loads are tried again on the next check.
+func (s *certStore) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		modTime, err := s.lastModified()
+		if err != nil {
+			log.Warn().Msgf("Failed to check certificates: %v", err)
+			continue
+		}
+		s.mu.RLock()
+		changed := !modTime.Equal(s.modTime)
+		s.mu.RUnlock()
+		if !changed {
+			continue
+		}
+
+		if err := s.load(); err != nil {
+			log.Error().Msgf("Failed to reload certificates, keeping the current ones: %v", err)
+			continue
+		}
+		log.Info().Msgf("Reloaded certificates [ca: %s, cert: %s]", s.caFile, s.certFile)
+	}
+}
+
+func (s *certStore) current() (*tls.Certificate, *x509.CertPool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cert, s.pool
+}
+
+func (s *certStore) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, _ := s.current()
+	return cert, nil
+}
+
+func (s *certStore) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert, _ := s.current()
+	return cert, nil
+}
+
+// serverConfig returns the config of gRPC servers. Clients are verified
+// against the CA when they present a certificate; calls without one are
+// refused by UnaryServerInterceptor, except health checks, as the consul
+// agent has no certificate.
+func (s *certStore) serverConfig(suites []uint16) *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := s.current()
+			return &tls.Config{
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    pool,
+				ClientAuth:   tls.VerifyClientCertIfGiven,
+				CipherSuites: suites,
+				NextProtos:   []string{"h2"},
+			}, nil
+		},
+	}
+}
+
+// clientConfig returns the config of gRPC clients, which present the
+// certificate of the service. Servers are found by address, so their
+// certificate is checked against serverName only when one is given, and
+// against the trust domain of SPIFFE IDs when one is set.
+func (s *certStore) clientConfig(serverName string, suites []uint16) *tls.Config {
+	return &tls.Config{
+		ServerName:           serverName,
+		CipherSuites:         suites,
+		GetClientCertificate: s.getClientCertificate,
+		// the chain is verified below, against the CA loaded last rather
+		// than the one of the handshake config
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("tls: server sent no certificate")
+			}
+			_, pool := s.current()
+			opts := x509.VerifyOptions{
+				Roots:         pool,
+				DNSName:       serverName,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, c := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(c)
+			}
+			if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+				return err
+			}
+			return authz.checkServer(cs.PeerCertificates[0])
+		},
+	}
+}
@@ -2,10 +2,10 @@ package tls
 
 import (
 	"crypto/tls"
-	"crypto/x509"
-	"io/ioutil"
+	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
@@ -77,51 +77,82 @@ func checkTLS() (bool, string) {
 	return false, ""
 }
 
+var (
+	enabled bool
+	cipher  string
+)
+
 func init() {
-	needTLS, cipher := checkTLS()
-	if needTLS {
-		b, err := ioutil.ReadFile("x509/ca_cert.pem")
-		if err != nil {
-			log.Panic().Msgf("failed to read credentials: %v", err)
-		}
-		cp := x509.NewCertPool()
-		if !cp.AppendCertsFromPEM(b) {
-			log.Panic().Msgf("credentials: failed to append certificates")
-		}
-		config := tls.Config{
-			ServerName: "x.test.example.com",
-			RootCAs:    cp,
+	enabled, cipher = checkTLS()
+	if !enabled {
+		log.Info().Msgf("TLS disabled.")
+	}
+}
+
+// Init loads the certificates of a service when TLS is enabled. Paths are
+// the <prefix>TLSCert and <prefix>TLSKey keys of config, as in GeoTLSCert,
+// falling back to TLSCert and TLSKey, and TLSCACert for the CA. Callers
+// allowed by the service are listed in <prefix>TLSAllowedClients, see
+// UnaryServerInterceptor. Files are checked every TLSReloadInterval (30s by
+// default) and reloaded when they change.
+func Init(config map[string]string, prefix string) error {
+	if !enabled {
+		return nil
+	}
+
+	get := func(key, def string) string {
+		if v := config[prefix+key]; v != "" {
+			return v
 		}
-		httpsopt = &tls.Config{
-			PreferServerCipherSuites: true,
-			RootCAs:                  cp,
+		if v := config[key]; v != "" {
+			return v
 		}
-		if cipher != "" {
-			log.Info().Msgf("TLS enabled cipher suite %s", cipher)
-			config.CipherSuites = append(config.CipherSuites, cipherSuites[cipher])
-			httpsopt.CipherSuites = append(httpsopt.CipherSuites, cipherSuites[cipher])
-			switch cipher {
-			case "TLS_AES_128_GCM_SHA256", "TLS_AES_256_GCM_SHA384", "TLS_CHACHA20_POLY1305_SHA256":
-				httpsopt.MinVersion = tls.VersionTLS13
-			}
-		} else {
-			log.Info().Msgf("TLS enabled without specified cipher suite")
+		return def
+	}
+	certs := &certStore{
+		caFile:   get("TLSCACert", "x509/ca_cert.pem"),
+		certFile: get("TLSCert", "x509/server_cert.pem"),
+		keyFile:  get("TLSKey", "x509/server_key.pem"),
+	}
+	if err := certs.load(); err != nil {
+		return err
+	}
+
+	interval := defaultReloadInterval
+	if v := config["TLSReloadInterval"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("tls: invalid TLSReloadInterval %q", v)
 		}
+		interval = d
+	}
+	go certs.watch(interval)
 
-		var creds credentials.TransportCredentials
-		creds = credentials.NewTLS(&config)
-		dialopt = grpc.WithTransportCredentials(creds)
+	authz = newAuthorizer(config["TLSTrustDomain"], config[prefix+"TLSAllowedClients"])
 
-		creds, err = credentials.NewServerTLSFromFile("x509/server_cert.pem", "x509/server_key.pem")
-		if err != nil {
-			log.Panic().Msgf("failed to create credentials: %v", err)
-		}
-		serveropt = grpc.Creds(creds)
+	var suites []uint16
+	if cipher != "" {
+		log.Info().Msgf("TLS enabled cipher suite %s", cipher)
+		suites = []uint16{cipherSuites[cipher]}
 	} else {
-		log.Info().Msgf("TLS disabled.")
-		dialopt = nil
-		serveropt = nil
+		log.Info().Msgf("TLS enabled without specified cipher suite")
+	}
+
+	dialopt = grpc.WithTransportCredentials(credentials.NewTLS(certs.clientConfig(config["TLSServerName"], suites)))
+	serveropt = grpc.Creds(credentials.NewTLS(certs.serverConfig(suites)))
+
+	httpsopt = &tls.Config{
+		PreferServerCipherSuites: true,
+		CipherSuites:             suites,
+		GetCertificate:           certs.getCertificate,
+	}
+	switch cipher {
+	case "TLS_AES_128_GCM_SHA256", "TLS_AES_256_GCM_SHA384", "TLS_CHACHA20_POLY1305_SHA256":
+		httpsopt.MinVersion = tls.VersionTLS13
 	}
+
+	log.Info().Msgf("TLS certificates loaded [ca: %s, cert: %s, key: %s]", certs.caFile, certs.certFile, certs.keyFile)
+	return nil
 }
 
 func GetDialOpt() grpc.DialOption {
@@ -132,6 +163,8 @@ func GetServerOpt() grpc.ServerOption {
 	return serveropt
 }
 
+// GetHttpsOpt returns the config of the frontend https server, which serves
+// the service certificate and does not ask for client ones
 func GetHttpsOpt() *tls.Config {
 	return httpsopt
 }
@@ -2,22 +2,33 @@ package tracing
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"os"
 	"strconv"
 
 	"github.com/rs/zerolog/log"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// otlpGRPCPort is where the collector takes OTLP over gRPC, next to the
+// OTLP/HTTP port traces and logs are sent to
+const otlpGRPCPort = "4317"
+
 var (
 	defaultSampleRatio float64 = 0.01
+	tracerProvider     *sdktrace.TracerProvider
+	meterProvider      *sdkmetric.MeterProvider
 )
 
 // Init returns a newly configured tracer
@@ -70,6 +81,24 @@ func Init(serviceName, host string) (trace.Tracer, error) {
 
 	// Set global tracer provider
 	otel.SetTracerProvider(tp)
+	tracerProvider = tp
+
+	// Set global meter provider, exporting e.g. the circuit breaker metrics
+	// of the dialer
+	metricExporter, err := otlpmetricgrpc.New(ctx,
+		otlpmetricgrpc.WithEndpoint(metricsEndpoint(endpoint)),
+		otlpmetricgrpc.WithInsecure(),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create OTLP metric exporter")
+		return nil, err
+	}
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetMeterProvider(mp)
+	meterProvider = mp
 
 	// Set global propagator
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
@@ -80,3 +109,43 @@ func Init(serviceName, host string) (trace.Tracer, error) {
 	log.Info().Msg("OpenTelemetry tracer initialized successfully")
 	return tp.Tracer(serviceName), nil
 }
+
+// metricsEndpoint returns OTEL_EXPORTER_OTLP_METRICS_ENDPOINT if set, else
+// the OTLP gRPC port on the host of the trace endpoint
+func metricsEndpoint(endpoint string) string {
+	if val, ok := os.LookupEnv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"); ok {
+		return val
+	}
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host = endpoint
+	}
+	return net.JoinHostPort(host, otlpGRPCPort)
+}
+
+// Shutdown exports the spans, metrics and logs still buffered and stops the
+// providers set up by Init and InitLogger
+func Shutdown(ctx context.Context) error {
+	var errs []error
+	if tracerProvider != nil {
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("tracer provider: %v", err))
+		}
+	}
+	if meterProvider != nil {
+		if err := meterProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("meter provider: %v", err))
+		}
+	}
+
+	loggerMutex.Lock()
+	lp := loggerProvider
+	otelLogger = nil
+	loggerMutex.Unlock()
+	if lp != nil {
+		if err := lp.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("logger provider: %v", err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,202 @@
+local socket = require("socket")
+math.randomseed(socket.gettime()*1000)
+math.random(); math.random(); math.random()
+
+local url = "http://localhost:5000"
+
+local function get_user()
+  local id = math.random(0, 500)
+  local user_name = "Cornell_" .. tostring(id)
+  local pass_word = ""
+  for i = 0, 9, 1 do 
+    pass_word = pass_word .. tostring(id)
+  end
+  return user_name, pass_word
+end
+
+local function search_hotel() 
+  local in_date = math.random(9, 23)
+  local out_date = math.random(in_date + 1, 24)
+
+  local in_date_str = tostring(in_date)
+  if in_date <= 9 then
+    in_date_str = "2015-04-0" .. in_date_str 
+  else
+    in_date_str = "2015-04-" .. in_date_str
+  end
+
+  local out_date_str = tostring(out_date)
+  if out_date <= 9 then
+    out_date_str = "2015-04-0" .. out_date_str 
+  else
+    out_date_str = "2015-04-" .. out_date_str
+  end
+
+  local lat = 38.0235 + (math.random(0, 481) - 240.5)/1000.0
+  local lon = -122.095 + (math.random(0, 325) - 157.0)/1000.0
+
+  local method = "GET"
+  local path = url .. "/hotels?inDate=" .. in_date_str .. 
+    "&outDate=" .. out_date_str .. "&lat=" .. tostring(lat) .. "&lon=" .. tostring(lon)
+
+  local headers = {}
+  -- headers["Content-Type"] = "application/x-www-form-urlencoded"
+  return wrk.format(method, path, headers, nil)
+end
+
+local function recommend()
+  local coin = math.random()
+  local req_param = ""
+  if coin < 0.33 then
+    req_param = "dis"
+  elseif coin < 0.66 then
+    req_param = "rate"
+  else
+    req_param = "price"
+  end
+
+  local lat = 38.0235 + (math.random(0, 481) - 240.5)/1000.0
+  local lon = -122.095 + (math.random(0, 325) - 157.0)/1000.0
+
+  local method = "GET"
+  local path = url .. "/recommendations?require=" .. req_param .. 
+    "&lat=" .. tostring(lat) .. "&lon=" .. tostring(lon)
+  local headers = {}
+  -- headers["Content-Type"] = "application/x-www-form-urlencoded"
+  return wrk.format(method, path, headers, nil)
+end
+
+local function reserve()
+  local in_date = math.random(9, 23)
+  local out_date = in_date + math.random(1, 5)
+
+  local in_date_str = tostring(in_date)
+  if in_date <= 9 then
+    in_date_str = "2015-04-0" .. in_date_str 
+  else
+    in_date_str = "2015-04-" .. in_date_str
+  end
+
+  local out_date_str = tostring(out_date)
+  if out_date <= 9 then
+    out_date_str = "2015-04-0" .. out_date_str 
+  else
+    out_date_str = "2015-04-" .. out_date_str
+  end
+
+  local hotel_id = tostring(math.random(1, 80))
+  local user_id, password = get_user()
+  local cust_name = user_id
+
+  local num_room = "1"
+
+  local method = "POST"
+  local path = url .. "/reservation?inDate=" .. in_date_str .. 
+    "&outDate=" .. out_date_str .. "&lat=" .. tostring(lat) .. "&lon=" .. tostring(lon) ..
+    "&hotelId=" .. hotel_id .. "&customerName=" .. cust_name .. "&username=" .. user_id ..
+    "&password=" .. password .. "&number=" .. num_room
+  local headers = {}
+  -- headers["Content-Type"] = "application/x-www-form-urlencoded"
+  return wrk.format(method, path, headers, nil)
+end
+
+local function user_login()
+  local user_name, password = get_user()
+  local method = "POST"
+  local path = url .. "/user?username=" .. user_name .. "&password=" .. password
+  local headers = {}
+  -- headers["Content-Type"] = "application/x-www-form-urlencoded"
+  return wrk.format(method, path, headers, nil)
+end
+
+local function nearby_restaurants()
+  -- Attractions DB has hotel coordinates for hotels 1-80 (see cmd/attractions/db.go)
+  local hotel_id = tostring(math.random(1, 80))
+  local user_name, password = get_user()
+  local method = "GET"
+  local path = url .. "/restaurants?username=" .. user_name .. 
+    "&password=" .. password .. "&hotelId=" .. hotel_id
+  local headers = {}
+  return wrk.format(method, path, headers, nil)
+end
+
+local function nearby_museums()
+  -- Attractions DB has hotel coordinates for hotels 1-80 (see cmd/attractions/db.go)
+  local hotel_id = tostring(math.random(1, 80))
+  local user_name, password = get_user()
+  local method = "GET"
+  local path = url .. "/museums?username=" .. user_name .. 
+    "&password=" .. password .. "&hotelId=" .. hotel_id
+  local headers = {}
+  return wrk.format(method, path, headers, nil)
+end
+
+local function nearby_cinemas()
+  -- Attractions DB has hotel coordinates for hotels 1-80 (see cmd/attractions/db.go)
+  local hotel_id = tostring(math.random(1, 80))
+  local user_name, password = get_user()
+  local method = "GET"
+  local path = url .. "/cinema?username=" .. user_name .. 
+    "&password=" .. password .. "&hotelId=" .. hotel_id
+  local headers = {}
+  return wrk.format(method, path, headers, nil)
+end
+
+local function hotel_page()
+  -- one request fanning out to profile, review, rate and attractions
+  local in_date = math.random(9, 23)
+  local out_date = in_date + math.random(1, 5)
+
+  local in_date_str = tostring(in_date)
+  if in_date <= 9 then
+    in_date_str = "2015-04-0" .. in_date_str
+  else
+    in_date_str = "2015-04-" .. in_date_str
+  end
+
+  local out_date_str = tostring(out_date)
+  if out_date <= 9 then
+    out_date_str = "2015-04-0" .. out_date_str
+  else
+    out_date_str = "2015-04-" .. out_date_str
+  end
+
+  local hotel_id = tostring(math.random(1, 80))
+  local user_name, password = get_user()
+  local method = "GET"
+  local path = url .. "/hotel/" .. hotel_id .. "?username=" .. user_name ..
+    "&password=" .. password .. "&inDate=" .. in_date_str .. "&outDate=" .. out_date_str
+  local headers = {}
+  return wrk.format(method, path, headers, nil)
+end
+
+request = function()
+  cur_time = math.floor(socket.gettime())
+  local search_ratio        = 0.45
+  local recommend_ratio     = 0.25
+  local user_ratio          = 0.005
+  local reserve_ratio       = 0.005
+  local restaurants_ratio   = 0.04
+  local museums_ratio       = 0.04
+  local cinemas_ratio       = 0.04
+  local hotel_page_ratio    = 0.17
+
+  local coin = math.random()
+  if coin < search_ratio then
+    return search_hotel(url)
+  elseif coin < search_ratio + recommend_ratio then
+    return recommend(url)
+  elseif coin < search_ratio + recommend_ratio + user_ratio then
+    return user_login(url)
+  elseif coin < search_ratio + recommend_ratio + user_ratio + reserve_ratio then
+    return reserve(url)
+  elseif coin < search_ratio + recommend_ratio + user_ratio + reserve_ratio + restaurants_ratio then
+    return nearby_restaurants(url)
+  elseif coin < search_ratio + recommend_ratio + user_ratio + reserve_ratio + restaurants_ratio + museums_ratio then
+    return nearby_museums(url)
+  elseif coin < search_ratio + recommend_ratio + user_ratio + reserve_ratio + restaurants_ratio + museums_ratio + cinemas_ratio then
+    return nearby_cinemas(url)
+  else
+    return hotel_page(url)
+  end
+end